@@ -6,14 +6,225 @@ import (
 	"go/parser"
 	"log"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/fatih/set"
 	"go/token"
+	"golang.org/x/tools/go/ast/astutil"
 )
 
+// packageUnderTest is what nameToTypeMap holds for the names a test file imports the package under test by.
+// It can't be mistaken for a type, since it isn't a valid identifier.
+const packageUnderTest = "<package under test>"
+
+// typeString renders a type expression the way nameToTypeMap stores types: package qualifiers and pointers
+// are dropped, but slices and maps are kept so that index expressions can recover their element type.
+// Types we can't do anything useful with (functions, channels, anonymous structs, etc.) yield "".
+func typeString(in ast.Expr) string {
+	switch t := in.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	case *ast.StarExpr:
+		return typeString(t.X)
+	case *ast.ParenExpr:
+		return typeString(t.X)
+	case *ast.IndexExpr:
+		// instantiations of generic types, i.e. `List[int]`, are named after the generic type
+		return typeString(t.X)
+	case *ast.IndexListExpr:
+		return typeString(t.X)
+	case *ast.ArrayType:
+		if elt := typeString(t.Elt); elt != "" {
+			return fmt.Sprintf("[]%s", elt)
+		}
+	case *ast.MapType:
+		key, value := typeString(t.Key), typeString(t.Value)
+		if key != "" && value != "" {
+			return fmt.Sprintf("map[%s]%s", key, value)
+		}
+	}
+	return ""
+}
+
+// elementType returns the type of the elements of a slice or map type produced by typeString.
+func elementType(in string) string {
+	if strings.HasPrefix(in, "[]") {
+		return in[2:]
+	}
+	if strings.HasPrefix(in, "map[") {
+		depth := 0
+		for i, r := range in {
+			switch r {
+			case '[':
+				depth++
+			case ']':
+				depth--
+				if depth == 0 {
+					return in[i+1:]
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// exprType makes a best effort at determining the type of an expression, following selector chains,
+// call results, and index expressions one hop at a time. Besides variable names, it relies on the
+// entries indexTypes records in nameToTypeMap. It returns "" when the type can't be determined.
+func exprType(in ast.Expr, nameToTypeMap map[string]string) string {
+	switch e := in.(type) {
+	case *ast.Ident:
+		return nameToTypeMap[e.Name]
+	case *ast.ParenExpr:
+		return exprType(e.X, nameToTypeMap)
+	case *ast.StarExpr:
+		return exprType(e.X, nameToTypeMap)
+	case *ast.UnaryExpr:
+		if e.Op == token.AND {
+			return exprType(e.X, nameToTypeMap)
+		}
+	case *ast.CompositeLit:
+		return typeString(e.Type)
+	case *ast.TypeAssertExpr:
+		return typeString(e.Type)
+	case *ast.IndexExpr:
+		if t := elementType(exprType(e.X, nameToTypeMap)); t != "" {
+			return t
+		}
+		// an instantiation of a declared generic type, as in the method expression `List[int].Push`
+		if t := typeString(e); t != "" && nameToTypeMap[t] == t {
+			return t
+		}
+	case *ast.IndexListExpr:
+		if t := typeString(e); t != "" && nameToTypeMap[t] == t {
+			return t
+		}
+	case *ast.SelectorExpr:
+		if parent := exprType(e.X, nameToTypeMap); parent == packageUnderTest {
+			return nameToTypeMap[e.Sel.Name]
+		} else if parent != "" {
+			return nameToTypeMap[fmt.Sprintf("%s.%s", parent, e.Sel.Name)]
+		}
+		// most likely a package qualifier, i.e. `pkg.Var`
+		if _, ok := e.X.(*ast.Ident); ok {
+			return nameToTypeMap[e.Sel.Name]
+		}
+	case *ast.CallExpr:
+		switch f := uninstantiate(astutil.Unparen(e.Fun), nameToTypeMap).(type) {
+		case *ast.Ident:
+			if t, ok := nameToTypeMap[fmt.Sprintf("%s()", f.Name)]; ok {
+				return t
+			}
+			// conversions to a declared type, i.e. `T(x)`
+			if nameToTypeMap[f.Name] == f.Name {
+				return f.Name
+			}
+		case *ast.SelectorExpr:
+			if parent := exprType(f.X, nameToTypeMap); parent == packageUnderTest {
+				return nameToTypeMap[fmt.Sprintf("%s()", f.Sel.Name)]
+			} else if parent != "" {
+				return nameToTypeMap[fmt.Sprintf("%s.%s()", parent, f.Sel.Name)]
+			}
+			if _, ok := f.X.(*ast.Ident); ok {
+				return nameToTypeMap[fmt.Sprintf("%s()", f.Sel.Name)]
+			}
+		}
+	}
+	return ""
+}
+
+// uninstantiate returns the generic function or type an explicit instantiation like `Map[int, string]` refers
+// to, or the expression itself when it isn't one. A single type argument looks just like indexing a slice or
+// map, so index expressions whose operand is known to be one are left alone.
+func uninstantiate(in ast.Expr, nameToTypeMap map[string]string) ast.Expr {
+	switch e := in.(type) {
+	case *ast.IndexExpr:
+		if elementType(exprType(e.X, nameToTypeMap)) == "" {
+			return astutil.Unparen(e.X)
+		}
+	case *ast.IndexListExpr:
+		return astutil.Unparen(e.X)
+	}
+	return in
+}
+
+// indexTypes records what the syntax tree can tell us about the types declared in a file, so that exprType
+// can follow selector chains and call results. Alongside variable names, nameToTypeMap gets:
+//
+//	T     -> T     for every declared type, so that method expressions like T.Method resolve
+//	T.f   -> type  for every field f of a struct type T
+//	f()   -> type  for the first result of function f
+//	T.m() -> type  for the first result of method m on T
+//	v     -> type  for every package-level variable v whose type is evident
+func indexTypes(in *ast.File, nameToTypeMap map[string]string) {
+	for _, d := range in.Decls {
+		switch n := d.(type) {
+		case *ast.GenDecl:
+			for _, spec := range n.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					nameToTypeMap[s.Name.Name] = s.Name.Name
+					if st, ok := s.Type.(*ast.StructType); ok {
+						for _, field := range st.Fields.List {
+							fieldType := typeString(field.Type)
+							if fieldType == "" {
+								continue
+							}
+							for _, name := range field.Names {
+								nameToTypeMap[fmt.Sprintf("%s.%s", s.Name.Name, name.Name)] = fieldType
+							}
+							// embedded fields are named after their type
+							if len(field.Names) == 0 {
+								nameToTypeMap[fmt.Sprintf("%s.%s", s.Name.Name, fieldType)] = fieldType
+							}
+						}
+					}
+				case *ast.ValueSpec:
+					for i, name := range s.Names {
+						if t := typeString(s.Type); s.Type != nil && t != "" {
+							nameToTypeMap[name.Name] = t
+						} else if i < len(s.Values) {
+							if t := exprType(s.Values[i], nameToTypeMap); t != "" {
+								nameToTypeMap[name.Name] = t
+							}
+						}
+					}
+				}
+			}
+		case *ast.FuncDecl:
+			if n.Type.Results == nil || len(n.Type.Results.List) == 0 {
+				continue
+			}
+			if t := typeString(n.Type.Results.List[0].Type); t != "" {
+				nameToTypeMap[fmt.Sprintf("%s()", parseFuncDecl(n))] = t
+			}
+		}
+	}
+}
+
+// parseReceiverExpr finds the calls made while computing the receiver of a method call,
+// so that something like `NewServer().Handle()` credits NewServer as well as Handle.
+func parseReceiverExpr(in ast.Expr, nameToTypeMap map[string]string, helperFunctionReturnMap map[string][]string, out *set.Set) {
+	switch r := in.(type) {
+	case *ast.CallExpr:
+		parseCallExpr(r, nameToTypeMap, helperFunctionReturnMap, out)
+	case *ast.SelectorExpr:
+		parseReceiverExpr(r.X, nameToTypeMap, helperFunctionReturnMap, out)
+	case *ast.IndexExpr:
+		parseReceiverExpr(r.X, nameToTypeMap, helperFunctionReturnMap, out)
+		parseReceiverExpr(r.Index, nameToTypeMap, helperFunctionReturnMap, out)
+	case *ast.ParenExpr:
+		parseReceiverExpr(r.X, nameToTypeMap, helperFunctionReturnMap, out)
+	case *ast.StarExpr:
+		parseReceiverExpr(r.X, nameToTypeMap, helperFunctionReturnMap, out)
+	}
+}
+
 func parseExpr(in ast.Expr, nameToTypeMap map[string]string, helperFunctionReturnMap map[string][]string, out *set.Set) {
-	// FIXME: iterate over in.Args to see if there are function calls
 	switch f := in.(type) {
 	case *ast.Ident:
 		functionName := f.Name
@@ -21,25 +232,65 @@ func parseExpr(in ast.Expr, nameToTypeMap map[string]string, helperFunctionRetur
 			out.Add(functionName)
 		}
 	case *ast.SelectorExpr:
-		if x, ok := f.X.(*ast.Ident); ok {
-			structVarName := x.Name
-			calledMethodName := f.Sel.Name
-			if _, ok := nameToTypeMap[structVarName]; ok {
-				out.Add(fmt.Sprintf("%s.%s", nameToTypeMap[structVarName], calledMethodName))
-			}
+		parseReceiverExpr(f.X, nameToTypeMap, helperFunctionReturnMap, out)
+		// exprType handles method expressions like `(*T).Method` too, since declared types map to themselves.
+		if receiverType := exprType(f.X, nameToTypeMap); receiverType == packageUnderTest {
+			out.Add(f.Sel.Name)
+		} else if receiverType != "" {
+			out.Add(fmt.Sprintf("%s.%s", receiverType, f.Sel.Name))
+		}
+	case *ast.ParenExpr:
+		parseExpr(f.X, nameToTypeMap, helperFunctionReturnMap, out)
+	case *ast.IndexExpr, *ast.IndexListExpr:
+		if fun := uninstantiate(f, nameToTypeMap); fun != f {
+			parseExpr(fun, nameToTypeMap, helperFunctionReturnMap, out)
 		}
 	case *ast.FuncLit:
 		parseFuncLit(f, nameToTypeMap, helperFunctionReturnMap, out)
 	}
 }
 
+// callVisitor is an ast.Visitor that credits every call it comes across, no matter where in a function
+// body it appears, and keeps nameToTypeMap up to date as variables are declared along the way.
+type callVisitor struct {
+	nameToTypeMap           map[string]string
+	helperFunctionReturnMap map[string][]string
+	out                     *set.Set
+}
+
+// Visit implements ast.Visitor. Returning the visitor itself means ast.Walk descends into every child
+// of the node, so no statement or expression position is skipped.
+func (v callVisitor) Visit(node ast.Node) ast.Visitor {
+	switch n := node.(type) {
+	case *ast.AssignStmt:
+		// parseAssignStmt walks both sides of the assignment itself, after recording the types of the variables.
+		parseAssignStmt(n, v.nameToTypeMap, v.helperFunctionReturnMap, v.out)
+		return nil
+	case *ast.DeclStmt:
+		parseDeclStmt(n, v.nameToTypeMap)
+	case *ast.RangeStmt:
+		parseRangeStmt(n, v.nameToTypeMap)
+	case *ast.CallExpr:
+		// the bodies of immediately invoked function literals are handled when ast.Walk descends into them.
+		if _, ok := n.Fun.(*ast.FuncLit); !ok {
+			parseExpr(n.Fun, v.nameToTypeMap, v.helperFunctionReturnMap, v.out)
+		}
+	}
+	return v
+}
+
 func parseCallExpr(in *ast.CallExpr, nameToTypeMap map[string]string, helperFunctionReturnMap map[string][]string, out *set.Set) {
-	for _, a := range in.Args {
-		if r, ok := a.(*ast.CallExpr); ok {
-			parseCallExpr(r, nameToTypeMap, helperFunctionReturnMap, out)
+	ast.Walk(callVisitor{nameToTypeMap: nameToTypeMap, helperFunctionReturnMap: helperFunctionReturnMap, out: out}, in)
+}
+
+// parseRangeStmt records the type of the value variable in a range statement, when it can be determined.
+// (handles loops like `for _, tc := range testCases`)
+func parseRangeStmt(in *ast.RangeStmt, nameToTypeMap map[string]string) {
+	if v, ok := in.Value.(*ast.Ident); ok && in.Tok == token.DEFINE {
+		if elementType := elementType(exprType(in.X, nameToTypeMap)); elementType != "" {
+			nameToTypeMap[v.Name] = elementType
 		}
 	}
-	parseExpr(in.Fun, nameToTypeMap, helperFunctionReturnMap, out)
 }
 
 // parseUnaryExpr parses Unary expressions. From the go/ast docs:
@@ -80,18 +331,18 @@ func parseDeclStmt(in *ast.DeclStmt, nameToTypeMap map[string]string) {
 			nameToTypeMap[varName] = t.Name
 		case *ast.SelectorExpr:
 			nameToTypeMap[varName] = t.Sel.Name
+		case *ast.IndexExpr, *ast.IndexListExpr:
+			nameToTypeMap[varName] = typeString(t)
+		case nil:
+			if len(s.Values) > 0 {
+				if valueType := exprType(s.Values[0], nameToTypeMap); valueType != "" {
+					nameToTypeMap[varName] = valueType
+				}
+			}
 		}
 	}
 }
 
-// parseExprStmt parses expression statements. From the go/ast docs:
-// 		An ExprStmt node represents a (stand-alone) expression in a statement list.
-func parseExprStmt(in *ast.ExprStmt, nameToTypeMap map[string]string, helperFunctionReturnMap map[string][]string, out *set.Set) {
-	if c, ok := in.X.(*ast.CallExpr); ok {
-		parseCallExpr(c, nameToTypeMap, helperFunctionReturnMap, out)
-	}
-}
-
 func parseCompositeLit(in *ast.CompositeLit, varName string, nameToTypeMap map[string]string, helperFunctionReturnMap map[string][]string, out *set.Set) {
 	for _, e := range in.Elts {
 		if et, ok := e.(*ast.CallExpr); ok {
@@ -130,13 +381,19 @@ func parseFuncDecl(f *ast.FuncDecl) string {
 	functionName := f.Name.Name // "Avoid Stutter" lol
 	var parentName string
 	if f.Recv != nil {
-		switch x := f.Recv.List[0].Type.(type) {
-		case *ast.StarExpr:
-			if parent, ok := x.X.(*ast.Ident); ok {
-				parentName = parent.Name
-			}
-		case *ast.Ident:
-			parentName = x.Obj.Name
+		recv := f.Recv.List[0].Type
+		if star, ok := recv.(*ast.StarExpr); ok {
+			recv = star.X
+		}
+		// methods of generic types have their type parameters listed on the receiver, i.e. `(l *List[T])`
+		switch x := recv.(type) {
+		case *ast.IndexExpr:
+			recv = x.X
+		case *ast.IndexListExpr:
+			recv = x.X
+		}
+		if parent, ok := recv.(*ast.Ident); ok {
+			parentName = parent.Name
 		}
 	}
 
@@ -158,18 +415,27 @@ func parseAssignStmt(in *ast.AssignStmt, nameToTypeMap map[string]string, helper
 		}
 	}
 
+	if len(in.Lhs) == len(in.Rhs) {
+		for i := range in.Lhs {
+			if l, ok := in.Lhs[i].(*ast.Ident); ok {
+				if rhsType := exprType(in.Rhs[i], nameToTypeMap); rhsType != "" {
+					nameToTypeMap[l.Name] = rhsType
+				}
+			}
+		}
+	}
+
 	for j := range in.Rhs {
+		var varName string
+		if len(leftHandSide) > j {
+			varName = leftHandSide[j]
+		}
+
 		switch t := in.Rhs[j].(type) {
-		case *ast.FuncLit:
-			parseFuncLit(t, nameToTypeMap, helperFunctionReturnMap, out)
 		case *ast.UnaryExpr:
-			parseUnaryExpr(t, leftHandSide[j], nameToTypeMap, helperFunctionReturnMap, out)
+			parseUnaryExpr(t, varName, nameToTypeMap, helperFunctionReturnMap, out)
 		case *ast.CompositeLit:
-			if len(leftHandSide) > j {
-				parseCompositeLit(t, leftHandSide[j], nameToTypeMap, helperFunctionReturnMap, out)
-			} else {
-				parseCompositeLit(t, "", nameToTypeMap, helperFunctionReturnMap, out)
-			}
+			parseCompositeLit(t, varName, nameToTypeMap, helperFunctionReturnMap, out)
 		case *ast.CallExpr:
 			if len(in.Rhs) != len(in.Lhs) {
 				var functionName string
@@ -185,9 +451,17 @@ func parseAssignStmt(in *ast.AssignStmt, nameToTypeMap map[string]string, helper
 					}
 				}
 			}
-			parseCallExpr(t, nameToTypeMap, helperFunctionReturnMap, out)
 		}
 	}
+
+	// the types are recorded, now credit every call made on either side of the assignment.
+	v := callVisitor{nameToTypeMap: nameToTypeMap, helperFunctionReturnMap: helperFunctionReturnMap, out: out}
+	for _, x := range in.Lhs {
+		ast.Walk(v, x)
+	}
+	for _, x := range in.Rhs {
+		ast.Walk(v, x)
+	}
 }
 
 func parseHelperSelectorExpr(in *ast.SelectorExpr, functionName string, helperFunctionReturnMap map[string][]string) {
@@ -223,148 +497,167 @@ func parseHelperFunction(in *ast.FuncDecl, helperFunctionReturnMap map[string][]
 // 		A FuncLit node represents a function literal.
 // FuncLits have bodies that we basically need to explore the same way that we explore a normal function.
 func parseFuncLit(in *ast.FuncLit, nameToTypeMap map[string]string, helperFunctionReturnMap map[string][]string, out *set.Set) {
-	for _, le := range in.Body.List {
-		parseStmt(le, nameToTypeMap, helperFunctionReturnMap, out)
-	}
-}
-
-func parseReturnStmt(in *ast.ReturnStmt, nameToTypeMap map[string]string, helperFunctionReturnMap map[string][]string, out *set.Set) {
-	for _, x := range in.Results {
-		if y, ok := x.(*ast.CallExpr); ok {
-			parseExpr(y.Fun, nameToTypeMap, helperFunctionReturnMap, out)
-		}
-	}
-}
-
-func parseSelectStmt(in *ast.SelectStmt, nameToTypeMap map[string]string, helperFunctionReturnMap map[string][]string, out *set.Set) {
-	for _, x := range in.Body.List {
-		if y, ok := x.(*ast.CommClause); ok {
-			for _, z := range y.Body {
-				parseStmt(z, nameToTypeMap, helperFunctionReturnMap, out)
-			}
-		}
-	}
-}
-
-// parseSendStmt parses a send statement. (<-)
-func parseSendStmt(in *ast.SendStmt, nameToTypeMap map[string]string, helperFunctionReturnMap map[string][]string, out *set.Set) {
-	if n, ok := in.Value.(*ast.CallExpr); ok {
-		parseCallExpr(n, nameToTypeMap, helperFunctionReturnMap, out)
-	}
-}
-
-func parseSwitchStmt(in *ast.SwitchStmt, nameToTypeMap map[string]string, helperFunctionReturnMap map[string][]string, out *set.Set) {
-	for _, x := range in.Body.List {
-		if y, ok := x.(*ast.CaseClause); ok {
-			for _, z := range y.Body {
-				parseStmt(z, nameToTypeMap, helperFunctionReturnMap, out)
-			}
-		}
-	}
-}
-
-// parseTypeSwitchStmt parses
-func parseTypeSwitchStmt(in *ast.TypeSwitchStmt, nameToTypeMap map[string]string, helperFunctionReturnMap map[string][]string, out *set.Set) {
-	if in.Body != nil {
-		for _, x := range in.Body.List {
-			if y, ok := x.(*ast.CaseClause); ok {
-				for _, z := range y.Body {
-					parseStmt(z, nameToTypeMap, helperFunctionReturnMap, out)
-				}
-			}
-		}
-	}
+	ast.Walk(callVisitor{nameToTypeMap: nameToTypeMap, helperFunctionReturnMap: helperFunctionReturnMap, out: out}, in.Body)
 }
 
 // parseStmt parses a statement. From the go/ast docs:
 // 		All statement nodes implement the Stmt interface.
-// Cases we don't handle:
-//		BadStmt - we only parse valid code
-//		BlockStmt (sort of, we iterate over these in the form of `x.Body.List`)
-//		these are simply unnecessary:
-//			BranchStmt
-//			EmptyStmt
-//			IncDeclStmt
-//			LabeledStmt
+// Every node beneath the statement is visited, so calls are found in conditions, init and post statements,
+// else branches, deferred arguments, closures, literals, and so on.
 func parseStmt(in ast.Stmt, nameToTypeMap map[string]string, helperFunctionReturnMap map[string][]string, out *set.Set) {
-	switch e := in.(type) {
-	case *ast.AssignStmt: // handles things like `e := Example{}` (with or without &)
-		parseAssignStmt(e, nameToTypeMap, helperFunctionReturnMap, out)
-	// NOTE: even though RangeStmt/IfStmt/ForStmt are handled identically, Go will (rightfully) complain when trying
-	// to use a multiple case statement (i.e. `case *ast.RangeStmt, *ast.IfStmt`), so we're doing it this way.
-	case *ast.RangeStmt:
-		for _, x := range e.Body.List {
-			parseStmt(x, nameToTypeMap, helperFunctionReturnMap, out)
-		}
-	case *ast.IfStmt:
-		for _, x := range e.Body.List {
-			parseStmt(x, nameToTypeMap, helperFunctionReturnMap, out)
-		}
-	case *ast.ForStmt:
-		for _, x := range e.Body.List {
-			parseStmt(x, nameToTypeMap, helperFunctionReturnMap, out)
-		}
-	case *ast.DeclStmt:
-		parseDeclStmt(e, nameToTypeMap)
-	case *ast.ExprStmt:
-		parseExprStmt(e, nameToTypeMap, helperFunctionReturnMap, out)
-	case *ast.DeferStmt:
-		parseExpr(e.Call.Fun, nameToTypeMap, helperFunctionReturnMap, out)
-	case *ast.GoStmt:
-		parseExpr(e.Call.Fun, nameToTypeMap, helperFunctionReturnMap, out)
-	case *ast.ReturnStmt:
-		parseReturnStmt(e, nameToTypeMap, helperFunctionReturnMap, out)
-	case *ast.SelectStmt:
-		parseSelectStmt(e, nameToTypeMap, helperFunctionReturnMap, out)
-	case *ast.SendStmt:
-		parseSendStmt(e, nameToTypeMap, helperFunctionReturnMap, out)
-	case *ast.SwitchStmt:
-		parseSwitchStmt(e, nameToTypeMap, helperFunctionReturnMap, out)
-	case *ast.TypeSwitchStmt:
-		parseTypeSwitchStmt(e, nameToTypeMap, helperFunctionReturnMap, out)
-	}
+	ast.Walk(callVisitor{nameToTypeMap: nameToTypeMap, helperFunctionReturnMap: helperFunctionReturnMap, out: out}, in)
 }
 
 func getDeclaredNames(in *ast.File, fileset *token.FileSet, declaredFuncDetails map[string]tarpFunc) {
+	fileIgnore := findIgnore(in.Comments, ignoreFileDirective, fileset)
+	generated := isGeneratedFile(in, fileset.Position(in.Package).Filename)
 	for _, d := range in.Decls {
 		if f, ok := d.(*ast.FuncDecl); ok {
 			declPos := fileset.Position(f.Type.Func)
 			functionName := parseFuncDecl(f)
 
 			tf := tarpFunc{
-				Name:     functionName,
-				Filename: declPos.Filename,
-				DeclPos:  declPos,
+				Name:      functionName,
+				Filename:  declPos.Filename,
+				DeclPos:   declPos,
+				Ignore:    fileIgnore,
+				Generated: generated,
+			}
+			if ignore := findIgnore([]*ast.CommentGroup{f.Doc}, ignoreDirective, fileset); ignore != nil {
+				tf.Ignore = ignore
 			}
 
 			if f.Body != nil {
 				tf.RBracePos = fileset.Position(f.Body.Lbrace)
 				tf.LBracePos = fileset.Position(f.Body.Rbrace)
 			}
-			declaredFuncDetails[functionName] = tf
+			declaredFuncDetails[funcKey(tf)] = tf
 		}
 	}
 }
 
-func getCalledNames(in *ast.File, nameToTypeMap map[string]string, helperFunctionReturnMap map[string][]string, out *set.Set) {
+func getCalledNames(in *ast.File, nameToTypeMap map[string]string, helperFunctionReturnMap map[string][]string, helpers *set.Set, out *set.Set, kinds map[string]*set.Set, helperCalls map[string]*set.Set) {
 	for _, d := range in.Decls {
+		called := set.New()
+		v := callVisitor{nameToTypeMap: nameToTypeMap, helperFunctionReturnMap: helperFunctionReturnMap, out: called}
+		kind := testFunc
 		switch n := d.(type) {
 		case *ast.GenDecl:
 			parseGenDecl(n, nameToTypeMap)
+			ast.Walk(v, n)
 		case *ast.FuncDecl:
+			// methods like those of testify suites make their calls through the receiver
+			if n.Recv != nil && len(n.Recv.List[0].Names) > 0 {
+				nameToTypeMap[n.Recv.List[0].Names[0].Name] = receiverTypeName(n)
+			}
 			if n.Body != nil {
-				for _, le := range n.Body.List {
-					parseStmt(le, nameToTypeMap, helperFunctionReturnMap, out)
+				ast.Walk(v, n.Body)
+				getHelperNames(n.Body, helpers, called)
+			}
+			// what helpers call is credited later on, to the tests that call them
+			if isTestHelper(n) {
+				helperCalls[n.Name.Name] = called
+				continue
+			}
+			kind = funcKind(n.Name.Name, n.Recv != nil)
+		}
+		recordFuncKind(kind, called, out, kinds)
+	}
+}
+
+// getCallEdges records the names called by each function declared in the given file, so that functions only
+// reached through other functions can be credited at a distance. Functions are keyed by file and name, like
+// their declarations, so the variants of a function declared for different platforms keep their own calls.
+func getCallEdges(in *ast.File, fileset *token.FileSet, nameToTypeMap map[string]string, helperFunctionReturnMap map[string][]string, edges map[string]*set.Set) {
+	for _, d := range in.Decls {
+		if f, ok := d.(*ast.FuncDecl); ok && f.Body != nil {
+			out := set.New()
+			ast.Walk(callVisitor{nameToTypeMap: nameToTypeMap, helperFunctionReturnMap: helperFunctionReturnMap, out: out}, f.Body)
+			edges[funcKey(tarpFunc{Name: parseFuncDecl(f), Filename: fileset.Position(f.Pos()).Filename})] = out
+		}
+	}
+}
+
+// getReferencedNames adds the functions and methods referenced without being called in the given file's
+// function bodies, like those passed as arguments or stored in tables, to out. Identifiers the parser resolved
+// to something declared in the test file itself, like locals, parameters and helpers, are left out, since
+// they can't refer to the code under test.
+func getReferencedNames(in *ast.File, nameToTypeMap map[string]string, out *set.Set) {
+	for _, d := range in.Decls {
+		f, ok := d.(*ast.FuncDecl)
+		if !ok || f.Body == nil {
+			continue
+		}
+
+		// handled keeps track of the expressions in call position, along with identifiers that can't be
+		// references, like selected names and composite literal keys.
+		handled := map[ast.Expr]bool{}
+		ast.Inspect(f.Body, func(n ast.Node) bool {
+			switch e := n.(type) {
+			case *ast.CallExpr:
+				fun := astutil.Unparen(e.Fun)
+				handled[fun] = true
+				if sel, ok := fun.(*ast.SelectorExpr); ok {
+					handled[sel.Sel] = true
+				}
+			case *ast.KeyValueExpr:
+				handled[e.Key] = true
+			case *ast.Field:
+				for _, name := range e.Names {
+					handled[name] = true
+				}
+			case *ast.SelectorExpr:
+				handled[e.Sel] = true
+				if handled[e] {
+					return true
+				}
+				switch receiverType := exprType(e.X, nameToTypeMap); {
+				case receiverType == packageUnderTest:
+					out.Add(e.Sel.Name)
+				case receiverType != "":
+					out.Add(fmt.Sprintf("%s.%s", receiverType, e.Sel.Name))
+				default:
+					// method expressions, like T.Method or (*T).Method
+					if t := typeString(e.X); t != "" {
+						out.Add(fmt.Sprintf("%s.%s", t, e.Sel.Name))
+					}
+				}
+			case *ast.Ident:
+				if !handled[e] && e.Obj == nil {
+					out.Add(e.Name)
 				}
 			}
+			return true
+		})
+	}
+}
+
+// getTableCoverage links the functions called in each table-driven test in the given file to its table.
+func getTableCoverage(in *ast.File, nameToTypeMap map[string]string, helperFunctionReturnMap map[string][]string, coverage map[string]*tableCoverage) {
+	for _, table := range findTableTests(in) {
+		called := set.New()
+		parseStmt(table.loop.Body, nameToTypeMap, helperFunctionReturnMap, called)
+		recordTableCoverage(table, called, coverage)
+	}
+}
+
+// getSpecCoverage links the functions called by each spec of the BDD style tests in the given file to the
+// spec's path.
+func getSpecCoverage(in *ast.File, nameToTypeMap map[string]string, helperFunctionReturnMap map[string][]string, coverage map[string]*set.Set) {
+	for _, spec := range findSpecs(in) {
+		called := set.New()
+		v := callVisitor{nameToTypeMap: nameToTypeMap, helperFunctionReturnMap: helperFunctionReturnMap, out: called}
+		for _, node := range spec.nodes {
+			ast.Walk(v, node)
 		}
+		recordSpecCoverage(spec, called, coverage)
 	}
 }
 
 func findHelperFuncs(in *ast.File, helperFunctionReturnMap map[string][]string, out *set.Set) {
 	for _, d := range in.Decls {
 		if n, ok := d.(*ast.FuncDecl); ok {
-			functionName := in.Name.Name
+			functionName := n.Name.Name
 			if !strings.HasPrefix(functionName, "Test") {
 				parseHelperFunction(n, helperFunctionReturnMap, out)
 			}
@@ -372,73 +665,283 @@ func findHelperFuncs(in *ast.File, helperFunctionReturnMap map[string][]string,
 	}
 }
 
-func analyze(analyzePackage string) tarpReport {
-	gopath := os.Getenv("GOPATH")
+// importedNames returns the names a file refers to the package at importPath by, which is the package's
+// name unless the import is renamed. Dot imports don't need a name, since their identifiers are used as is.
+func importedNames(in *ast.File, importPath string, pkgName string) []string {
+	names := []string{}
+	for _, spec := range in.Imports {
+		if path, err := strconv.Unquote(spec.Path.Value); err != nil || path != importPath {
+			continue
+		}
+		switch {
+		case spec.Name == nil:
+			names = append(names, pkgName)
+		case spec.Name.Name != "." && spec.Name.Name != "_":
+			names = append(names, spec.Name.Name)
+		}
+	}
+	return names
+}
 
-	pkgDir := strings.Join([]string{gopath, "src", analyzePackage}, "/")
-	if analyzePackage == "." {
-		var err error
-		pkgDir, err = os.Getwd()
-		if err != nil {
-			log.Fatalf("error encountered getting current working directory: %v", err)
+// creditTestPackages merges the names called from each test package into calledFuncs, and returns the kinds
+// of test that credited each name. Test packages ending in _test are external, and so are black-box tests.
+func creditTestPackages(calledByPackage map[string]*set.Set, calledFuncs *set.Set) map[string]*set.Set {
+	credits := map[string]*set.Set{}
+	for pkgName, called := range calledByPackage {
+		calledFuncs.Merge(called)
+
+		credit := whiteBoxCredit
+		if strings.HasSuffix(pkgName, "_test") {
+			credit = blackBoxCredit
+		}
+		for _, name := range set.StringSlice(called) {
+			if _, ok := credits[name]; !ok {
+				credits[name] = set.New()
+			}
+			credits[name].Add(credit)
 		}
 	}
+	return credits
+}
 
-	if debug {
-		log.Printf("package directory: %s", pkgDir)
+// creditAs merges names that tests reach some other way than calling them directly, like through an interface
+// or by reference, into calledFuncs, and gives them the credit so they can be told apart from direct calls.
+func creditAs(names *set.Set, credit string, calledFuncs *set.Set, credits map[string]*set.Set) {
+	for _, name := range set.StringSlice(names) {
+		calledFuncs.Add(name)
+		if _, ok := credits[name]; !ok {
+			credits[name] = set.New()
+		}
+		credits[name].Add(credit)
 	}
+}
 
-	_, err := os.Stat(pkgDir)
-	if os.IsNotExist(err) {
-		log.Fatalf("packageDir doesn't exist: %s", pkgDir)
+// declaredOnly returns the names in the given set that belong to declared functions or methods, leaving out
+// the ones that name types, variables or constants.
+func declaredOnly(names *set.Set, declaredFuncInfo map[string]tarpFunc) *set.Set {
+	out := set.New()
+	for _, tf := range declaredFuncInfo {
+		if names.Has(tf.Name) {
+			out.Add(tf.Name)
+		}
 	}
+	return out
+}
 
-	astPkg, err := parser.ParseDir(fileset, pkgDir, nil, parser.AllErrors)
+// testDistances walks outward from the names called directly by tests, returning the minimum number of
+// call hops between any test and every declared function it can reach, keyed like declaredFuncInfo and
+// callEdges. Direct calls are at distance 1. Calls are made by name, so they reach every declaration of that
+// name, but only the calls each declaration makes itself are followed from it.
+func testDistances(calledFuncs *set.Set, callEdges map[string]*set.Set, declaredFuncInfo map[string]tarpFunc) map[string]int {
+	keys := map[string][]string{}
+	for key, f := range declaredFuncInfo {
+		keys[f.Name] = append(keys[f.Name], key)
+	}
+
+	distances := map[string]int{}
+	frontier := set.StringSlice(calledFuncs)
+	for distance := 1; len(frontier) > 0; distance++ {
+		next := []string{}
+		for _, name := range frontier {
+			for _, key := range keys[name] {
+				if _, ok := distances[key]; ok {
+					continue
+				}
+				distances[key] = distance
+				if callees, ok := callEdges[key]; ok {
+					next = append(next, set.StringSlice(callees)...)
+				}
+			}
+		}
+		frontier = next
+	}
+	return distances
+}
+
+// buildReport assembles a tarpReport from the declared functions, the names called directly by tests, and
+// the calls each declared function makes. Every declared function is given its distance from the nearest
+// test, along with the kinds of test that call it directly, and only those within maxDepth hops are
+// considered called. The Declared and Called sets hold the keys of declaredFuncInfo, rather than names.
+func buildReport(declaredFuncInfo map[string]tarpFunc, calledFuncs *set.Set, callEdges map[string]*set.Set, credits map[string]*set.Set) tarpReport {
+	// init runs whenever the package is loaded, so it counts as called, but that doesn't make what it calls tested
+	roots := calledFuncs.Copy().(*set.Set)
+	roots.Remove("init")
+	distances := testDistances(roots, callEdges, declaredFuncInfo)
+	for key, f := range declaredFuncInfo {
+		if f.Name == "init" && calledFuncs.Has("init") {
+			distances[key] = 1
+		}
+	}
+
+	declaredFuncs, testedFuncs := set.New(), set.New()
+	for key, f := range declaredFuncInfo {
+		declaredFuncs.Add(key)
+
+		f.Distance = untestedDistance
+		if credit, ok := credits[f.Name]; ok {
+			f.Credits = set.StringSlice(credit)
+			sort.Strings(f.Credits)
+		}
+		if distance, ok := distances[key]; ok {
+			f.Distance = distance
+			if distance <= maxDepth {
+				testedFuncs.Add(key)
+			}
+		}
+		declaredFuncInfo[key] = f
+	}
+
+	return tarpReport{
+		DeclaredDetails: declaredFuncInfo,
+		Declared:        declaredFuncs,
+		Called:          testedFuncs,
+	}
+}
+
+// astAnalysis builds a tarpReport for the package in pkgDir purely from the syntax tree,
+// guessing at the types of variables to figure out which methods are called.
+func astAnalysis(pkgDir string) tarpReport {
+	astPkg, err := parser.ParseDir(fileset, pkgDir, fileFilter(pkgDir), parser.AllErrors|parser.ParseComments)
 	if err != nil {
-		log.Fatal(err)
+		fatalf(exitLoadError, "%v", err)
 	}
 
 	if len(astPkg) == 0 || astPkg == nil {
-		log.Fatal("no go files found!")
+		fatalf(exitLoadError, "no go files found!")
 	}
 
 	declaredFuncInfo := map[string]tarpFunc{}
-	calledFuncs := set.New("init")
+	calledByPackage := map[string]*set.Set{}
+	callEdges := map[string]*set.Set{}
+	referenced := set.New()
+	coverage := map[string]*tableCoverage{}
+	specCoverage := map[string]*set.Set{}
+	kinds, carriers := map[string]*set.Set{}, map[string]*set.Set{}
+	helpers := map[string]*set.Set{}
 	helperFunctionReturnMap := map[string][]string{}
 	nameToTypeMap := map[string]string{}
 
-	// find all helper funcs first so we have an idea of what they are.
-	for _, pkg := range astPkg {
-		for name, f := range pkg.Files {
-			if strings.HasSuffix(name, "_test.go") {
-				findHelperFuncs(f, helperFunctionReturnMap, calledFuncs)
+	// black-box tests import the package under test, so we need its name and import path to recognize it.
+	importPath, pkgName := packageName(pkgDir), ""
+	for name := range astPkg {
+		if !strings.HasSuffix(name, "_test") || pkgName == "" {
+			pkgName = strings.TrimSuffix(name, "_test")
+		}
+	}
+
+	// find all helper funcs and declared types first so we have an idea of what they are.
+	suites := set.New()
+	for name, pkg := range astPkg {
+		calledByPackage[name] = set.New()
+		helpers[name] = set.New()
+		for filename, f := range pkg.Files {
+			indexTypes(f, nameToTypeMap)
+			if strings.HasSuffix(filename, "_test.go") {
+				dropSkippedSpecs(f)
+				findHelperFuncs(f, helperFunctionReturnMap, calledByPackage[name])
+				findTestHelpers(f, helpers[name])
+				findSuiteTypes(f, suites)
 			}
 		}
 	}
 
+	// suite fields are often only given a concrete type in a setup hook, which may be in any of the test files.
 	for _, pkg := range astPkg {
-		for name, f := range pkg.Files {
-			if strings.HasSuffix(name, "_test.go") {
-				getCalledNames(f, nameToTypeMap, helperFunctionReturnMap, calledFuncs)
+		for filename, f := range pkg.Files {
+			if strings.HasSuffix(filename, "_test.go") {
+				recordSuiteFields(f, suites, nameToTypeMap)
+			}
+		}
+	}
+
+	for name, pkg := range astPkg {
+		helperCalls := map[string]*set.Set{}
+		for filename, f := range pkg.Files {
+			if strings.HasSuffix(filename, "_test.go") {
+				imported := importedNames(f, importPath, pkgName)
+				for _, alias := range imported {
+					nameToTypeMap[alias] = packageUnderTest
+				}
+				getCalledNames(f, nameToTypeMap, helperFunctionReturnMap, helpers[name], calledByPackage[name], kinds, helperCalls)
+				if creditReferences {
+					getReferencedNames(f, nameToTypeMap, referenced)
+				}
+				getTableCoverage(f, nameToTypeMap, helperFunctionReturnMap, coverage)
+				getSpecCoverage(f, nameToTypeMap, helperFunctionReturnMap, specCoverage)
+				for _, alias := range imported {
+					delete(nameToTypeMap, alias)
+				}
 			} else {
 				getDeclaredNames(f, fileset, declaredFuncInfo)
+				getCallEdges(f, fileset, nameToTypeMap, helperFunctionReturnMap, callEdges)
 			}
 		}
+		creditHelpers(helperCalls, calledByPackage[name], kinds, carriers)
 	}
 
-	declaredFuncs := set.New()
-	for _, f := range declaredFuncInfo {
-		declaredFuncs.Add(f.Name)
+	calledFuncs := set.New("init")
+	credits := creditTestPackages(calledByPackage, calledFuncs)
+	creditAs(declaredOnly(referenced, declaredFuncInfo), byReferenceCredit, calledFuncs, credits)
+	report := buildReport(declaredFuncInfo, calledFuncs, callEdges, credits)
+	applyTableCoverage(report.DeclaredDetails, coverage)
+	applySpecCoverage(report.DeclaredDetails, specCoverage)
+	applyFuncKinds(report.DeclaredDetails, kinds)
+	applyHelperCredits(report.DeclaredDetails, carriers)
+	report.Engine = astEngine
+	return report
+}
+
+// analyze builds a tarpReport for a single package, given either its import path or a path relative to
+// the current directory.
+func analyze(analyzePackage string) tarpReport {
+	return analyzeDir(packageDir(analyzePackage))
+}
+
+// analyzeDir builds a tarpReport for the package in pkgDir with the configured analysis engine, or by the
+// names of its tests with --convention.
+func analyzeDir(pkgDir string) tarpReport {
+	if debug {
+		log.Printf("package directory: %s", pkgDir)
 	}
 
-	for _, x := range set.StringSlice(set.Difference(calledFuncs, declaredFuncs)) {
-		calledFuncs.Remove(x)
+	if maxDepth < 1 {
+		fatalf(exitUsageError, "--max-depth must be at least 1, got %d", maxDepth)
 	}
+	validateGeneratedPatterns()
 
-	tr := tarpReport{
-		DeclaredDetails: declaredFuncInfo,
-		Declared:        declaredFuncs,
-		Called:          calledFuncs,
+	_, err := os.Stat(pkgDir)
+	if os.IsNotExist(err) {
+		fatalf(exitLoadError, "packageDir doesn't exist: %s", pkgDir)
+	}
+
+	if convention {
+		return conventionAnalysis(pkgDir)
+	}
+
+	var report tarpReport
+	switch analysisEngine {
+	case typesEngine:
+		report, err = typeCheckedAnalysis(pkgDir)
+	case callgraphEngine:
+		switch callgraphAlgorithm {
+		case staticAlgorithm, declaredAlgorithm, constructedAlgorithm:
+			report, err = callgraphAnalysis(pkgDir, callgraphAlgorithm)
+		default:
+			fatalf(exitUsageError, "unknown call graph algorithm: %q", callgraphAlgorithm)
+		}
+	case astEngine:
+	default:
+		fatalf(exitUsageError, "unknown analysis engine: %q", analysisEngine)
+	}
+
+	if analysisEngine == astEngine {
+		return astAnalysis(pkgDir)
+	}
+	if err != nil {
+		if debug {
+			log.Printf("unable to type-check package, falling back to AST heuristics: %v", err)
+		}
+		return astAnalysis(pkgDir)
 	}
-	return tr
+	return report
 }
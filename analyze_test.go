@@ -1,9 +1,11 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"go/ast"
 	"go/parser"
+	"go/printer"
 	"go/token"
 	"log"
 	"testing"
@@ -33,7 +35,216 @@ func parseChunkOfCode(t *testing.T, chunkOfCode string) *ast.File {
 //                                                    //
 ////////////////////////////////////////////////////////
 
+func TestTypeString(t *testing.T) {
+	codeSample := `
+		package main
+
+		var (
+			a Example
+			b *pkg.Example
+			c []*Example
+			d map[string][]Example
+			e func()
+			f map[string]func()
+			g *List[int]
+			h pkg.Pair[string, int]
+		)
+	`
+
+	p := parseChunkOfCode(t, codeSample)
+	expected := []string{"Example", "Example", "[]Example", "map[string][]Example", "", "", "List", "Pair"}
+	for i, spec := range p.Decls[0].(*ast.GenDecl).Specs {
+		assert.Equal(t, expected[i], typeString(spec.(*ast.ValueSpec).Type))
+	}
+}
+
+func TestElementType(t *testing.T) {
+	assert.Equal(t, "Example", elementType("[]Example"))
+	assert.Equal(t, "[]Example", elementType("map[string][]Example"))
+	assert.Equal(t, "Example", elementType("map[map[string]int]Example"))
+	assert.Equal(t, "", elementType("Example"))
+}
+
+func TestExprType(t *testing.T) {
+	codeSample := `
+		package main
+
+		func main() {
+			_ = x
+			_ = &Example{}
+			_ = (*x)
+			_ = y.(Example)
+			_ = xs[0]
+			_ = x.field
+			_ = pkg.Global
+			_ = NewExample()
+			_ = x.Clone()
+			_ = pkg.NewExample()
+			_ = (NewExample)()
+			_ = Example(y)
+			_ = unknown.field
+			_ = -x
+			_ = self.Global
+			_ = self.NewExample()
+			_ = &List[int]{}
+			_ = NewList[int]()
+			_ = List[int](x)
+			_ = Pair[string, int]{}
+		}
+	`
+
+	nameToTypeMap := map[string]string{
+		"self":            packageUnderTest,
+		"x":               "Example",
+		"xs":              "[]Example",
+		"Example":         "Example",
+		"Example.field":   "Field",
+		"Global":          "Example",
+		"NewExample()":    "Example",
+		"Example.Clone()": "Example",
+		"List":            "List",
+		"Pair":            "Pair",
+		"NewList()":       "List",
+	}
+	expected := []string{
+		"Example",
+		"Example",
+		"Example",
+		"Example",
+		"Example",
+		"Field",
+		"Example",
+		"Example",
+		"Example",
+		"Example",
+		"Example",
+		"Example",
+		"",
+		"",
+		"Example",
+		"Example",
+		"List",
+		"List",
+		"List",
+		"Pair",
+	}
+
+	p := parseChunkOfCode(t, codeSample)
+	for i, stmt := range p.Decls[0].(*ast.FuncDecl).Body.List {
+		input := stmt.(*ast.AssignStmt).Rhs[0]
+		assert.Equal(t, expected[i], exprType(input, nameToTypeMap), "unexpected type for statement %d", i)
+	}
+}
+
+func TestUninstantiate(t *testing.T) {
+	codeSample := `
+		package main
+
+		func main() {
+			_ = Map[int]
+			_ = Map[int, string]
+			_ = pkg.Map[int]
+			_ = fns[0]
+			_ = Map
+		}
+	`
+
+	nameToTypeMap := map[string]string{"fns": "[]handler"}
+	expected := []string{"Map", "Map", "pkg.Map", "fns[0]", "Map"}
+
+	p := parseChunkOfCode(t, codeSample)
+	for i, stmt := range p.Decls[0].(*ast.FuncDecl).Body.List {
+		input := stmt.(*ast.AssignStmt).Rhs[0]
+		var buf bytes.Buffer
+		printer.Fprint(&buf, token.NewFileSet(), uninstantiate(input, nameToTypeMap))
+		assert.Equal(t, expected[i], buf.String(), "unexpected expression for statement %d", i)
+	}
+}
+
+func TestIndexTypes(t *testing.T) {
+	codeSample := `
+		package main
+
+		type Server struct {
+			client *Client
+			handlers map[string]Handler
+			Embedded
+		}
+
+		type Client struct{}
+
+		var global = &Server{}
+
+		func NewServer() *Server {
+			return &Server{}
+		}
+
+		func (s *Server) Client() (*Client, error) {
+			return s.client, nil
+		}
+
+		func noResults() {}
+	`
+
+	expected := map[string]string{
+		"Server":          "Server",
+		"Server.client":   "Client",
+		"Server.handlers": "map[string]Handler",
+		"Server.Embedded": "Embedded",
+		"Client":          "Client",
+		"global":          "Server",
+		"NewServer()":     "Server",
+		"Server.Client()": "Client",
+	}
+	actual := map[string]string{}
+
+	indexTypes(parseChunkOfCode(t, codeSample), actual)
+
+	assert.Equal(t, expected, actual, "actual output does not match expected output")
+}
+
+func TestParseReceiverExpr(t *testing.T) {
+	codeSample := `
+		package main
+
+		func main() {
+			(*a().b[c()]).d()
+		}
+	`
+
+	p := parseChunkOfCode(t, codeSample)
+	input := p.Decls[0].(*ast.FuncDecl).Body.List[0].(*ast.ExprStmt).X.(*ast.CallExpr).Fun.(*ast.SelectorExpr).X
+
+	actual := set.New()
+	expected := set.New("a", "c")
+
+	parseReceiverExpr(input, map[string]string{}, map[string][]string{}, actual)
+
+	assert.Equal(t, expected, actual, "expected the calls made in the receiver to be added to output")
+}
+
 func TestParseExpr(t *testing.T) {
+	packageUnderTestSelector := func(t *testing.T) {
+		codeSample := `
+			package main_test
+
+			func main() {
+				self.Function()
+			}
+		`
+
+		p := parseChunkOfCode(t, codeSample)
+		input := p.Decls[0].(*ast.FuncDecl).Body.List[0].(*ast.ExprStmt).X.(*ast.CallExpr).Fun
+
+		actual := set.New()
+		expected := set.New("Function")
+
+		parseExpr(input, map[string]string{"self": packageUnderTest}, map[string][]string{}, actual)
+
+		assert.Equal(t, expected, actual, "expected functions qualified with the package under test to be added to output")
+	}
+	t.Run("package under test selector", packageUnderTestSelector)
+
 	identTest := func(t *testing.T) {
 		codeSample := `
 			package main
@@ -99,6 +310,131 @@ func TestParseExpr(t *testing.T) {
 		assert.Equal(t, expected, actual, "expected function name to be added to output")
 	}
 	t.Run("function literal", funcLitTest)
+
+	deeplyNestedSelectorTest := func(t *testing.T) {
+		codeSample := `
+			package main
+
+			func main() {
+				x.First.Second.Third.methodCall()
+			}
+		`
+
+		p := parseChunkOfCode(t, codeSample)
+		input := p.Decls[0].(*ast.FuncDecl).Body.List[0].(*ast.ExprStmt).X.(*ast.CallExpr).Fun
+		nameToTypeMap := map[string]string{
+			"x":            "Outer",
+			"Outer.First":  "First",
+			"First.Second": "Second",
+			"Second.Third": "Example",
+			"Example":      "Example",
+		}
+
+		actual := set.New()
+		expected := set.New("Example.methodCall")
+
+		parseExpr(input, nameToTypeMap, map[string][]string{}, actual)
+
+		assert.Equal(t, expected, actual, "expected method name to be added to output")
+	}
+	t.Run("deeply nested selector", deeplyNestedSelectorTest)
+
+	chainedCallTest := func(t *testing.T) {
+		codeSample := `
+			package main
+
+			func main() {
+				NewServer().Handle()
+			}
+		`
+
+		p := parseChunkOfCode(t, codeSample)
+		input := p.Decls[0].(*ast.FuncDecl).Body.List[0].(*ast.ExprStmt).X.(*ast.CallExpr).Fun
+		nameToTypeMap := map[string]string{"NewServer()": "Server"}
+
+		actual := set.New()
+		expected := set.New("NewServer", "Server.Handle")
+
+		parseExpr(input, nameToTypeMap, map[string][]string{}, actual)
+
+		assert.Equal(t, expected, actual, "expected both the constructor and the method to be added to output")
+	}
+	t.Run("chained call receiver", chainedCallTest)
+
+	methodExpressionTest := func(t *testing.T) {
+		codeSample := `
+			package main
+
+			func main() {
+				(*Example).methodCall(x)
+			}
+		`
+
+		p := parseChunkOfCode(t, codeSample)
+		input := p.Decls[0].(*ast.FuncDecl).Body.List[0].(*ast.ExprStmt).X.(*ast.CallExpr).Fun
+
+		actual := set.New()
+		expected := set.New("Example.methodCall")
+
+		parseExpr(input, map[string]string{"Example": "Example"}, map[string][]string{}, actual)
+
+		assert.Equal(t, expected, actual, "expected method name to be added to output")
+	}
+	t.Run("method expression", methodExpressionTest)
+
+	instantiationTest := func(t *testing.T) {
+		codeSample := `
+			package main
+
+			func main() {
+				Map[int, string](xs, strconv.Itoa)
+				NewList[int]()
+				l.Push(1)
+				handlers[0]()
+			}
+		`
+
+		p := parseChunkOfCode(t, codeSample)
+		nameToTypeMap := map[string]string{"l": "List", "handlers": "[]handler"}
+		actual := set.New()
+		for _, stmt := range p.Decls[0].(*ast.FuncDecl).Body.List {
+			parseExpr(stmt.(*ast.ExprStmt).X.(*ast.CallExpr).Fun, nameToTypeMap, map[string][]string{}, actual)
+		}
+
+		expected := set.New("Map", "NewList", "List.Push")
+		assert.Equal(t, expected, actual, "explicit instantiations should be credited as the generic function, but indexing shouldn't")
+	}
+	t.Run("explicit instantiation", instantiationTest)
+}
+
+func TestCallVisitorVisit(t *testing.T) {
+	codeSample := `
+		package main
+
+		func main() {
+			x := NewExample()
+			if ok := x.Check(); !ok {
+				defer x.Close(cleanup())
+			}
+		}
+	`
+
+	p := parseChunkOfCode(t, codeSample)
+	input := p.Decls[0].(*ast.FuncDecl).Body
+
+	actual := set.New()
+	expected := set.New("NewExample", "Example.Check", "Example.Close", "cleanup")
+	v := callVisitor{
+		nameToTypeMap:           map[string]string{"NewExample()": "Example"},
+		helperFunctionReturnMap: map[string][]string{},
+		out:                     actual,
+	}
+
+	ast.Walk(v, input)
+
+	assert.Equal(t, expected, actual, "expected every call in the function body to be added to output")
+	assert.Nil(t, v.Visit(input.List[0]), "assignments should be walked by parseAssignStmt rather than by ast.Walk")
+	assert.Equal(t, v, v.Visit(input.List[1]), "every other node's children should be walked")
 }
 
 func TestParseCallExpr(t *testing.T) {
@@ -170,6 +506,29 @@ func TestParseCallExpr(t *testing.T) {
 	t.Run("with ast.SelectorExpr, but no matching entit", astSelectorExprTestWithoutMatchInMap)
 }
 
+func TestParseRangeStmt(t *testing.T) {
+	codeSample := `
+		package main
+		func main(){
+			for _, tc := range testCases {}
+			for i := range testCases {}
+			for _, x = range testCases {}
+		}
+	`
+
+	p := parseChunkOfCode(t, codeSample)
+	body := p.Decls[0].(*ast.FuncDecl).Body.List
+
+	actual := map[string]string{"testCases": "[]testCase"}
+	expected := map[string]string{"testCases": "[]testCase", "tc": "testCase"}
+
+	for _, stmt := range body {
+		parseRangeStmt(stmt.(*ast.RangeStmt), actual)
+	}
+
+	assert.Equal(t, expected, actual, "actual output does not match expected output")
+}
+
 func TestParseUnaryExpr(t *testing.T) {
 	codeSample := `
 			package main
@@ -209,52 +568,6 @@ func TestParseDeclStmt(t *testing.T) {
 	assert.Equal(t, expected, actual, "actual output does not match expected output")
 }
 
-func TestParseExprStmt(t *testing.T) {
-	ident := func(t *testing.T) {
-		codeSample := `
-			package main
-			var example func()
-			func main(){
-				example()
-			}
-		`
-		p := parseChunkOfCode(t, codeSample)
-		input := p.Decls[1].(*ast.FuncDecl).Body.List[0].(*ast.ExprStmt)
-
-		expected := set.New("example")
-		actual := set.New()
-
-		parseExprStmt(input, map[string]string{}, map[string][]string{}, actual)
-
-		assert.Equal(t, expected, actual, "actual output does not match expected output")
-	}
-	t.Run("CallExpr.Fun.(*ast.Ident)", ident)
-
-	selector := func(t *testing.T) {
-		codeSample := `
-			package main
-			type Example struct{}
-			func (e Example) method() {}
-			func main() {
-				var e Example
-				e.method()
-			}
-
-		`
-		p := parseChunkOfCode(t, codeSample)
-		input := p.Decls[2].(*ast.FuncDecl).Body.List[1].(*ast.ExprStmt)
-
-		expected := set.New("Example.method")
-		actual := set.New()
-
-		parseExprStmt(input, map[string]string{"e": "Example"}, map[string][]string{}, actual)
-
-		assert.Equal(t, expected, actual, "actual output does not match expected output")
-	}
-
-	t.Run("CallExpr.Fun.(*ast.Selector)", selector)
-}
-
 func TestParseCompositeLit(t *testing.T) {
 	ident := func(t *testing.T) {
 		codeSample := `
@@ -369,6 +682,30 @@ func TestParseFuncDecl(t *testing.T) {
 		assert.Equal(t, expected, actual, "actual output does not match expected output")
 	}
 	t.Run("with ptr receiver", methodASTStarExprType)
+
+	genericReceivers := func(t *testing.T) {
+		codeSample := `
+			package test
+			type List[T any] struct{}
+			func (l *List[T]) Push(v T){}
+			func (l List[T]) Len() int { return 0 }
+			type Pair[K, V any] struct{}
+			func (p *Pair[K, V]) Swap(){}
+			func Map[T, U any](in []T, f func(T) U) []U { return nil }
+		`
+
+		p := parseChunkOfCode(t, codeSample)
+		expected := []string{"List.Push", "List.Len", "Pair.Swap", "Map"}
+		actual := []string{}
+		for _, d := range p.Decls {
+			if f, ok := d.(*ast.FuncDecl); ok {
+				actual = append(actual, parseFuncDecl(f))
+			}
+		}
+
+		assert.Equal(t, expected, actual, "actual output does not match expected output")
+	}
+	t.Run("with generic receivers", genericReceivers)
 }
 
 func TestParseAssignStmt(t *testing.T) {
@@ -695,170 +1032,30 @@ func TestParseFuncLit(t *testing.T) {
 	t.Run("all cases", totalTest)
 }
 
-func TestParseReturnStmt(t *testing.T) {
-	test := func(t *testing.T) {
+func TestParseStmt(t *testing.T) {
+	all := func(t *testing.T) {
 		codeSample := `
 			package main
-			func main(){
-				return functionCall()
-			}
-		`
 
-		p := parseChunkOfCode(t, codeSample)
-		input := p.Decls[0].(*ast.FuncDecl).Body.List[0].(*ast.ReturnStmt)
+			import "testing"
 
-		actual := set.New()
-		expected := set.New("functionCall")
+			func TestX(t *testing.T) string {
+				// AssignStmt:
+				tmp := "AssignStmt"
+				foo := Foo{}
+				bar := bar.Bar{}
+				baz := &baz.Baz{}
+				var x Example
 
-		parseReturnStmt(input, map[string]string{}, map[string][]string{}, actual)
+				// RangeStmt
+				for range [1]struct{}{} {
+					A()
+				}
 
-		assert.Equal(t, expected, actual, "expected function name to be added to output")
-	}
-	t.Run("test", test)
-}
-
-func TestParseSelectStmt(t *testing.T) {
-	test := func(t *testing.T) {
-		codeSample := `
-			package main
-			func main(){
-			temp := make(chan int)
-			go func() {
-				temp <- 0
-			}()
-
-			for {
-				select {
-				case <-temp:
-					functionCall()
-					return
-				}
-			}
-			}
-		`
-
-		p := parseChunkOfCode(t, codeSample)
-		input := p.Decls[0].(*ast.FuncDecl).Body.List[2].(*ast.ForStmt).Body.List[0].(*ast.SelectStmt)
-
-		actual := set.New()
-		expected := set.New("functionCall")
-
-		parseSelectStmt(input, map[string]string{}, map[string][]string{}, actual)
-
-		assert.Equal(t, expected, actual, "expected function name to be added to output")
-	}
-	t.Run("test", test)
-}
-
-func TestParseSendStmt(t *testing.T) {
-	test := func(t *testing.T) {
-		codeSample := `
-			package main
-			func main(){
-				thing <- First()
-				thing <- func(){
-					Second()
-				}()
-				thing <- x.Third()
-			}
-		`
-
-		p := parseChunkOfCode(t, codeSample)
-		input := p.Decls[0].(*ast.FuncDecl).Body.List
-
-		actual := set.New()
-		expected := set.New(
-			"First",
-			"Second",
-			"Example.Third",
-		)
-
-		for _, x := range input {
-			in := x.(*ast.SendStmt)
-			parseSendStmt(in, map[string]string{"x": "Example"}, map[string][]string{}, actual)
-		}
-
-		assert.Equal(t, expected, actual, "expected function name to be added to output")
-	}
-	t.Run("test", test)
-}
-
-func TestParseSwitchStmt(t *testing.T) {
-	test := func(t *testing.T) {
-		codeSample := `
-			package main
-			func main(){
-				switch tmp {
-				case tmp:
-					functionCall()
-				}
-			}
-		`
-
-		p := parseChunkOfCode(t, codeSample)
-		input := p.Decls[0].(*ast.FuncDecl).Body.List[0].(*ast.SwitchStmt)
-
-		actual := set.New()
-		expected := set.New("functionCall")
-
-		parseSwitchStmt(input, map[string]string{}, map[string][]string{}, actual)
-
-		assert.Equal(t, expected, actual, "expected function name to be added to output")
-	}
-	t.Run("test", test)
-}
-
-func TestParseTypeSwitchStmt(t *testing.T) {
-	test := func(t *testing.T) {
-		codeSample := `
- 			package main
- 			func main(){
-				func(i interface{}) {
-					switch i.(type) {
-					case string:
-						functionCall()
-					}
-				}(tmp)
- 			}
- 		`
-
-		p := parseChunkOfCode(t, codeSample)
-		input := p.Decls[0].(*ast.FuncDecl).Body.List[0].(*ast.ExprStmt).X.(*ast.CallExpr).Fun.(*ast.FuncLit).Body.List[0].(*ast.TypeSwitchStmt)
-
-		actual := set.New()
-		expected := set.New("functionCall")
-
-		parseTypeSwitchStmt(input, map[string]string{}, map[string][]string{}, actual)
-
-		assert.Equal(t, expected, actual, "expected function name to be added to output")
-	}
-	t.Run("test", test)
-}
-
-func TestParseStmt(t *testing.T) {
-	all := func(t *testing.T) {
-		codeSample := `
-			package main
-
-			import "testing"
-
-			func TestX(t *testing.T) string {
-				// AssignStmt:
-				tmp := "AssignStmt"
-				foo := Foo{}
-				bar := bar.Bar{}
-				baz := &baz.Baz{}
-				var x Example
-
-				// RangeStmt
-				for range [1]struct{}{} {
-					A()
-				}
-
-				// IfStmt
-				if true {
-					B()
-				}
+				// IfStmt
+				if true {
+					B()
+				}
 
 				// DeclStmt
 				var declStmt ast.DeclStmt
@@ -979,6 +1176,180 @@ func TestParseStmt(t *testing.T) {
 		assert.Equal(t, expected, actual, "actual output does not match expected output")
 	}
 	t.Run("all", all)
+
+	exprStmtIdent := func(t *testing.T) {
+		codeSample := `
+			package main
+			var example func()
+			func main(){
+				example()
+			}
+		`
+		p := parseChunkOfCode(t, codeSample)
+		input := p.Decls[1].(*ast.FuncDecl).Body.List[0].(*ast.ExprStmt)
+
+		expected := set.New("example")
+		actual := set.New()
+
+		parseStmt(input, map[string]string{}, map[string][]string{}, actual)
+
+		assert.Equal(t, expected, actual, "actual output does not match expected output")
+	}
+	t.Run("ExprStmt with CallExpr.Fun.(*ast.Ident)", exprStmtIdent)
+
+	exprStmtSelector := func(t *testing.T) {
+		codeSample := `
+			package main
+			type Example struct{}
+			func (e Example) method() {}
+			func main() {
+				var e Example
+				e.method()
+			}
+
+		`
+		p := parseChunkOfCode(t, codeSample)
+		input := p.Decls[2].(*ast.FuncDecl).Body.List[1].(*ast.ExprStmt)
+
+		expected := set.New("Example.method")
+		actual := set.New()
+
+		parseStmt(input, map[string]string{"e": "Example"}, map[string][]string{}, actual)
+
+		assert.Equal(t, expected, actual, "actual output does not match expected output")
+	}
+
+	t.Run("ExprStmt with CallExpr.Fun.(*ast.Selector)", exprStmtSelector)
+
+	returnStmt := func(t *testing.T) {
+		codeSample := `
+			package main
+			func main(){
+				return functionCall()
+			}
+		`
+
+		p := parseChunkOfCode(t, codeSample)
+		input := p.Decls[0].(*ast.FuncDecl).Body.List[0].(*ast.ReturnStmt)
+
+		actual := set.New()
+		expected := set.New("functionCall")
+
+		parseStmt(input, map[string]string{}, map[string][]string{}, actual)
+
+		assert.Equal(t, expected, actual, "expected function name to be added to output")
+	}
+	t.Run("ReturnStmt", returnStmt)
+
+	selectStmt := func(t *testing.T) {
+		codeSample := `
+			package main
+			func main(){
+			temp := make(chan int)
+			go func() {
+				temp <- 0
+			}()
+
+			for {
+				select {
+				case <-temp:
+					functionCall()
+					return
+				}
+			}
+			}
+		`
+
+		p := parseChunkOfCode(t, codeSample)
+		input := p.Decls[0].(*ast.FuncDecl).Body.List[2].(*ast.ForStmt).Body.List[0].(*ast.SelectStmt)
+
+		actual := set.New()
+		expected := set.New("functionCall")
+
+		parseStmt(input, map[string]string{}, map[string][]string{}, actual)
+
+		assert.Equal(t, expected, actual, "expected function name to be added to output")
+	}
+	t.Run("SelectStmt", selectStmt)
+
+	sendStmt := func(t *testing.T) {
+		codeSample := `
+			package main
+			func main(){
+				thing <- First()
+				thing <- func(){
+					Second()
+				}()
+				thing <- x.Third()
+			}
+		`
+
+		p := parseChunkOfCode(t, codeSample)
+		input := p.Decls[0].(*ast.FuncDecl).Body.List
+
+		actual := set.New()
+		expected := set.New(
+			"First",
+			"Second",
+			"Example.Third",
+		)
+
+		for _, x := range input {
+			in := x.(*ast.SendStmt)
+			parseStmt(in, map[string]string{"x": "Example"}, map[string][]string{}, actual)
+		}
+
+		assert.Equal(t, expected, actual, "expected function name to be added to output")
+	}
+	t.Run("SendStmt", sendStmt)
+
+	switchStmt := func(t *testing.T) {
+		codeSample := `
+			package main
+			func main(){
+				switch tmp {
+				case tmp:
+					functionCall()
+				}
+			}
+		`
+
+		p := parseChunkOfCode(t, codeSample)
+		input := p.Decls[0].(*ast.FuncDecl).Body.List[0].(*ast.SwitchStmt)
+
+		actual := set.New()
+		expected := set.New("functionCall")
+
+		parseStmt(input, map[string]string{}, map[string][]string{}, actual)
+
+		assert.Equal(t, expected, actual, "expected function name to be added to output")
+	}
+	t.Run("SwitchStmt", switchStmt)
+
+	typeSwitchStmt := func(t *testing.T) {
+		codeSample := `
+ 			package main
+ 			func main(){
+				func(i interface{}) {
+					switch i.(type) {
+					case string:
+						functionCall()
+					}
+				}(tmp)
+ 			}
+ 		`
+
+		p := parseChunkOfCode(t, codeSample)
+		input := p.Decls[0].(*ast.FuncDecl).Body.List[0].(*ast.ExprStmt).X.(*ast.CallExpr).Fun.(*ast.FuncLit).Body.List[0].(*ast.TypeSwitchStmt)
+
+		actual := set.New()
+		expected := set.New("functionCall")
+
+		parseStmt(input, map[string]string{}, map[string][]string{}, actual)
+
+		assert.Equal(t, expected, actual, "expected function name to be added to output")
+	}
+	t.Run("TypeSwitchStmt", typeSwitchStmt)
 }
 
 func TestGetDeclaredNames(t *testing.T) {
@@ -1048,6 +1419,49 @@ func TestGetDeclaredNames(t *testing.T) {
 		assert.Equal(t, expected, actual, "expected output did not match actual output")
 	}
 	t.Run("methods", methods)
+
+	ignored := func(t *testing.T) {
+		fileset := token.NewFileSet()
+		in, err := parser.ParseFile(fileset, "example_packages/ignore/e2e.go", nil, parser.AllErrors|parser.ParseComments)
+		if err != nil {
+			t.Logf("failing because ParseFile returned error: %v", err)
+			t.FailNow()
+		}
+
+		actual := map[string]tarpFunc{}
+		getDeclaredNames(in, fileset, actual)
+
+		assert.Equal(t, &tarpIgnore{Reason: "exercised by the e2e suite"}, actual["e2e.go:Start"].Ignore)
+		assert.Equal(t, &tarpIgnore{Reason: "exercised by the e2e suite"}, actual["e2e.go:Stop"].Ignore)
+
+		in, err = parser.ParseFile(fileset, "example_packages/ignore/main.go", nil, parser.AllErrors|parser.ParseComments)
+		if err != nil {
+			t.Logf("failing because ParseFile returned error: %v", err)
+			t.FailNow()
+		}
+
+		actual = map[string]tarpFunc{}
+		getDeclaredNames(in, fileset, actual)
+
+		assert.Equal(t, &tarpIgnore{Reason: "covered by the e2e suite", Until: "2000-01-01"}, actual["main.go:Serve"].Ignore)
+		assert.Nil(t, actual["main.go:Parse"].Ignore)
+	}
+	t.Run("ignore directives", ignored)
+
+	generated := func(t *testing.T) {
+		fileset := token.NewFileSet()
+		in, err := parser.ParseFile(fileset, "example_packages/generated/kind_string.go", nil, parser.AllErrors|parser.ParseComments)
+		if err != nil {
+			t.Logf("failing because ParseFile returned error: %v", err)
+			t.FailNow()
+		}
+
+		actual := map[string]tarpFunc{}
+		getDeclaredNames(in, fileset, actual)
+
+		assert.True(t, actual["kind_string.go:Kind.String"].Generated)
+	}
+	t.Run("generated files", generated)
 }
 
 func TestGetCalledNames(t *testing.T) {
@@ -1066,7 +1480,7 @@ func TestGetCalledNames(t *testing.T) {
 
 		actual := set.New()
 
-		getCalledNames(in, map[string]string{}, map[string][]string{}, actual)
+		getCalledNames(in, map[string]string{}, map[string][]string{}, set.New(), actual, map[string]*set.Set{}, map[string]*set.Set{})
 
 		assert.Equal(t, expected, actual, "expected output did not match actual output")
 	}
@@ -1095,11 +1509,136 @@ func TestGetCalledNames(t *testing.T) {
 				"error",
 			},
 		}
-		getCalledNames(in, map[string]string{}, helperFunctionMap, actual)
+		getCalledNames(in, map[string]string{}, helperFunctionMap, set.New(), actual, map[string]*set.Set{}, map[string]*set.Set{})
 
 		assert.Equal(t, expected, actual, "expected output did not match actual output")
 	}
 	t.Run("methods", methods)
+
+	receivers := func(t *testing.T) {
+		p := parseChunkOfCode(t, `
+			package main
+			func (s *StoreSuite) TestLookup() {
+				s.cache.Lookup("key")
+			}
+		`)
+
+		nameToTypeMap := map[string]string{"StoreSuite.cache": "Cache"}
+		actual := set.New()
+		getCalledNames(p, nameToTypeMap, map[string][]string{}, set.New(), actual, map[string]*set.Set{}, map[string]*set.Set{})
+
+		assert.Equal(t, set.New("Cache.Lookup"), actual, "calls made through the receiver of a method should be resolved")
+	}
+	t.Run("receivers", receivers)
+
+	testFuncs := func(t *testing.T) {
+		defer func() { countBenchmarks = true }()
+		countBenchmarks = false
+
+		p := parseChunkOfCode(t, `
+			package main
+			func TestParse(t *testing.T) {
+				Parse()
+			}
+			func BenchmarkFormat(b *testing.B) {
+				Format()
+			}
+			func ExampleValidate() {
+				Validate()
+			}
+		`)
+
+		actual, kinds := set.New(), map[string]*set.Set{}
+		getCalledNames(p, map[string]string{}, map[string][]string{}, set.New(), actual, kinds, map[string]*set.Set{})
+
+		assert.Equal(t, set.New("Parse", "Validate"), actual, "calls made by benchmarks shouldn't count")
+		assert.Equal(t, set.New(benchmarkFunc), kinds["Format"])
+		assert.Equal(t, set.New(exampleFunc), kinds["Validate"])
+	}
+	t.Run("test function kinds", testFuncs)
+
+	helpers := func(t *testing.T) {
+		p := parseChunkOfCode(t, helpersChunkOfCode)
+
+		actual, kinds, helperCalls := set.New(), map[string]*set.Set{}, map[string]*set.Set{}
+		getCalledNames(p, map[string]string{}, map[string][]string{}, set.New("newTestStore", "fill"), actual, kinds, helperCalls)
+
+		assert.False(t, actual.Has("NewStore"), "calls made by helpers should be left for creditHelpers")
+		assert.Equal(t, set.New(testFunc), kinds["newTestStore"])
+		assert.Equal(t, set.New("NewStore"), helperCalls["newTestStore"])
+		assert.Contains(t, helperCalls, "fill")
+	}
+	t.Run("test helpers", helpers)
+}
+
+func TestGetReferencedNames(t *testing.T) {
+	codeSample := `
+		package main_test
+
+		func TestA(t *testing.T) {
+			h := handler{}
+			apply(h.Handle)
+			called()
+			tests := []struct{ fn func() }{{fn: B}}
+			_ = tests
+			_ = (*handler).Close
+			_ = pkg.Exported
+		}
+
+		func TestShadowed(t *testing.T) {
+			C := func() {}
+			apply(C)
+			for _, D := range []func(){C} {
+				apply(D)
+			}
+			apply(helper)
+		}
+
+		func helper() {}
+	`
+
+	actual := set.New()
+	getReferencedNames(parseChunkOfCode(t, codeSample), map[string]string{"h": "handler", "pkg": packageUnderTest}, actual)
+
+	for _, name := range []string{"handler.Handle", "B", "handler.Close", "Exported"} {
+		assert.True(t, actual.Has(name), "expected %s to be found", name)
+	}
+	for _, name := range []string{"apply", "called", "fn", "tests", "h", "t", "C", "D", "helper"} {
+		assert.False(t, actual.Has(name), "expected %s to be left out", name)
+	}
+}
+
+func TestGetTableCoverage(t *testing.T) {
+	codeSample := `
+		package main
+
+		func TestA(t *testing.T) {
+			tests := []struct{ name string }{{name: "first"}, {name: "second"}}
+			for _, tc := range tests {
+				t.Run(tc.name, func(t *testing.T) {
+					a()
+				})
+			}
+			b()
+		}
+	`
+
+	coverage := map[string]*tableCoverage{}
+	getTableCoverage(parseChunkOfCode(t, codeSample), map[string]string{}, map[string][]string{}, coverage)
+
+	assert.Equal(t, set.New("first", "second"), coverage["a"].names)
+	assert.Equal(t, 2, coverage["a"].largest)
+	assert.Nil(t, coverage["b"], "calls outside of the loop shouldn't be linked to the table")
+}
+
+func TestGetSpecCoverage(t *testing.T) {
+	coverage := map[string]*set.Set{}
+	nameToTypeMap := map[string]string{"NewStore()": "Store"}
+	getSpecCoverage(parseChunkOfCode(t, ginkgoChunkOfCode), nameToTypeMap, map[string][]string{}, coverage)
+
+	assert.Equal(t, set.New("Store › Get › returns not found"), coverage["NewStore"], "calls made in BeforeEach should be linked to every spec it runs for, but pending specs never run")
+	assert.Nil(t, coverage["Store.Put"])
+	assert.Equal(t, set.New("Store › Get › returns not found"), coverage["Store.Get"])
 }
 
 func TestFindHelperFuncs(t *testing.T) {
@@ -1122,6 +1661,361 @@ func TestFindHelperFuncs(t *testing.T) {
 		assert.Equal(t, expected, actual, "expected output did not match actual output")
 	}
 	t.Run("methods", methodsPkg)
+
+	tests := func(t *testing.T) {
+		p := parseChunkOfCode(t, `
+			package main
+			func TestStore(t *testing.T) *Store { return nil }
+			func newTestStore(t *testing.T) *Store { return nil }
+		`)
+
+		actual := map[string][]string{}
+		findHelperFuncs(p, actual, set.New())
+
+		assert.Equal(t, map[string][]string{"newTestStore": {"Store"}}, actual, "tests aren't helpers")
+	}
+	t.Run("tests", tests)
+}
+
+func TestGetCallEdges(t *testing.T) {
+	codeSample := `
+		package main
+
+		type example struct{}
+		func (e example) method() { helper() }
+
+		func helper() {}
+		func wrapper() {
+			var e example
+			e.method()
+			helper()
+		}
+		func empty() {}
+	`
+
+	p := parseChunkOfCode(t, codeSample)
+	nameToTypeMap := map[string]string{}
+	indexTypes(p, nameToTypeMap)
+
+	actual := map[string]*set.Set{}
+	expected := map[string]*set.Set{
+		"example.method": set.New("helper"),
+		"helper":         set.New(),
+		"wrapper":        set.New("example.method", "helper"),
+		"empty":          set.New(),
+	}
+
+	getCallEdges(p, token.NewFileSet(), nameToTypeMap, map[string][]string{}, actual)
+
+	assert.Equal(t, expected, actual, "expected output did not match actual output")
+
+	platformVariants := func(t *testing.T) {
+		fileset := token.NewFileSet()
+		actual := map[string]*set.Set{}
+		for filename, callee := range map[string]string{"open_linux.go": "linuxOnly", "open_windows.go": "windowsOnly"} {
+			p, err := parser.ParseFile(fileset, filename, "package example\nfunc open() { "+callee+"() }", parser.AllErrors)
+			if err != nil {
+				t.Logf("failing because ParseFile returned error: %v", err)
+				t.FailNow()
+			}
+			getCallEdges(p, fileset, map[string]string{}, map[string][]string{}, actual)
+		}
+
+		expected := map[string]*set.Set{
+			"open_linux.go:open":   set.New("linuxOnly"),
+			"open_windows.go:open": set.New("windowsOnly"),
+		}
+		assert.Equal(t, expected, actual, "each variant should keep its own calls")
+	}
+	t.Run("platform variants", platformVariants)
+
+}
+
+func TestImportedNames(t *testing.T) {
+	codeSample := `
+		package example_test
+
+		import (
+			"fmt"
+			"github.com/us/example"
+			ex "github.com/us/example"
+			. "github.com/us/example"
+			_ "github.com/us/example"
+		)
+	`
+
+	expected := []string{"example", "ex"}
+	actual := importedNames(parseChunkOfCode(t, codeSample), "github.com/us/example", "example")
+
+	assert.Equal(t, expected, actual)
+	assert.Empty(t, importedNames(parseChunkOfCode(t, codeSample), "github.com/us/other", "other"))
+}
+
+func TestCreditTestPackages(t *testing.T) {
+	calledByPackage := map[string]*set.Set{
+		"example":      set.New("a", "b"),
+		"example_test": set.New("b", "c"),
+	}
+
+	calledFuncs := set.New("init")
+	actual := creditTestPackages(calledByPackage, calledFuncs)
+
+	expected := map[string]*set.Set{
+		"a": set.New(whiteBoxCredit),
+		"b": set.New(whiteBoxCredit, blackBoxCredit),
+		"c": set.New(blackBoxCredit),
+	}
+	assert.Equal(t, expected, actual)
+	assert.Equal(t, set.New("init", "a", "b", "c"), calledFuncs)
+}
+
+func TestCreditAs(t *testing.T) {
+	calledFuncs := set.New("init", "a")
+	credits := map[string]*set.Set{"a": set.New(whiteBoxCredit)}
+
+	creditAs(set.New("a", "b"), viaInterfaceCredit, calledFuncs, credits)
+
+	expected := map[string]*set.Set{
+		"a": set.New(whiteBoxCredit, viaInterfaceCredit),
+		"b": set.New(viaInterfaceCredit),
+	}
+	assert.Equal(t, expected, credits)
+	assert.Equal(t, set.New("init", "a", "b"), calledFuncs)
+}
+
+func TestDeclaredOnly(t *testing.T) {
+	declaredFuncInfo := map[string]tarpFunc{"main.go:a": {Name: "a"}, "main.go:handler.Close": {Name: "handler.Close"}}
+	actual := declaredOnly(set.New("a", "handler.Close", "handler", "limit"), declaredFuncInfo)
+	assert.Equal(t, set.New("a", "handler.Close"), actual, "types, variables and constants shouldn't be credited")
+}
+
+func TestTestDistances(t *testing.T) {
+	callEdges := map[string]*set.Set{
+		"a": set.New("b"),
+		"b": set.New("c", "a"),
+		"c": set.New(),
+		"d": set.New("a"),
+	}
+
+	declaredFuncInfo := map[string]tarpFunc{"a": {Name: "a"}, "b": {Name: "b"}, "c": {Name: "c"}, "d": {Name: "d"}}
+
+	expected := map[string]int{"a": 1, "b": 2, "c": 3}
+	actual := testDistances(set.New("a"), callEdges, declaredFuncInfo)
+
+	assert.Equal(t, expected, actual, "functions should be given their shortest distance from a test, and unreachable ones left out")
+	assert.Equal(t, map[string]int{"a": 1, "c": 1, "b": 2}, testDistances(set.New("a", "c"), callEdges, declaredFuncInfo))
+
+	platformVariants := func(t *testing.T) {
+		callEdges := map[string]*set.Set{
+			"open_linux.go:open":   set.New("linuxOnly"),
+			"open_windows.go:open": set.New("windowsOnly"),
+			"main.go:A":            set.New("open"),
+		}
+		declaredFuncInfo := map[string]tarpFunc{
+			"open_linux.go:open":          {Name: "open"},
+			"open_windows.go:open":        {Name: "open"},
+			"open_linux.go:linuxOnly":     {Name: "linuxOnly"},
+			"open_windows.go:windowsOnly": {Name: "windowsOnly"},
+			"main.go:A":                   {Name: "A"},
+		}
+
+		expected := map[string]int{
+			"main.go:A":                   1,
+			"open_linux.go:open":          2,
+			"open_windows.go:open":        2,
+			"open_linux.go:linuxOnly":     3,
+			"open_windows.go:windowsOnly": 3,
+		}
+		assert.Equal(t, expected, testDistances(set.New("A"), callEdges, declaredFuncInfo))
+
+		delete(callEdges, "open_windows.go:open")
+		actual := testDistances(set.New("A"), callEdges, declaredFuncInfo)
+		_, ok := actual["open_windows.go:windowsOnly"]
+		assert.False(t, ok, "one variant's calls shouldn't be credited to the other")
+	}
+	t.Run("platform variants", platformVariants)
+
+}
+
+func TestBuildReport(t *testing.T) {
+	declaredFuncInfo := map[string]tarpFunc{
+		"a": {Name: "a"},
+		"b": {Name: "b"},
+	}
+
+	directOnly := func(t *testing.T) {
+		credits := map[string]*set.Set{"a": set.New(whiteBoxCredit, blackBoxCredit)}
+		actual := buildReport(declaredFuncInfo, set.New("init", "a", "fmt.Println"), map[string]*set.Set{}, credits)
+
+		assert.Equal(t, set.New("a", "b"), actual.Declared)
+		assert.Equal(t, set.New("a"), actual.Called, "called names without a matching declaration should be discarded")
+		expected := map[string]tarpFunc{
+			"a": {Name: "a", Distance: 1, Credits: []string{blackBoxCredit, whiteBoxCredit}},
+			"b": {Name: "b", Distance: untestedDistance},
+		}
+		assert.Equal(t, expected, actual.DeclaredDetails)
+	}
+	t.Run("direct calls only", directOnly)
+
+	maxDepthRespected := func(t *testing.T) {
+		callEdges := map[string]*set.Set{"a": set.New("b")}
+
+		actual := buildReport(declaredFuncInfo, set.New("a"), callEdges, map[string]*set.Set{})
+		assert.Equal(t, set.New("a"), actual.Called, "b is two calls away from a test, so shouldn't count with the default max depth")
+		assert.Equal(t, 2, actual.DeclaredDetails["b"].Distance)
+
+		maxDepth = 2
+		defer func() { maxDepth = 1 }()
+		actual = buildReport(declaredFuncInfo, set.New("a"), callEdges, map[string]*set.Set{})
+		assert.Equal(t, set.New("a", "b"), actual.Called)
+	}
+	t.Run("max depth", maxDepthRespected)
+
+	initNotARoot := func(t *testing.T) {
+		declaredFuncInfo := map[string]tarpFunc{
+			"init":   {Name: "init"},
+			"helper": {Name: "helper"},
+		}
+		callEdges := map[string]*set.Set{"init": set.New("helper")}
+
+		maxDepth = 2
+		defer func() { maxDepth = 1 }()
+		actual := buildReport(declaredFuncInfo, set.New("init"), callEdges, map[string]*set.Set{})
+		assert.Equal(t, set.New("init"), actual.Called, "what init calls shouldn't be credited to a test")
+		assert.Equal(t, untestedDistance, actual.DeclaredDetails["helper"].Distance)
+	}
+	t.Run("init", initNotARoot)
+}
+
+func TestAstAnalysis(t *testing.T) {
+	methods := func(t *testing.T) {
+		actual := astAnalysis(buildExamplePackagePath(t, "methods", true))
+
+		assert.Equal(t, set.New("main.go:example.A", "main.go:example.B", "main.go:example.C", "main.go:example.D", "main.go:example.E", "main.go:wrapper"), actual.Called)
+		assert.Equal(t, 7, actual.Declared.Size())
+	}
+	t.Run("methods", methods)
+
+	statements := func(t *testing.T) {
+		actual := astAnalysis(buildExamplePackagePath(t, "statements", true))
+
+		assert.Empty(t, set.StringSlice(set.Difference(actual.Declared, actual.Called)), "expected calls in every statement and expression position to be found")
+	}
+	t.Run("statements", statements)
+
+	external := func(t *testing.T) {
+		actual := astAnalysis(buildExamplePackagePath(t, "external", true))
+
+		assert.Equal(t, set.New("main.go:Untested"), set.Difference(actual.Declared, actual.Called), "calls from external tests through renamed and dot imports should be credited")
+		assert.Equal(t, []string{whiteBoxCredit}, actual.DeclaredDetails["main.go:internal"].Credits)
+		assert.Equal(t, []string{blackBoxCredit}, actual.DeclaredDetails["main.go:Renamed"].Credits)
+		assert.Equal(t, []string{blackBoxCredit}, actual.DeclaredDetails["main.go:counter.Increment"].Credits)
+	}
+	t.Run("external tests", external)
+
+	constraints := func(t *testing.T) {
+		defer func() { buildTags, targetOS = nil, "" }()
+
+		targetOS = "linux"
+		actual := astAnalysis(buildExamplePackagePath(t, "constraints", true))
+		assert.Equal(t, set.New("main.go:name", "main.go:flush", "file_linux.go:open"), actual.Declared)
+		assert.Equal(t, set.New("file_linux.go:open"), actual.Called, "tests behind unsatisfied build tags shouldn't credit anything")
+
+		targetOS, buildTags = "windows", []string{"integration"}
+		actual = astAnalysis(buildExamplePackagePath(t, "constraints", true))
+		assert.Equal(t, set.New("main.go:name", "main.go:flush", "file_windows.go:open"), actual.Declared)
+		assert.Equal(t, set.New("main.go:flush", "file_windows.go:open"), actual.Called)
+	}
+	t.Run("build constraints", constraints)
+
+	references := func(t *testing.T) {
+		creditReferences = true
+		defer func() { creditReferences = false }()
+		actual := astAnalysis(buildExamplePackagePath(t, "references", true))
+
+		assert.Equal(t, set.New("main.go:Untested"), set.Difference(actual.Declared, actual.Called))
+		assert.Equal(t, []string{byReferenceCredit}, actual.DeclaredDetails["main.go:lessByName"].Credits)
+		assert.Equal(t, []string{byReferenceCredit}, actual.DeclaredDetails["main.go:handler.ServeUser"].Credits)
+	}
+	t.Run("credit references", references)
+
+	tables := func(t *testing.T) {
+		actual := astAnalysis(buildExamplePackagePath(t, "tables", true))
+
+		assert.Equal(t, []string{"negative", "positive"}, actual.DeclaredDetails["main.go:Add"].Cases)
+		assert.Equal(t, 1, actual.DeclaredDetails["main.go:Sub"].CaseCount)
+		assert.Equal(t, 2, actual.DeclaredDetails["main.go:Mul"].CaseCount)
+		assert.Equal(t, 0, actual.DeclaredDetails["main.go:Div"].CaseCount)
+	}
+	t.Run("table-driven tests", tables)
+
+	generics := func(t *testing.T) {
+		actual := astAnalysis(buildExamplePackagePath(t, "generics", true))
+
+		assert.Equal(t, set.New("main.go:List.Len", "main.go:Filter"), set.Difference(actual.Declared, actual.Called))
+	}
+	t.Run("generics", generics)
+
+	suites := func(t *testing.T) {
+		actual := astAnalysis(buildExamplePackagePath(t, "suites", true))
+
+		assert.Equal(t, set.New("main.go:Cache.Hits", "main.go:Untested"), set.Difference(actual.Declared, actual.Called))
+	}
+	t.Run("testify suites", suites)
+
+	bdd := func(t *testing.T) {
+		actual := astAnalysis(buildExamplePackagePath(t, "bdd", true))
+
+		assert.Equal(t, set.New("main.go:Store.Delete", "main.go:Store.Clear", "main.go:Parse", "main.go:Untested"), set.Difference(actual.Declared, actual.Called), "pending and skipped specs never run")
+		assert.Equal(t, []string{"Store › Get › returns not found"}, actual.DeclaredDetails["main.go:Store.Get"].Specs)
+		assert.Equal(t, []string{"Format › pads short keys"}, actual.DeclaredDetails["main.go:Format"].Specs)
+	}
+	t.Run("bdd specs", bdd)
+
+	testFuncs := func(t *testing.T) {
+		defer func() { countBenchmarks, countFuzz = true, true }()
+		countBenchmarks, countFuzz = false, false
+		actual := astAnalysis(buildExamplePackagePath(t, "testfuncs", true))
+
+		assert.Equal(t, set.New("main.go:Format", "main.go:Normalize", "main.go:Untested"), set.Difference(actual.Declared, actual.Called))
+		assert.Equal(t, []string{benchmarkFunc, testFunc}, actual.DeclaredDetails["main.go:Parse"].TestFuncs)
+		assert.Equal(t, []string{exampleFunc}, actual.DeclaredDetails["main.go:Validate"].TestFuncs)
+	}
+	t.Run("examples, benchmarks and fuzz targets", testFuncs)
+
+	helpers := func(t *testing.T) {
+		actual := astAnalysis(buildExamplePackagePath(t, "helpers", true))
+
+		assert.Equal(t, set.New("main.go:Abandoned", "main.go:Untested"), set.Difference(actual.Declared, actual.Called), "helpers no test calls shouldn't credit anything")
+		assert.Equal(t, []string{"fill"}, actual.DeclaredDetails["main.go:Store.Put"].Helpers)
+		assert.Nil(t, actual.DeclaredDetails["main.go:Store.Get"].Helpers)
+	}
+	t.Run("test helpers", helpers)
+
+	emptyPackage := func(t *testing.T) {
+		var fatalCalled bool
+		defer func() {
+			// recovered from our monkey patched log.Fatal
+			if r := recover(); r != nil {
+				fatalCalled = true
+			}
+			assert.True(t, fatalCalled, "astAnalysis should call log.Fatal() when there are no go files")
+		}()
+
+		astAnalysis(buildExamplePackagePath(t, "no_go_files", true))
+	}
+	t.Run("empty package", emptyPackage)
+}
+
+func TestAnalyzeDir(t *testing.T) {
+	actual := analyzeDir(buildExamplePackagePath(t, "simple", true))
+	assert.Equal(t, set.New("main.go:a", "main.go:c", "main.go:wrapper"), actual.Called)
+	assert.Equal(t, set.New("main.go:a", "main.go:b", "main.go:c", "main.go:wrapper"), actual.Declared)
+
+	convention = true
+	defer func() { convention = false }()
+	actual = analyzeDir(buildExamplePackagePath(t, "simple", true))
+	assert.Equal(t, conventionEngine, actual.Engine)
 }
 
 func TestAnalyze(t *testing.T) {
@@ -1129,10 +2023,14 @@ func TestAnalyze(t *testing.T) {
 		debug = true
 		simpleMainPath := fmt.Sprintf("%s/main.go", buildExamplePackagePath(t, "simple", true))
 		expected := tarpReport{
+			Engine: typesEngine,
 			DeclaredDetails: map[string]tarpFunc{
-				"a": {
-					Name:     "a",
-					Filename: simpleMainPath,
+				"main.go:a": {
+					Name:      "a",
+					Filename:  simpleMainPath,
+					Distance:  1,
+					Credits:   []string{whiteBoxCredit},
+					TestFuncs: []string{testFunc},
 					DeclPos: token.Position{
 						Filename: simpleMainPath,
 						Offset:   16,
@@ -1152,9 +2050,10 @@ func TestAnalyze(t *testing.T) {
 						Column:   1,
 					},
 				},
-				"b": {
+				"main.go:b": {
 					Name:     "b",
 					Filename: simpleMainPath,
+					Distance: 2,
 					DeclPos: token.Position{
 						Filename: simpleMainPath,
 						Offset:   49,
@@ -1174,9 +2073,12 @@ func TestAnalyze(t *testing.T) {
 						Column:   1,
 					},
 				},
-				"c": {
-					Name:     "c",
-					Filename: simpleMainPath,
+				"main.go:c": {
+					Name:      "c",
+					Filename:  simpleMainPath,
+					Distance:  1,
+					Credits:   []string{whiteBoxCredit},
+					TestFuncs: []string{testFunc},
 					DeclPos: token.Position{
 						Filename: simpleMainPath,
 						Offset:   82,
@@ -1196,9 +2098,12 @@ func TestAnalyze(t *testing.T) {
 						Column:   1,
 					},
 				},
-				"wrapper": {
-					Name:     "wrapper",
-					Filename: simpleMainPath,
+				"main.go:wrapper": {
+					Name:      "wrapper",
+					Filename:  simpleMainPath,
+					Distance:  1,
+					Credits:   []string{whiteBoxCredit},
+					TestFuncs: []string{testFunc},
 					DeclPos: token.Position{
 						Filename: simpleMainPath,
 						Offset:   115,
@@ -1219,8 +2124,8 @@ func TestAnalyze(t *testing.T) {
 					},
 				},
 			},
-			Called:   set.New("a", "c", "wrapper"),
-			Declared: set.New("a", "b", "c", "wrapper"),
+			Called:   set.New("main.go:a", "main.go:c", "main.go:wrapper"),
+			Declared: set.New("main.go:a", "main.go:b", "main.go:c", "main.go:wrapper"),
 		}
 		examplePath := buildExamplePackagePath(t, "simple", false)
 		actual := analyze(examplePath)
@@ -1228,4 +2133,110 @@ func TestAnalyze(t *testing.T) {
 		assert.Equal(t, expected, actual, "expected output did not match actual output")
 	}
 	t.Run("simple", simplePkg)
+
+	maxDepthTest := func(t *testing.T) {
+		defer func() { maxDepth = 1 }()
+		examplePath := buildExamplePackagePath(t, "depth", false)
+
+		for _, engine := range []string{typesEngine, astEngine, callgraphEngine} {
+			analysisEngine = engine
+
+			maxDepth = 1
+			actual := analyze(examplePath)
+			assert.Equal(t, set.New("main.go:top"), actual.Called, "only direct calls should count with --max-depth=1 (engine %q)", engine)
+			assert.Equal(t, 1, actual.DeclaredDetails["main.go:top"].Distance)
+			assert.Equal(t, 2, actual.DeclaredDetails["main.go:middle"].Distance)
+			assert.Equal(t, 3, actual.DeclaredDetails["main.go:bottom"].Distance)
+			assert.Equal(t, untestedDistance, actual.DeclaredDetails["main.go:unreachable"].Distance)
+
+			maxDepth = 2
+			actual = analyze(examplePath)
+			assert.Equal(t, set.New("main.go:top", "main.go:middle"), actual.Called, "calls within two hops should count with --max-depth=2 (engine %q)", engine)
+		}
+		analysisEngine = typesEngine
+	}
+	t.Run("max depth", maxDepthTest)
+
+	initCalls := func(t *testing.T) {
+		maxDepth = 2
+		defer func() { maxDepth = 1 }()
+		examplePath := buildExamplePackagePath(t, "init_calls", false)
+
+		for _, engine := range []string{typesEngine, astEngine, callgraphEngine} {
+			analysisEngine = engine
+
+			actual := analyze(examplePath)
+			assert.Equal(t, set.New("main.go:init", "main.go:Greet"), actual.Called, "functions only called by init shouldn't count as tested (engine %q)", engine)
+			assert.Equal(t, untestedDistance, actual.DeclaredDetails["main.go:helper"].Distance)
+		}
+		analysisEngine = typesEngine
+	}
+	t.Run("init calls", initCalls)
+
+	invalidMaxDepth := func(t *testing.T) {
+		maxDepth = 0
+		var fatalfCalled bool
+		defer func() {
+			maxDepth = 1
+			// recovered from our monkey patched log.Fatalf
+			if r := recover(); r != nil {
+				fatalfCalled = true
+			}
+			assert.True(t, fatalfCalled, "analyze should call log.Fatalf() when --max-depth is less than 1")
+		}()
+
+		analyze(buildExamplePackagePath(t, "simple", false))
+	}
+	t.Run("invalid max depth", invalidMaxDepth)
+
+	astEngineTest := func(t *testing.T) {
+		analysisEngine = astEngine
+		defer func() { analysisEngine = typesEngine }()
+
+		actual := analyze(buildExamplePackagePath(t, "simple", false))
+		assert.Equal(t, set.New("main.go:a", "main.go:c", "main.go:wrapper"), actual.Called)
+	}
+	t.Run("ast engine", astEngineTest)
+
+	callgraphEngineTest := func(t *testing.T) {
+		analysisEngine = callgraphEngine
+		defer func() { analysisEngine = typesEngine }()
+
+		actual := analyze(buildExamplePackagePath(t, "simple", false))
+		assert.Equal(t, callgraphEngine, actual.Engine)
+		assert.Equal(t, set.New("main.go:a", "main.go:c", "main.go:wrapper"), actual.Called)
+	}
+	t.Run("callgraph engine", callgraphEngineTest)
+
+	unknownAlgorithm := func(t *testing.T) {
+		analysisEngine, callgraphAlgorithm = callgraphEngine, "pineapple"
+		var fatalfCalled bool
+		defer func() {
+			analysisEngine, callgraphAlgorithm = typesEngine, constructedAlgorithm
+			// recovered from our monkey patched log.Fatalf
+			if r := recover(); r != nil {
+				fatalfCalled = true
+			}
+			assert.True(t, fatalfCalled, "analyze should call log.Fatalf() when given an unknown call graph algorithm")
+		}()
+
+		analyze(buildExamplePackagePath(t, "simple", false))
+	}
+	t.Run("unknown call graph algorithm", unknownAlgorithm)
+
+	unknownEngine := func(t *testing.T) {
+		analysisEngine = "pineapple"
+		var fatalfCalled bool
+		defer func() {
+			analysisEngine = typesEngine
+			// recovered from our monkey patched log.Fatalf
+			if r := recover(); r != nil {
+				fatalfCalled = true
+			}
+			assert.True(t, fatalfCalled, "analyze should call log.Fatalf() when given an unknown engine")
+		}()
+
+		analyze(buildExamplePackagePath(t, "simple", false))
+	}
+	t.Run("unknown engine", unknownEngine)
 }
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/fatih/set"
+)
+
+// defaultBaselineFile is where `tarp baseline write` records the functions without direct unit tests,
+// unless it's given a --baseline file.
+const defaultBaselineFile = ".tarp-baseline.json"
+
+// tarpBaseline is what gets recorded in a baseline file: the functions without direct unit tests at the time
+// it was written, which analyze --baseline doesn't fail on.
+type tarpBaseline struct {
+	Untested []baselineEntry `json:"untested"`
+}
+
+// baselineEntry is a function recorded in a baseline. Its fingerprint is a hash of its qualified name and its
+// normalized body, so the entry stops matching once the function is changed, but not when it's moved,
+// reformatted or has its comments edited.
+type baselineEntry struct {
+	Package     string `json:"package"`
+	Function    string `json:"function"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// normalizedBody returns the body of the given function as the printer formats it, without comments, and with
+// every run of whitespace collapsed into a single space.
+func normalizedBody(f *ast.FuncDecl, fileset *token.FileSet) string {
+	if f.Body == nil {
+		return ""
+	}
+	var buf bytes.Buffer
+	// printing a node on its own, rather than the file it belongs to, leaves its comments out
+	printer.Fprint(&buf, fileset, f.Body)
+	return strings.Join(strings.Fields(buf.String()), " ")
+}
+
+// fingerprint hashes the given function's qualified name along with its normalized body.
+func fingerprint(qualifiedName, body string) string {
+	sum := sha256.Sum256([]byte(qualifiedName + "\n" + body))
+	return hex.EncodeToString(sum[:8])
+}
+
+// normalizedBodies returns the normalized body of every function declared in the given file, keyed by name.
+func normalizedBodies(filename string) map[string]string {
+	fileset := token.NewFileSet()
+	in, err := parser.ParseFile(fileset, filename, nil, parser.AllErrors)
+	if err != nil {
+		fatalf(exitLoadError, "error encountered parsing %s: %v", filename, err)
+	}
+	bodies := map[string]string{}
+	for _, d := range in.Decls {
+		if f, ok := d.(*ast.FuncDecl); ok {
+			bodies[parseFuncDecl(f)] = normalizedBody(f, fileset)
+		}
+	}
+	return bodies
+}
+
+// sortBaselineEntries sorts the given entries by package and function, so baseline files diff cleanly.
+func sortBaselineEntries(entries []baselineEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Package != entries[j].Package {
+			return entries[i].Package < entries[j].Package
+		}
+		if entries[i].Function != entries[j].Function {
+			return entries[i].Function < entries[j].Function
+		}
+		return entries[i].Fingerprint < entries[j].Fingerprint
+	})
+}
+
+// untestedEntries returns a baseline entry for every function without direct unit tests in the given
+// reports. Functions reported more than once, like by a --matrix run, only get one.
+func untestedEntries(outputs []tarpOutput) []baselineEntry {
+	entries, seen, bodies := []baselineEntry{}, set.New(), map[string]map[string]string{}
+	for _, output := range outputs {
+		for filename, funcs := range output.Details {
+			if _, ok := bodies[filename]; !ok {
+				bodies[filename] = normalizedBodies(filename)
+			}
+			for _, tf := range funcs {
+				qualifiedName := output.Package + "." + tf.Name
+				entry := baselineEntry{
+					Package:     output.Package,
+					Function:    tf.Name,
+					Fingerprint: fingerprint(qualifiedName, bodies[filename][tf.Name]),
+				}
+				if !seen.Has(entry.Fingerprint) {
+					seen.Add(entry.Fingerprint)
+					entries = append(entries, entry)
+				}
+			}
+		}
+	}
+	sortBaselineEntries(entries)
+	return entries
+}
+
+// analyzedPackages returns the names of the packages the given reports cover.
+func analyzedPackages(outputs []tarpOutput) *set.Set {
+	packages := set.New()
+	for _, output := range outputs {
+		packages.Add(output.Package)
+	}
+	return packages
+}
+
+// readBaseline reads the given baseline file.
+func readBaseline(filename string) tarpBaseline {
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		fatalf(exitLoadError, "error reading baseline: %v", err)
+	}
+	baseline := tarpBaseline{}
+	if err := json.Unmarshal(contents, &baseline); err != nil {
+		fatalf(exitUsageError, "error reading baseline %s: %v", filename, err)
+	}
+	return baseline
+}
+
+// writeBaseline writes the given baseline to the given file, sorted, so it can be committed.
+func writeBaseline(filename string, baseline tarpBaseline) {
+	sortBaselineEntries(baseline.Untested)
+	// the baseline is plain data, so it can always be marshalled
+	out, _ := json.MarshalIndent(baseline, "", "  ")
+	if err := ioutil.WriteFile(filename, append(out, '\n'), 0644); err != nil {
+		fatalf(exitLoadError, "error writing baseline: %v", err)
+	}
+}
+
+// buildBaseline returns a baseline of the functions without direct unit tests in the given reports. The
+// entries an existing baseline has for packages the reports don't cover are kept.
+func buildBaseline(existing tarpBaseline, outputs []tarpOutput) tarpBaseline {
+	packages := analyzedPackages(outputs)
+	baseline := tarpBaseline{Untested: untestedEntries(outputs)}
+	for _, entry := range existing.Untested {
+		if !packages.Has(entry.Package) {
+			baseline.Untested = append(baseline.Untested, entry)
+		}
+	}
+	sortBaselineEntries(baseline.Untested)
+	return baseline
+}
+
+// compareBaseline returns the functions without direct unit tests that the baseline doesn't record, and the
+// entries of the baseline that no longer match any, because their functions have been tested, changed or
+// removed since. Only the entries for the packages the reports cover can be known not to match.
+func compareBaseline(baseline tarpBaseline, outputs []tarpOutput) ([]baselineEntry, []baselineEntry) {
+	current, recorded := set.New(), set.New()
+	untested := untestedEntries(outputs)
+	for _, entry := range untested {
+		current.Add(entry.Fingerprint)
+	}
+	for _, entry := range baseline.Untested {
+		recorded.Add(entry.Fingerprint)
+	}
+
+	gaps, fixed := []baselineEntry{}, []baselineEntry{}
+	for _, entry := range untested {
+		if !recorded.Has(entry.Fingerprint) {
+			gaps = append(gaps, entry)
+		}
+	}
+	packages := analyzedPackages(outputs)
+	for _, entry := range baseline.Untested {
+		if packages.Has(entry.Package) && !current.Has(entry.Fingerprint) {
+			fixed = append(fixed, entry)
+		}
+	}
+	return gaps, fixed
+}
+
+// checkBaseline returns a message for each function without direct unit tests in the given reports that the
+// given baseline file doesn't record. Entries of the baseline that no longer match are removed from it when
+// --update-baseline is set, and pointed out otherwise, so the baseline only ever gets tighter.
+func checkBaseline(filename string, outputs []tarpOutput) []string {
+	baseline := readBaseline(filename)
+	gaps, fixed := compareBaseline(baseline, outputs)
+
+	changed := set.New()
+	for _, entry := range fixed {
+		changed.Add(entry.Package + "." + entry.Function)
+	}
+	breaches := []string{}
+	for _, entry := range gaps {
+		if qualifiedName := entry.Package + "." + entry.Function; changed.Has(qualifiedName) {
+			breaches = append(breaches, fmt.Sprintf("%s has changed since the baseline was written, and has no direct unit tests", qualifiedName))
+		} else {
+			breaches = append(breaches, fmt.Sprintf("%s has no direct unit tests, and isn't in the baseline", qualifiedName))
+		}
+	}
+
+	if len(fixed) > 0 {
+		if updateBaseline {
+			stale := set.New()
+			for _, entry := range fixed {
+				stale.Add(entry.Fingerprint)
+			}
+			tightened := tarpBaseline{Untested: []baselineEntry{}}
+			for _, entry := range baseline.Untested {
+				if !stale.Has(entry.Fingerprint) {
+					tightened.Untested = append(tightened.Untested, entry)
+				}
+			}
+			writeBaseline(filename, tightened)
+			log.Printf("removed %d functions that no longer lack direct unit tests from %s", len(fixed), filename)
+		} else {
+			log.Printf("%d functions in %s no longer lack direct unit tests, or have changed since it was written; pass --update-baseline to remove them", len(fixed), filename)
+		}
+	}
+	return breaches
+}
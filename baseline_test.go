@@ -0,0 +1,255 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////
+//                                                    //
+//               Test Helper Functions                //
+//                                                    //
+////////////////////////////////////////////////////////
+
+// buildBaselineOutputs returns reports for the simple example package, where b has no direct unit tests, and
+// for a package where every function has them.
+func buildBaselineOutputs() []tarpOutput {
+	return []tarpOutput{
+		{
+			Package: "example/simple",
+			Details: map[string][]tarpFunc{
+				"example_packages/simple/main.go": {{Name: "b", Filename: "example_packages/simple/main.go"}},
+			},
+		},
+		{
+			Package: "example/methods",
+			Details: map[string][]tarpFunc{},
+		},
+	}
+}
+
+// buildBaselineFile writes the given baseline to a temporary file, and returns its name.
+func buildBaselineFile(t *testing.T, baseline tarpBaseline) string {
+	t.Helper()
+	f, err := ioutil.TempFile("", "tarp_baseline")
+	if err != nil {
+		t.Logf("error encountered creating temp file: %v", err)
+		t.FailNow()
+	}
+	f.Close()
+	writeBaseline(f.Name(), baseline)
+	return f.Name()
+}
+
+// exitFrom returns what our monkey patched os.Exit panicked with when f exited, or nil if it didn't.
+func exitFrom(f func()) (out interface{}) {
+	defer func() { out = recover() }()
+	f()
+	return nil
+}
+
+////////////////////////////////////////////////////////
+//                                                    //
+//                   Actual Tests                     //
+//                                                    //
+////////////////////////////////////////////////////////
+
+func TestNormalizedBody(t *testing.T) {
+	src := `package example
+
+func a() string {
+	// comments aren't part of the body
+	x :=   "A"
+
+	return x
+}
+
+func b() string
+
+func c() string { x := "A"; return x }
+`
+	fileset := token.NewFileSet()
+	in, err := parser.ParseFile(fileset, "example.go", src, parser.ParseComments)
+	if err != nil {
+		t.Logf("failing because ParseFile returned error: %v", err)
+		t.FailNow()
+	}
+
+	a, b, c := in.Decls[0].(*ast.FuncDecl), in.Decls[1].(*ast.FuncDecl), in.Decls[2].(*ast.FuncDecl)
+	assert.Equal(t, `{ x := "A" return x }`, normalizedBody(a, fileset))
+	assert.Equal(t, "", normalizedBody(b, fileset), "functions without bodies should have empty ones")
+	assert.Equal(t, normalizedBody(a, fileset), normalizedBody(c, fileset), "formatting shouldn't matter")
+}
+
+func TestFingerprint(t *testing.T) {
+	expected := fingerprint("example/simple.b", `{ return "B" }`)
+	assert.Len(t, expected, 16)
+	assert.Equal(t, expected, fingerprint("example/simple.b", `{ return "B" }`))
+	assert.NotEqual(t, expected, fingerprint("example/simple.c", `{ return "B" }`), "names should matter")
+	assert.NotEqual(t, expected, fingerprint("example/simple.b", `{ return "C" }`), "bodies should matter")
+}
+
+func TestNormalizedBodies(t *testing.T) {
+	expected := map[string]string{
+		"a":       `{ return "A" }`,
+		"b":       `{ return "B" }`,
+		"c":       `{ return "C" }`,
+		"wrapper": "{ a() b() c() }",
+	}
+	assert.Equal(t, expected, normalizedBodies("example_packages/simple/main.go"))
+	assert.Equal(t, `{ return "A" }`, normalizedBodies("example_packages/methods/main.go")["example.A"], "methods should be named like tarp names them")
+	assert.Panics(t, func() { normalizedBodies("example_packages/nonexistent/main.go") }, "unreadable files should be fatal")
+}
+
+func TestSortBaselineEntries(t *testing.T) {
+	actual := []baselineEntry{
+		{Package: "b", Function: "a", Fingerprint: "1"},
+		{Package: "a", Function: "b", Fingerprint: "2"},
+		{Package: "a", Function: "b", Fingerprint: "1"},
+		{Package: "a", Function: "a", Fingerprint: "3"},
+	}
+	expected := []baselineEntry{
+		{Package: "a", Function: "a", Fingerprint: "3"},
+		{Package: "a", Function: "b", Fingerprint: "1"},
+		{Package: "a", Function: "b", Fingerprint: "2"},
+		{Package: "b", Function: "a", Fingerprint: "1"},
+	}
+	sortBaselineEntries(actual)
+	assert.Equal(t, expected, actual)
+}
+
+func TestUntestedEntries(t *testing.T) {
+	expected := []baselineEntry{{Package: "example/simple", Function: "b", Fingerprint: fingerprint("example/simple.b", `{ return "B" }`)}}
+	assert.Equal(t, expected, untestedEntries(buildBaselineOutputs()))
+
+	outputs := append(buildBaselineOutputs(), buildBaselineOutputs()...)
+	assert.Equal(t, expected, untestedEntries(outputs), "functions reported more than once should only get one entry")
+}
+
+func TestAnalyzedPackages(t *testing.T) {
+	actual := analyzedPackages(buildBaselineOutputs())
+	assert.Equal(t, 2, actual.Size())
+	assert.True(t, actual.Has("example/simple", "example/methods"))
+}
+
+func TestReadBaseline(t *testing.T) {
+	expected := tarpBaseline{Untested: untestedEntries(buildBaselineOutputs())}
+	filename := buildBaselineFile(t, expected)
+	defer os.Remove(filename)
+
+	assert.Equal(t, expected, readBaseline(filename))
+
+	ioutil.WriteFile(filename, []byte("{"), 0644)
+	assert.Equal(t, "os.Exit(64)", exitFrom(func() { readBaseline(filename) }), "invalid baselines should be a usage error")
+	assert.Equal(t, "os.Exit(66)", exitFrom(func() { readBaseline(filename + ".missing") }), "missing baselines should be a load error")
+}
+
+func TestWriteBaseline(t *testing.T) {
+	filename := buildBaselineFile(t, tarpBaseline{Untested: []baselineEntry{
+		{Package: "example/simple", Function: "c", Fingerprint: "2"},
+		{Package: "example/simple", Function: "b", Fingerprint: "1"},
+	}})
+	defer os.Remove(filename)
+
+	expected := `{
+  "untested": [
+    {
+      "package": "example/simple",
+      "function": "b",
+      "fingerprint": "1"
+    },
+    {
+      "package": "example/simple",
+      "function": "c",
+      "fingerprint": "2"
+    }
+  ]
+}
+`
+	actual, _ := ioutil.ReadFile(filename)
+	assert.Equal(t, expected, string(actual), "baselines should be written sorted")
+
+	assert.Equal(t, "os.Exit(66)", exitFrom(func() { writeBaseline(filepath.Join(filename, "nested"), tarpBaseline{}) }), "unwritable baselines should be a load error")
+}
+
+func TestBuildBaseline(t *testing.T) {
+	existing := tarpBaseline{Untested: []baselineEntry{
+		{Package: "example/simple", Function: "c", Fingerprint: "1"},
+		{Package: "example/methods", Function: "example.A", Fingerprint: "2"},
+		{Package: "example/other", Function: "a", Fingerprint: "3"},
+	}}
+
+	expected := tarpBaseline{Untested: []baselineEntry{
+		{Package: "example/other", Function: "a", Fingerprint: "3"},
+		untestedEntries(buildBaselineOutputs())[0],
+	}}
+	assert.Equal(t, expected, buildBaseline(existing, buildBaselineOutputs()), "only the entries for packages that weren't analyzed should be kept")
+}
+
+func TestCompareBaseline(t *testing.T) {
+	current := untestedEntries(buildBaselineOutputs())
+	baseline := tarpBaseline{Untested: []baselineEntry{
+		{Package: "example/simple", Function: "a", Fingerprint: "1"},
+		{Package: "example/other", Function: "a", Fingerprint: "2"},
+	}}
+
+	gaps, fixed := compareBaseline(baseline, buildBaselineOutputs())
+	assert.Equal(t, current, gaps)
+	assert.Equal(t, baseline.Untested[:1], fixed, "entries for packages that weren't analyzed can't be known to be fixed")
+
+	gaps, fixed = compareBaseline(tarpBaseline{Untested: current}, buildBaselineOutputs())
+	assert.Empty(t, gaps)
+	assert.Empty(t, fixed)
+}
+
+func TestCheckBaseline(t *testing.T) {
+	current := untestedEntries(buildBaselineOutputs())
+
+	matching := func(t *testing.T) {
+		filename := buildBaselineFile(t, tarpBaseline{Untested: current})
+		defer os.Remove(filename)
+
+		assert.Empty(t, checkBaseline(filename, buildBaselineOutputs()))
+	}
+	t.Run("matching", matching)
+
+	gaps := func(t *testing.T) {
+		filename := buildBaselineFile(t, tarpBaseline{Untested: []baselineEntry{}})
+		defer os.Remove(filename)
+
+		expected := []string{"example/simple.b has no direct unit tests, and isn't in the baseline"}
+		assert.Equal(t, expected, checkBaseline(filename, buildBaselineOutputs()))
+	}
+	t.Run("new gaps", gaps)
+
+	changed := func(t *testing.T) {
+		filename := buildBaselineFile(t, tarpBaseline{Untested: []baselineEntry{{Package: "example/simple", Function: "b", Fingerprint: "1"}}})
+		defer os.Remove(filename)
+
+		expected := []string{"example/simple.b has changed since the baseline was written, and has no direct unit tests"}
+		assert.Equal(t, expected, checkBaseline(filename, buildBaselineOutputs()))
+	}
+	t.Run("changed functions", changed)
+
+	tightened := func(t *testing.T) {
+		fixed := baselineEntry{Package: "example/methods", Function: "example.A", Fingerprint: "1"}
+		filename := buildBaselineFile(t, tarpBaseline{Untested: append([]baselineEntry{fixed}, current...)})
+		defer os.Remove(filename)
+
+		assert.Empty(t, checkBaseline(filename, buildBaselineOutputs()))
+		assert.Contains(t, readBaseline(filename).Untested, fixed, "the baseline should only be tightened when asked to")
+
+		updateBaseline = true
+		defer func() { updateBaseline = false }()
+		assert.Empty(t, checkBaseline(filename, buildBaselineOutputs()))
+		assert.Equal(t, current, readBaseline(filename).Untested)
+	}
+	t.Run("tightening", tightened)
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/set"
+)
+
+// bddImportPaths are the import paths of the BDD style test frameworks whose specs tarp understands.
+var bddImportPaths = []string{
+	"github.com/onsi/ginkgo",
+	"github.com/onsi/ginkgo/v2",
+	"github.com/smartystreets/goconvey/convey",
+}
+
+// specSeparator joins the description of a spec to the descriptions of the containers it's nested in.
+const specSeparator = " › "
+
+// bddSpec is a single spec of a BDD style test, like a Ginkgo It or an innermost GoConvey Convey block. Nodes
+// holds its body, preceded by the statements of every container it's nested in, since those run for each of
+// their specs.
+type bddSpec struct {
+	path  string
+	nodes []ast.Node
+}
+
+// isSpecContainer reports whether a Ginkgo function with the given name groups specs together.
+func isSpecContainer(name string) bool {
+	switch strings.TrimLeft(name, "FPX") {
+	case "Describe", "Context", "When":
+		return true
+	}
+	return false
+}
+
+// isSpecLeaf reports whether a Ginkgo function with the given name declares a spec.
+func isSpecLeaf(name string) bool {
+	switch strings.TrimLeft(name, "FPX") {
+	case "It", "Specify":
+		return true
+	}
+	return false
+}
+
+// isConveyBlock reports whether a GoConvey function with the given name declares a block. Blocks without
+// blocks nested in them are specs, the rest are containers.
+func isConveyBlock(name string) bool {
+	switch name {
+	case "Convey", "FocusConvey", "SkipConvey":
+		return true
+	}
+	return false
+}
+
+// isSkippedNode reports whether a BDD framework function with the given name declares a container or spec
+// that never runs, like Ginkgo's pending and excluded PIt and XDescribe, or GoConvey's SkipConvey.
+func isSkippedNode(name string) bool {
+	if name == "SkipConvey" {
+		return true
+	}
+	return (isSpecContainer(name) || isSpecLeaf(name)) && (strings.HasPrefix(name, "P") || strings.HasPrefix(name, "X"))
+}
+
+// bddImports returns the names the given file refers to the BDD frameworks by, and whether it dot imports
+// any of them, in which case their functions are called without a qualifier.
+func bddImports(in *ast.File) (*set.Set, bool) {
+	qualifiers, dotImported := set.New(), false
+	for _, spec := range in.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		for _, bddPath := range bddImportPaths {
+			if importPath != bddPath {
+				continue
+			}
+			switch {
+			case spec.Name == nil:
+				qualifiers.Add(path.Base(strings.TrimSuffix(importPath, "/v2")))
+			case spec.Name.Name == ".":
+				dotImported = true
+			case spec.Name.Name != "_":
+				qualifiers.Add(spec.Name.Name)
+			}
+		}
+	}
+	return qualifiers, dotImported
+}
+
+// bddCallName returns the name of the container or spec a call to one of the BDD frameworks declares, or ""
+// if the call declares neither.
+func bddCallName(call *ast.CallExpr, qualifiers *set.Set, dotImported bool) string {
+	var name string
+	switch f := call.Fun.(type) {
+	case *ast.Ident:
+		if !dotImported {
+			return ""
+		}
+		name = f.Name
+	case *ast.SelectorExpr:
+		if pkg, ok := f.X.(*ast.Ident); !ok || !qualifiers.Has(pkg.Name) {
+			return ""
+		}
+		name = f.Sel.Name
+	default:
+		return ""
+	}
+
+	if isSpecContainer(name) || isSpecLeaf(name) || isConveyBlock(name) {
+		return name
+	}
+	return ""
+}
+
+// specDescription returns the description a container or spec is declared with.
+func specDescription(call *ast.CallExpr) string {
+	if len(call.Args) == 0 {
+		return ""
+	}
+	if s, ok := stringLit(call.Args[0]); ok {
+		return s
+	}
+	return types.ExprString(call.Args[0])
+}
+
+// specBody returns the function literal holding the body of a container or spec, which is its last one.
+func specBody(call *ast.CallExpr) *ast.FuncLit {
+	for i := len(call.Args) - 1; i >= 0; i-- {
+		if lit, ok := call.Args[i].(*ast.FuncLit); ok {
+			return lit
+		}
+	}
+	return nil
+}
+
+// bddCalls returns the outermost calls to the BDD frameworks that declare containers or specs in the given node.
+func bddCalls(in ast.Node, qualifiers *set.Set, dotImported bool) []*ast.CallExpr {
+	out := []*ast.CallExpr{}
+	ast.Inspect(in, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok && bddCallName(call, qualifiers, dotImported) != "" {
+			out = append(out, call)
+			return false
+		}
+		return true
+	})
+	return out
+}
+
+// dropSkippedSpecs empties the bodies of the containers and specs in the given file that never run, so that
+// nothing they call is credited to a test, whichever engine reads the file.
+func dropSkippedSpecs(in *ast.File) {
+	qualifiers, dotImported := bddImports(in)
+	if qualifiers.Size() == 0 && !dotImported {
+		return
+	}
+	ast.Inspect(in, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || !isSkippedNode(bddCallName(call, qualifiers, dotImported)) {
+			return true
+		}
+		for _, arg := range call.Args {
+			if lit, ok := arg.(*ast.FuncLit); ok {
+				lit.Body.List = nil
+			}
+		}
+		return false
+	})
+}
+
+// findSpecs returns the specs of the BDD style tests in the given file, each named by its path through the
+// containers it's nested in, like "Store › Get › returns not found". Specs that never run, because they're
+// pending, skipped or have no body, are left out.
+func findSpecs(in *ast.File) []bddSpec {
+	specs := []bddSpec{}
+	qualifiers, dotImported := bddImports(in)
+	if qualifiers.Size() == 0 && !dotImported {
+		return specs
+	}
+
+	var visit func(call *ast.CallExpr, parents []string, setup []ast.Node)
+	visit = func(call *ast.CallExpr, parents []string, setup []ast.Node) {
+		name, body := bddCallName(call, qualifiers, dotImported), specBody(call)
+		if body == nil || isSkippedNode(name) {
+			return
+		}
+		description := specDescription(call)
+		specPath := append(append([]string{}, parents...), description)
+		children := bddCalls(body.Body, qualifiers, dotImported)
+		if isSpecLeaf(name) || (isConveyBlock(name) && len(children) == 0) {
+			nodes := append(append([]ast.Node{}, setup...), body.Body)
+			specs = append(specs, bddSpec{path: strings.Join(specPath, specSeparator), nodes: nodes})
+			return
+		}
+
+		// whatever a container does besides declaring what's nested in it, like a BeforeEach, runs for its specs
+		nested := append([]ast.Node{}, setup...)
+		for _, stmt := range body.Body.List {
+			if es, ok := stmt.(*ast.ExprStmt); ok {
+				if c, ok := es.X.(*ast.CallExpr); ok && bddCallName(c, qualifiers, dotImported) != "" {
+					continue
+				}
+			}
+			nested = append(nested, stmt)
+		}
+		for _, child := range children {
+			visit(child, specPath, nested)
+		}
+	}
+
+	for _, d := range in.Decls {
+		for _, call := range bddCalls(d, qualifiers, dotImported) {
+			visit(call, nil, nil)
+		}
+	}
+	return specs
+}
+
+// recordSpecCoverage links every function called by a spec to the spec's path.
+func recordSpecCoverage(spec bddSpec, called *set.Set, coverage map[string]*set.Set) {
+	for _, name := range set.StringSlice(called) {
+		if _, ok := coverage[name]; !ok {
+			coverage[name] = set.New()
+		}
+		coverage[name].Add(spec.path)
+	}
+}
+
+// applySpecCoverage records the paths of the specs that exercise each declared function.
+func applySpecCoverage(declaredFuncInfo map[string]tarpFunc, coverage map[string]*set.Set) {
+	for key, f := range declaredFuncInfo {
+		if paths, ok := coverage[f.Name]; ok {
+			f.Specs = set.StringSlice(paths)
+			sort.Strings(f.Specs)
+			declaredFuncInfo[key] = f
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"go/ast"
+	"testing"
+
+	"github.com/fatih/set"
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////
+//                                                    //
+//               Test Helper Functions                //
+//                                                    //
+////////////////////////////////////////////////////////
+
+const ginkgoChunkOfCode = `
+	package main
+
+	import . "github.com/onsi/ginkgo/v2"
+
+	var _ = Describe("Store", func() {
+		var store *Store
+		BeforeEach(func() {
+			store = NewStore()
+		})
+
+		Describe("Get", func() {
+			It("returns not found", func() {
+				store.Get("missing")
+			})
+			It("is pending")
+		})
+
+		Context("Put", func() {
+			PIt("stores the value", func() {
+				store.Put("key", "value")
+			})
+		})
+	})
+`
+
+// calledNames returns the names of the functions and methods called anywhere in the given node.
+func calledNames(in ast.Node) *set.Set {
+	called := set.New()
+	ast.Inspect(in, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			switch f := call.Fun.(type) {
+			case *ast.Ident:
+				called.Add(f.Name)
+			case *ast.SelectorExpr:
+				called.Add(f.Sel.Name)
+			}
+		}
+		return true
+	})
+	return called
+}
+
+func specPaths(in []bddSpec) []string {
+	out := []string{}
+	for _, spec := range in {
+		out = append(out, spec.path)
+	}
+	return out
+}
+
+////////////////////////////////////////////////////////
+//                                                    //
+//                   Actual Tests                     //
+//                                                    //
+////////////////////////////////////////////////////////
+
+func TestIsSpecContainer(t *testing.T) {
+	assert.True(t, isSpecContainer("Describe"))
+	assert.True(t, isSpecContainer("FContext"))
+	assert.True(t, isSpecContainer("XWhen"))
+	assert.False(t, isSpecContainer("It"))
+	assert.False(t, isSpecContainer("BeforeEach"))
+}
+
+func TestIsSpecLeaf(t *testing.T) {
+	assert.True(t, isSpecLeaf("It"))
+	assert.True(t, isSpecLeaf("PSpecify"))
+	assert.False(t, isSpecLeaf("Describe"))
+	assert.False(t, isSpecLeaf("Convey"))
+}
+
+func TestIsConveyBlock(t *testing.T) {
+	assert.True(t, isConveyBlock("Convey"))
+	assert.True(t, isConveyBlock("FocusConvey"))
+	assert.False(t, isConveyBlock("So"))
+}
+
+func TestIsSkippedNode(t *testing.T) {
+	assert.True(t, isSkippedNode("PIt"))
+	assert.True(t, isSkippedNode("XIt"))
+	assert.True(t, isSkippedNode("PDescribe"))
+	assert.True(t, isSkippedNode("XContext"))
+	assert.True(t, isSkippedNode("SkipConvey"))
+	assert.False(t, isSkippedNode("It"))
+	assert.False(t, isSkippedNode("FDescribe"))
+	assert.False(t, isSkippedNode("Convey"))
+	assert.False(t, isSkippedNode("PBeforeEach"), "only containers and specs can be pending")
+}
+
+func TestDropSkippedSpecs(t *testing.T) {
+	p := parseChunkOfCode(t, ginkgoChunkOfCode)
+	dropSkippedSpecs(p)
+
+	called := calledNames(p)
+	assert.True(t, called.Has("Get"))
+	assert.False(t, called.Has("Put"), "the body of a pending spec should be emptied")
+
+	convey := parseChunkOfCode(t, `
+		package main
+
+		import "github.com/smartystreets/goconvey/convey"
+
+		func TestFormat(t *testing.T) {
+			convey.SkipConvey("Format", t, func() {
+				Format("a", 1)
+			})
+		}
+	`)
+	dropSkippedSpecs(convey)
+	assert.False(t, calledNames(convey).Has("Format"), "the body of a skipped block should be emptied")
+}
+
+func TestBddImports(t *testing.T) {
+	p := parseChunkOfCode(t, `
+		package main
+		import (
+			"github.com/onsi/ginkgo/v2"
+			c "github.com/smartystreets/goconvey/convey"
+			_ "github.com/onsi/ginkgo"
+			"github.com/stretchr/testify/suite"
+		)
+	`)
+	qualifiers, dotImported := bddImports(p)
+	assert.Equal(t, set.New("ginkgo", "c"), qualifiers)
+	assert.False(t, dotImported)
+
+	p = parseChunkOfCode(t, ginkgoChunkOfCode)
+	qualifiers, dotImported = bddImports(p)
+	assert.Equal(t, 0, qualifiers.Size())
+	assert.True(t, dotImported)
+}
+
+func TestBddCallName(t *testing.T) {
+	p := parseChunkOfCode(t, `
+		package main
+		func main() {
+			Describe("x", func() {})
+			ginkgo.It("x", func() {})
+			other.It("x", func() {})
+			BeforeEach(func() {})
+			(It)("x", func() {})
+		}
+	`)
+	body := p.Decls[0].(*ast.FuncDecl).Body.List
+	callAt := func(i int) *ast.CallExpr {
+		return body[i].(*ast.ExprStmt).X.(*ast.CallExpr)
+	}
+
+	qualifiers := set.New("ginkgo")
+	assert.Equal(t, "Describe", bddCallName(callAt(0), qualifiers, true))
+	assert.Equal(t, "", bddCallName(callAt(0), qualifiers, false), "unqualified calls only count when the framework is dot imported")
+	assert.Equal(t, "It", bddCallName(callAt(1), qualifiers, false))
+	assert.Equal(t, "", bddCallName(callAt(2), qualifiers, true))
+	assert.Equal(t, "", bddCallName(callAt(3), qualifiers, true), "hooks aren't containers or specs")
+	assert.Equal(t, "", bddCallName(callAt(4), qualifiers, true))
+}
+
+func TestSpecDescription(t *testing.T) {
+	p := parseChunkOfCode(t, `
+		package main
+		func main() {
+			It("returns not found", func() {})
+			It(description, func() {})
+			It()
+		}
+	`)
+	body := p.Decls[0].(*ast.FuncDecl).Body.List
+
+	assert.Equal(t, "returns not found", specDescription(body[0].(*ast.ExprStmt).X.(*ast.CallExpr)))
+	assert.Equal(t, "description", specDescription(body[1].(*ast.ExprStmt).X.(*ast.CallExpr)))
+	assert.Equal(t, "", specDescription(body[2].(*ast.ExprStmt).X.(*ast.CallExpr)))
+}
+
+func TestSpecBody(t *testing.T) {
+	p := parseChunkOfCode(t, `
+		package main
+		func main() {
+			Convey("x", t, func() {})
+			It("pending")
+		}
+	`)
+	body := p.Decls[0].(*ast.FuncDecl).Body.List
+
+	assert.NotNil(t, specBody(body[0].(*ast.ExprStmt).X.(*ast.CallExpr)))
+	assert.Nil(t, specBody(body[1].(*ast.ExprStmt).X.(*ast.CallExpr)))
+}
+
+func TestBddCalls(t *testing.T) {
+	p := parseChunkOfCode(t, ginkgoChunkOfCode)
+
+	actual := bddCalls(p, set.New(), true)
+	assert.Len(t, actual, 1, "only the outermost calls should be returned")
+	assert.Equal(t, "Store", specDescription(actual[0]))
+	assert.Empty(t, bddCalls(p, set.New(), false))
+}
+
+func TestFindSpecs(t *testing.T) {
+	ginkgo := func(t *testing.T) {
+		p := parseChunkOfCode(t, ginkgoChunkOfCode)
+		actual := findSpecs(p)
+
+		assert.Equal(t, []string{"Store › Get › returns not found"}, specPaths(actual), "pending specs, and specs without bodies, never run")
+		assert.Len(t, actual[0].nodes, 3, "the containers' statements should run before each spec")
+	}
+	t.Run("ginkgo", ginkgo)
+
+	convey := func(t *testing.T) {
+		p := parseChunkOfCode(t, `
+			package main
+
+			import "github.com/smartystreets/goconvey/convey"
+
+			func TestFormat(t *testing.T) {
+				convey.Convey("Format", t, func() {
+					key := "a"
+					convey.Convey("pads short keys", func() {
+						Format(key, 1)
+					})
+					convey.Convey("with long keys", func() {
+						convey.Convey("truncates them", func() {})
+					})
+				})
+			}
+		`)
+		actual := findSpecs(p)
+
+		assert.Equal(t, []string{"Format › pads short keys", "Format › with long keys › truncates them"}, specPaths(actual))
+	}
+	t.Run("goconvey", convey)
+
+	noFramework := func(t *testing.T) {
+		p := parseChunkOfCode(t, `
+			package main
+			func TestDescribe(t *testing.T) {
+				Describe("x", func() {
+					It("y", func() {})
+				})
+			}
+		`)
+		assert.Empty(t, findSpecs(p))
+	}
+	t.Run("without the frameworks", noFramework)
+}
+
+func TestRecordSpecCoverage(t *testing.T) {
+	coverage := map[string]*set.Set{}
+	recordSpecCoverage(bddSpec{path: "Store › Get"}, set.New("Store.Get", "NewStore"), coverage)
+	recordSpecCoverage(bddSpec{path: "Store › Put"}, set.New("Store.Put", "NewStore"), coverage)
+
+	assert.Equal(t, set.New("Store › Get", "Store › Put"), coverage["NewStore"])
+	assert.Equal(t, set.New("Store › Get"), coverage["Store.Get"])
+}
+
+func TestApplySpecCoverage(t *testing.T) {
+	declaredFuncInfo := map[string]tarpFunc{
+		"main.go:NewStore": {Name: "NewStore"},
+		"main.go:Untested": {Name: "Untested"},
+	}
+	applySpecCoverage(declaredFuncInfo, map[string]*set.Set{"NewStore": set.New("Store › Put", "Store › Get")})
+
+	assert.Equal(t, []string{"Store › Get", "Store › Put"}, declaredFuncInfo["main.go:NewStore"].Specs)
+	assert.Nil(t, declaredFuncInfo["main.go:Untested"].Specs)
+}
@@ -0,0 +1,472 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"github.com/fatih/set"
+	"golang.org/x/tools/go/loader"
+)
+
+/*
+	The vendored go/ssa predates type aliases and type parameters, and panics when it meets either in the
+	standard library, so rather than building SSA we build the call graph straight from the type checker's
+	output. That isn't what golang.org/x/tools/go/callgraph does, so the algorithms are named after what they
+	do rather than after CHA and RTA, which they only approximate:
+
+		static:      only calls whose callee is known at compile time
+		declared:    static, plus interface calls dispatched to the methods of every declared type that could
+		             satisfy them, and function value calls dispatched to every referenced function with a
+		             matching signature
+		constructed: declared, restricted to the types constructed and functions referenced in code reachable
+		             from a test
+
+	Unlike x/tools, only the functions declared in the package and its tests are part of the graph, calls made
+	inside function literals belong to the function that encloses them, and a type only counts as constructed
+	when it's written in a composite literal, a conversion or a call to new, not when a variable of it is declared.
+*/
+
+const (
+	callgraphEngine = "callgraph"
+
+	staticAlgorithm      = "static"
+	declaredAlgorithm    = "declared"
+	constructedAlgorithm = "constructed"
+)
+
+// funcSummary is what a single function declaration contributes to the call graph.
+// Calls made inside function literals are attributed to the declaration that encloses them, except for
+// the specs of BDD style tests, which are summarized as functions of their own.
+type funcSummary struct {
+	fn           *types.Func
+	test         bool
+	spec         bool
+	static       []*types.Func
+	invokes      []*types.Func
+	dynamic      []*types.Signature
+	addressTaken []*types.Func
+	constructed  []*types.Named
+}
+
+// callGraph maps every function declared in a package (and its tests) to the declared functions it calls.
+// The edges that come from dispatching interface method calls are also kept separately in dispatched.
+type callGraph struct {
+	summaries  map[*types.Func]*funcSummary
+	methods    []*types.Func
+	edges      map[*types.Func]*set.Set
+	dispatched map[*types.Func]*set.Set
+}
+
+// namedTypeOf returns the named type underlying t, looking through a single pointer indirection.
+func namedTypeOf(t types.Type) *types.Named {
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	n, _ := t.(*types.Named)
+	return n
+}
+
+// isInterfaceMethod reports whether fn is an abstract method belonging to an interface type.
+func isInterfaceMethod(fn *types.Func) bool {
+	sig, ok := fn.Type().(*types.Signature)
+	return ok && sig.Recv() != nil && types.IsInterface(sig.Recv().Type())
+}
+
+// summarizeFunc records the calls, references, and constructed types found in a function declaration.
+func summarizeFunc(decl *ast.FuncDecl, info *types.Info, test bool) *funcSummary {
+	fn, _ := info.Defs[decl.Name].(*types.Func)
+	summary := &funcSummary{fn: fn, test: test}
+	if decl.Body == nil {
+		return summary
+	}
+	summarizeNode(decl.Body, info, summary)
+	return summary
+}
+
+// summarizeSpec summarizes a spec of a BDD style test as if it were a test function of its own, named after
+// the spec's path.
+func summarizeSpec(spec bddSpec, pkg *types.Package, info *types.Info) *funcSummary {
+	pos := spec.nodes[len(spec.nodes)-1].Pos()
+	fn := types.NewFunc(pos, pkg, spec.path, types.NewSignatureType(nil, nil, nil, nil, nil, false))
+	summary := &funcSummary{fn: fn, test: true, spec: true}
+	for _, node := range spec.nodes {
+		summarizeNode(node, info, summary)
+	}
+	return summary
+}
+
+// summarizeNode adds the calls, references, and constructed types found in the given node to summary.
+func summarizeNode(in ast.Node, info *types.Info, summary *funcSummary) {
+	// handled keeps track of the expressions in call position, along with the identifiers we've already
+	// looked at, so that only functions referenced without being called count as address taken.
+	handled := map[ast.Expr]bool{}
+	ast.Inspect(in, func(n ast.Node) bool {
+		switch e := n.(type) {
+		case *ast.CallExpr:
+			fun := calleeExpr(e)
+			handled[fun] = true
+			if sel, ok := fun.(*ast.SelectorExpr); ok {
+				handled[sel.Sel] = true
+			}
+
+			callee, tv := calleeOf(e, info), info.Types[e.Fun]
+			switch {
+			case callee != nil && isInterfaceMethod(callee):
+				summary.invokes = append(summary.invokes, callee)
+			case callee != nil:
+				summary.static = append(summary.static, callee)
+			case tv.IsType():
+				// conversions create values of the type too
+				if named := namedTypeOf(tv.Type); named != nil {
+					summary.constructed = append(summary.constructed, named)
+				}
+			case tv.IsBuiltin():
+				if id, ok := fun.(*ast.Ident); ok && id.Name == "new" {
+					if named := namedTypeOf(info.Types[e].Type); named != nil {
+						summary.constructed = append(summary.constructed, named)
+					}
+				}
+			case tv.IsValue():
+				if sig, ok := tv.Type.Underlying().(*types.Signature); ok {
+					summary.dynamic = append(summary.dynamic, sig)
+				}
+			}
+		case *ast.CompositeLit:
+			if tv, ok := info.Types[e]; ok {
+				if named := namedTypeOf(tv.Type); named != nil {
+					summary.constructed = append(summary.constructed, named)
+				}
+			}
+		case *ast.SelectorExpr:
+			if handled[e] {
+				return true
+			}
+			handled[e.Sel] = true
+			if sel, ok := info.Selections[e]; ok && sel.Kind() == types.MethodVal {
+				if method, ok := sel.Obj().(*types.Func); ok && !isInterfaceMethod(method) {
+					summary.addressTaken = append(summary.addressTaken, method.Origin())
+				}
+			} else if ref, ok := info.Uses[e.Sel].(*types.Func); ok {
+				summary.addressTaken = append(summary.addressTaken, ref.Origin())
+			}
+		case *ast.Ident:
+			if ref, ok := info.Uses[e].(*types.Func); ok && !handled[e] {
+				summary.addressTaken = append(summary.addressTaken, ref.Origin())
+			}
+		}
+		return true
+	})
+}
+
+// referencedNames returns the names of the functions belonging to pkgPath that the summarized function refers
+// to without calling them, like functions passed as arguments and method values stored in tables.
+func (s *funcSummary) referencedNames(pkgPath string) *set.Set {
+	out := set.New()
+	for _, ref := range s.addressTaken {
+		if ref.Pkg() != nil && ref.Pkg().Path() == pkgPath {
+			out.Add(qualifiedFuncName(ref))
+		}
+	}
+	return out
+}
+
+// dispatch returns the declared methods an interface method call could end up in, considering only the given
+// named types as possible receivers.
+func dispatch(invoked *types.Func, receivers []*types.Named, methods []*types.Func) []*types.Func {
+	iface, ok := invoked.Type().(*types.Signature).Recv().Type().Underlying().(*types.Interface)
+	if !ok {
+		return nil
+	}
+
+	targets := []*types.Func{}
+	for _, named := range receivers {
+		if !types.Implements(named, iface) && !types.Implements(types.NewPointer(named), iface) {
+			continue
+		}
+		obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(named), true, invoked.Pkg(), invoked.Name())
+		if method, ok := obj.(*types.Func); ok {
+			// methods looked up on an instantiated generic type are instantiated too
+			method = method.Origin()
+			for _, m := range methods {
+				if m == method {
+					targets = append(targets, method)
+				}
+			}
+		}
+	}
+	return targets
+}
+
+// matchingSignatures returns the referenced functions a call through a function value of type sig could invoke.
+func matchingSignatures(sig *types.Signature, referenced []*types.Func) []*types.Func {
+	targets := []*types.Func{}
+	for _, fn := range referenced {
+		if types.Identical(fn.Type(), sig) {
+			targets = append(targets, fn)
+		}
+	}
+	return targets
+}
+
+// resolve computes the outgoing edges of a single function, given the receivers and references the algorithm
+// considers live.
+func (g *callGraph) resolve(summary *funcSummary, algorithm string, receivers []*types.Named, referenced []*types.Func) *set.Set {
+	edges := set.New()
+	addEdges := func(targets []*types.Func) {
+		for _, target := range targets {
+			if _, ok := g.summaries[target]; ok {
+				edges.Add(target)
+			}
+		}
+	}
+
+	addEdges(summary.static)
+	if algorithm == staticAlgorithm {
+		return edges
+	}
+	dispatched := set.New()
+	for _, invoked := range summary.invokes {
+		for _, target := range dispatch(invoked, receivers, g.methods) {
+			if _, ok := g.summaries[target]; ok {
+				edges.Add(target)
+				dispatched.Add(target)
+			}
+		}
+	}
+	g.dispatched[summary.fn] = dispatched
+	for _, sig := range summary.dynamic {
+		addEdges(matchingSignatures(sig, referenced))
+	}
+	return edges
+}
+
+// buildCallGraph builds a call graph over every function declared in the initial packages of prog.
+func buildCallGraph(prog *loader.Program, algorithm string) *callGraph {
+	g := &callGraph{
+		summaries:  map[*types.Func]*funcSummary{},
+		edges:      map[*types.Func]*set.Set{},
+		dispatched: map[*types.Func]*set.Set{},
+	}
+
+	declaredTypes := []*types.Named{}
+	for _, pkgInfo := range prog.InitialPackages() {
+		fields := suiteFields(pkgInfo.Files, &pkgInfo.Info)
+		for _, f := range pkgInfo.Files {
+			test := strings.HasSuffix(fileset.Position(f.Pos()).Filename, "_test.go")
+			if test {
+				for _, spec := range findSpecs(f) {
+					summary := summarizeSpec(spec, pkgInfo.Pkg, &pkgInfo.Info)
+					g.summaries[summary.fn] = summary
+				}
+			}
+			for _, d := range f.Decls {
+				switch n := d.(type) {
+				case *ast.FuncDecl:
+					summary := summarizeFunc(n, &pkgInfo.Info, test)
+					if summary.fn == nil {
+						continue
+					}
+					if test {
+						summary.static = append(summary.static, suiteCalls(n, &pkgInfo.Info, fields)...)
+					}
+					g.summaries[summary.fn] = summary
+					if n.Recv != nil {
+						g.methods = append(g.methods, summary.fn)
+					}
+				case *ast.GenDecl:
+					for _, spec := range n.Specs {
+						if ts, ok := spec.(*ast.TypeSpec); ok {
+							if named := namedTypeOf(pkgInfo.Defs[ts.Name].Type()); named != nil {
+								declaredTypes = append(declaredTypes, named)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	referenced := []*types.Func{}
+	for _, summary := range g.summaries {
+		referenced = append(referenced, summary.addressTaken...)
+	}
+
+	if algorithm != constructedAlgorithm {
+		for fn, summary := range g.summaries {
+			g.edges[fn] = g.resolve(summary, algorithm, declaredTypes, referenced)
+		}
+		return g
+	}
+
+	// like rapid type analysis, only the types constructed and functions referenced by code that's reachable
+	// from a test are considered, and what's reachable grows as we learn more, so iterate until nothing changes.
+	reachable := map[*types.Func]bool{}
+	for fn, summary := range g.summaries {
+		if summary.test || fn.Name() == "init" {
+			reachable[fn] = true
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		liveTypes, liveRefs := []*types.Named{}, []*types.Func{}
+		for fn := range reachable {
+			liveTypes = append(liveTypes, g.summaries[fn].constructed...)
+			liveRefs = append(liveRefs, g.summaries[fn].addressTaken...)
+		}
+		for fn := range reachable {
+			g.edges[fn] = g.resolve(g.summaries[fn], algorithm, liveTypes, liveRefs)
+			for _, target := range g.edges[fn].List() {
+				if !reachable[target.(*types.Func)] {
+					reachable[target.(*types.Func)] = true
+					changed = true
+				}
+			}
+		}
+	}
+	return g
+}
+
+// dispatchedNames returns the names of the functions belonging to pkgPath that fn only reaches by calling
+// methods through an interface. Functions fn also calls directly are left out.
+func (g *callGraph) dispatchedNames(fn *types.Func, pkgPath string) *set.Set {
+	out := set.New()
+	dispatched, ok := g.dispatched[fn]
+	if !ok {
+		return out
+	}
+
+	static := map[*types.Func]bool{}
+	for _, callee := range g.summaries[fn].static {
+		static[callee] = true
+	}
+	for _, target := range dispatched.List() {
+		if callee := target.(*types.Func); !static[callee] && callee.Pkg() != nil && callee.Pkg().Path() == pkgPath {
+			out.Add(qualifiedFuncName(callee))
+		}
+	}
+	return out
+}
+
+// isTestFunc reports whether a function declared in a _test.go file is a test function. The Test methods of
+// testify suites count, and so do the hooks testify runs around them. Examples, benchmarks and fuzz targets
+// are test functions too, though whether what they call counts as tested is up to countsAsTest.
+func isTestFunc(fn *types.Func) bool {
+	sig, ok := fn.Type().(*types.Signature)
+	if ok && sig.Recv() != nil {
+		return isSuiteMethod(fn) && (strings.HasPrefix(fn.Name(), "Test") || isSuiteHook(fn.Name()))
+	}
+	return ok && (strings.HasPrefix(fn.Name(), "Test") || funcKind(fn.Name(), false) != testFunc)
+}
+
+// callgraphAnalysis builds a tarpReport for the package in pkgDir, crediting every function that a
+// Test function has an edge to in the package's call graph. The edges out of the package's own functions
+// are kept so that functions further away from a test can be credited at a distance.
+func callgraphAnalysis(pkgDir string, algorithm string) (tarpReport, error) {
+	prog, importPath, err := loadPackage(pkgDir)
+	if err != nil {
+		return tarpReport{}, err
+	}
+
+	declaredFuncInfo := map[string]tarpFunc{}
+	coverage := map[string]*tableCoverage{}
+	specCoverage := map[string]*set.Set{}
+	helpers := map[*types.Func]bool{}
+	for _, pkgInfo := range prog.InitialPackages() {
+		for _, f := range pkgInfo.Files {
+			if strings.HasSuffix(fileset.Position(f.Pos()).Filename, "_test.go") {
+				getResolvedTableCoverage(f, &pkgInfo.Info, importPath, coverage)
+				getResolvedSpecCoverage(f, &pkgInfo.Info, importPath, specCoverage)
+				// every other function declared in a test file is a node calls pass through on the way from
+				// a test, so it's treated like a helper, whether or not it calls t.Helper()
+				for _, d := range f.Decls {
+					if decl, ok := d.(*ast.FuncDecl); ok {
+						if fn, ok := pkgInfo.Defs[decl.Name].(*types.Func); ok && (isTestHelper(decl) || !isTestFunc(fn)) {
+							helpers[fn] = true
+						}
+					}
+				}
+			} else {
+				getDeclaredNames(f, fileset, declaredFuncInfo)
+			}
+		}
+	}
+
+	g := buildCallGraph(prog, algorithm)
+	calledByPackage := map[string]*set.Set{}
+	callEdges := map[string]*set.Set{}
+	viaInterface, referenced := set.New(), set.New()
+	kinds, carriers := map[string]*set.Set{}, map[string]*set.Set{}
+	helperCalls := map[string]map[string]*set.Set{}
+	for fn, summary := range g.summaries {
+		edges, ok := g.edges[fn]
+		if !ok {
+			continue
+		}
+
+		var out *set.Set
+		dispatched := set.New()
+		kind := ""
+		switch {
+		case summary.test && (summary.spec || isTestFunc(fn)):
+			if _, ok := calledByPackage[fn.Pkg().Path()]; !ok {
+				calledByPackage[fn.Pkg().Path()] = set.New()
+			}
+			out = set.New()
+			kind = testFunc
+			if !summary.spec {
+				kind = funcKind(fn.Name(), fn.Type().(*types.Signature).Recv() != nil)
+			}
+			dispatched = g.dispatchedNames(fn, importPath)
+			if countsAsTest(kind) {
+				viaInterface.Merge(dispatched)
+				if creditReferences {
+					referenced.Merge(summary.referencedNames(importPath))
+				}
+			}
+		case helpers[fn]:
+			if _, ok := helperCalls[fn.Pkg().Path()]; !ok {
+				helperCalls[fn.Pkg().Path()] = map[string]*set.Set{}
+			}
+			out = set.New()
+			helperCalls[fn.Pkg().Path()][qualifiedFuncName(fn)] = out
+		case !summary.test && fn.Pkg().Path() == importPath:
+			out = set.New()
+			callEdges[funcKey(tarpFunc{Name: qualifiedFuncName(fn), Filename: fileset.Position(fn.Pos()).Filename})] = out
+		default:
+			continue
+		}
+
+		for _, target := range edges.List() {
+			callee := target.(*types.Func)
+			if callee.Pkg() != nil && callee.Pkg().Path() == importPath && !dispatched.Has(qualifiedFuncName(callee)) {
+				out.Add(qualifiedFuncName(callee))
+			}
+			// helpers are credited to the tests that call them once every test has been seen
+			if helpers[callee] && callee.Pkg() == fn.Pkg() {
+				out.Add(qualifiedFuncName(callee))
+			}
+		}
+		if kind != "" {
+			recordFuncKind(kind, out, calledByPackage[fn.Pkg().Path()], kinds)
+		}
+	}
+	for pkgPath, calls := range helperCalls {
+		if _, ok := calledByPackage[pkgPath]; !ok {
+			calledByPackage[pkgPath] = set.New()
+		}
+		creditHelpers(calls, calledByPackage[pkgPath], kinds, carriers)
+	}
+
+	calledFuncs := set.New("init")
+	credits := creditTestPackages(calledByPackage, calledFuncs)
+	creditAs(viaInterface, viaInterfaceCredit, calledFuncs, credits)
+	creditAs(referenced, byReferenceCredit, calledFuncs, credits)
+	report := buildReport(declaredFuncInfo, calledFuncs, callEdges, credits)
+	applyTableCoverage(report.DeclaredDetails, coverage)
+	applySpecCoverage(report.DeclaredDetails, specCoverage)
+	applyFuncKinds(report.DeclaredDetails, kinds)
+	applyHelperCredits(report.DeclaredDetails, carriers)
+	report.Engine = callgraphEngine
+	return report, nil
+}
@@ -0,0 +1,402 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+	"testing"
+
+	"github.com/fatih/set"
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////
+//                                                    //
+//               Test Helper Functions                //
+//                                                    //
+////////////////////////////////////////////////////////
+
+func funcNames(in []*types.Func) *set.Set {
+	out := set.New()
+	for _, fn := range in {
+		out.Add(qualifiedFuncName(fn))
+	}
+	return out
+}
+
+////////////////////////////////////////////////////////
+//                                                    //
+//                   Actual Tests                     //
+//                                                    //
+////////////////////////////////////////////////////////
+
+func TestNamedTypeOf(t *testing.T) {
+	_, pkg, _ := typeCheckChunkOfCode(t, `
+		package main
+		type example struct{}
+	`)
+	named := pkg.Scope().Lookup("example").Type()
+
+	assert.Equal(t, named, namedTypeOf(named))
+	assert.Equal(t, named, namedTypeOf(types.NewPointer(named)))
+	assert.Nil(t, namedTypeOf(types.Typ[types.Int]))
+}
+
+func TestIsInterfaceMethod(t *testing.T) {
+	_, pkg, _ := typeCheckChunkOfCode(t, `
+		package main
+		type iface interface{ method() }
+		type example struct{}
+		func (e example) method() {}
+		func function() {}
+	`)
+
+	assert.True(t, isInterfaceMethod(pkg.Scope().Lookup("iface").Type().Underlying().(*types.Interface).Method(0)))
+	assert.False(t, isInterfaceMethod(pkg.Scope().Lookup("example").Type().(*types.Named).Method(0)))
+	assert.False(t, isInterfaceMethod(pkg.Scope().Lookup("function").(*types.Func)))
+}
+
+func TestSummarizeFunc(t *testing.T) {
+	p, _, info := typeCheckChunkOfCode(t, `
+		package main
+
+		type iface interface{ method() }
+		type example struct{}
+		func (e example) method() {}
+		func function() {}
+
+		func main() {
+			var i iface = example{}
+			i.method()
+			function()
+			f := example{}.method
+			f()
+			_ = new(example)
+			_ = function
+		}
+	`)
+
+	actual := summarizeFunc(p.Decls[4].(*ast.FuncDecl), info, true)
+
+	assert.Equal(t, "main", actual.fn.Name())
+	assert.True(t, actual.test)
+	assert.Equal(t, set.New("function"), funcNames(actual.static))
+	assert.Equal(t, set.New("iface.method"), funcNames(actual.invokes))
+	assert.Len(t, actual.dynamic, 1)
+	assert.Equal(t, set.New("example.method", "function"), funcNames(actual.addressTaken))
+	assert.Len(t, actual.constructed, 3, "expected both composite literals and the call to new to be recorded")
+}
+
+func TestSummarizeSpec(t *testing.T) {
+	prog, _, err := loadPackage(buildExamplePackagePath(t, "bdd", true))
+	assert.Nil(t, err)
+
+	pkgInfo := prog.InitialPackages()[0]
+	for _, f := range pkgInfo.Files {
+		for _, spec := range findSpecs(f) {
+			if spec.path != "Store › Put › stores the value" {
+				continue
+			}
+			actual := summarizeSpec(spec, pkgInfo.Pkg, &pkgInfo.Info)
+
+			assert.Equal(t, spec.path, actual.fn.Name())
+			assert.True(t, actual.test)
+			assert.True(t, actual.spec)
+			assert.True(t, funcNames(actual.static).Has("NewStore"), "calls made in BeforeEach should be part of the spec")
+			assert.True(t, funcNames(actual.static).Has("Store.Put"))
+			return
+		}
+	}
+	t.Fatal("spec not found")
+}
+
+func TestSummarizeNode(t *testing.T) {
+	p, _, info := typeCheckChunkOfCode(t, `
+		package main
+
+		type example struct{}
+		func function() {}
+
+		func main() {
+			function()
+			_ = example{}
+		}
+	`)
+
+	actual := &funcSummary{}
+	summarizeNode(p.Decls[2].(*ast.FuncDecl).Body, info, actual)
+
+	assert.Equal(t, set.New("function"), funcNames(actual.static))
+	assert.Len(t, actual.constructed, 1)
+}
+
+func TestDispatch(t *testing.T) {
+	_, pkg, _ := typeCheckChunkOfCode(t, `
+		package main
+
+		type iface interface{ method() }
+		type value struct{}
+		func (v value) method() {}
+		type pointer struct{}
+		func (p *pointer) method() {}
+		type unrelated struct{}
+	`)
+	lookup := func(name string) *types.Named { return pkg.Scope().Lookup(name).Type().(*types.Named) }
+	invoked := lookup("iface").Underlying().(*types.Interface).Method(0)
+	methods := []*types.Func{lookup("value").Method(0), lookup("pointer").Method(0)}
+
+	actual := dispatch(invoked, []*types.Named{lookup("value"), lookup("pointer"), lookup("unrelated")}, methods)
+	assert.Equal(t, set.New("value.method", "pointer.method"), funcNames(actual))
+
+	actual = dispatch(invoked, []*types.Named{lookup("value")}, methods)
+	assert.Equal(t, set.New("value.method"), funcNames(actual))
+}
+
+func TestMatchingSignatures(t *testing.T) {
+	_, pkg, _ := typeCheckChunkOfCode(t, `
+		package main
+
+		func a(s string) string { return s }
+		func b(s string) string { return s }
+		func c(i int) string { return "" }
+	`)
+	lookup := func(name string) *types.Func { return pkg.Scope().Lookup(name).(*types.Func) }
+	sig := lookup("a").Type().(*types.Signature)
+
+	actual := matchingSignatures(sig, []*types.Func{lookup("a"), lookup("b"), lookup("c")})
+
+	assert.Equal(t, set.New("a", "b"), funcNames(actual))
+}
+
+func TestCallGraphResolve(t *testing.T) {
+	_, pkg, _ := typeCheckChunkOfCode(t, `
+		package main
+
+		type iface interface{ method() }
+		type example struct{}
+		func (e example) method() {}
+		func function() {}
+	`)
+	method := pkg.Scope().Lookup("example").Type().(*types.Named).Method(0)
+	function := pkg.Scope().Lookup("function").(*types.Func)
+	invoked := pkg.Scope().Lookup("iface").Type().Underlying().(*types.Interface).Method(0)
+
+	summary := &funcSummary{
+		static:  []*types.Func{function},
+		invokes: []*types.Func{invoked},
+	}
+	g := &callGraph{
+		summaries:  map[*types.Func]*funcSummary{method: {fn: method}, function: {fn: function}},
+		methods:    []*types.Func{method},
+		dispatched: map[*types.Func]*set.Set{},
+	}
+	receivers := []*types.Named{pkg.Scope().Lookup("example").Type().(*types.Named)}
+
+	assert.Equal(t, set.New(function), g.resolve(summary, staticAlgorithm, receivers, nil))
+	assert.Equal(t, set.New(function, method), g.resolve(summary, declaredAlgorithm, receivers, nil))
+	assert.Equal(t, set.New(method), g.dispatched[summary.fn], "edges from interface calls should be kept separately too")
+}
+
+func TestBuildCallGraph(t *testing.T) {
+	prog, _, err := loadPackage(buildExamplePackagePath(t, "dispatch", true))
+	if err != nil {
+		t.Logf("failing because loadPackage returned error: %v", err)
+		t.FailNow()
+	}
+
+	edgesFrom := func(g *callGraph, name string) *set.Set {
+		out := set.New()
+		for fn, edges := range g.edges {
+			if qualifiedFuncName(fn) == name {
+				for _, target := range edges.List() {
+					out.Add(qualifiedFuncName(target.(*types.Func)))
+				}
+			}
+		}
+		return out
+	}
+
+	static := func(t *testing.T) {
+		g := buildCallGraph(prog, staticAlgorithm)
+		assert.Equal(t, set.New(), edgesFrom(g, "TestInterface"))
+		assert.Equal(t, set.New("newCache"), edgesFrom(g, "TestMethodValue"))
+	}
+	t.Run("static", static)
+
+	declared := func(t *testing.T) {
+		g := buildCallGraph(prog, declaredAlgorithm)
+		assert.Equal(t, set.New("memStore.get", "diskStore.get"), edgesFrom(g, "TestInterface"))
+		assert.Equal(t, set.New("newCache", "cache.lookup"), edgesFrom(g, "TestMethodValue"))
+	}
+	t.Run("declared", declared)
+
+	constructed := func(t *testing.T) {
+		g := buildCallGraph(prog, constructedAlgorithm)
+		assert.Equal(t, set.New("memStore.get"), edgesFrom(g, "TestInterface"))
+		assert.Equal(t, set.New("memStore.get"), edgesFrom(g, "cache.lookup"))
+	}
+	t.Run("constructed", constructed)
+}
+
+func TestFuncSummaryReferencedNames(t *testing.T) {
+	_, pkg, _ := typeCheckChunkOfCode(t, `
+		package main
+
+		import "strings"
+
+		var _ = strings.ToUpper
+		func function() {}
+	`)
+	function := pkg.Scope().Lookup("function").(*types.Func)
+	other := pkg.Imports()[0].Scope().Lookup("ToUpper").(*types.Func)
+
+	summary := &funcSummary{addressTaken: []*types.Func{function, other}}
+
+	assert.Equal(t, set.New("function"), summary.referencedNames("example"), "references to other packages should be left out")
+}
+
+func TestDispatchedNames(t *testing.T) {
+	prog, importPath, err := loadPackage(buildExamplePackagePath(t, "dispatch", true))
+	if err != nil {
+		t.Logf("failing because loadPackage returned error: %v", err)
+		t.FailNow()
+	}
+
+	g := buildCallGraph(prog, declaredAlgorithm)
+	dispatchedFrom := func(name string) *set.Set {
+		for fn := range g.summaries {
+			if qualifiedFuncName(fn) == name {
+				return g.dispatchedNames(fn, importPath)
+			}
+		}
+		return nil
+	}
+
+	assert.Equal(t, set.New("memStore.get", "diskStore.get"), dispatchedFrom("TestInterface"))
+	assert.Equal(t, set.New(), dispatchedFrom("TestMethodValue"), "calls through function values aren't interface calls")
+	assert.Equal(t, set.New(), dispatchedFrom("closed"))
+}
+
+func TestIsTestFunc(t *testing.T) {
+	_, pkg, _ := typeCheckChunkOfCode(t, `
+		package main
+
+		type example struct{}
+		func (e example) TestMethod() {}
+		func TestFunction() {}
+		func BenchmarkFunction() {}
+		func helper() {}
+	`)
+
+	assert.True(t, isTestFunc(pkg.Scope().Lookup("TestFunction").(*types.Func)))
+	assert.True(t, isTestFunc(pkg.Scope().Lookup("BenchmarkFunction").(*types.Func)))
+	assert.False(t, isTestFunc(pkg.Scope().Lookup("helper").(*types.Func)))
+	assert.False(t, isTestFunc(pkg.Scope().Lookup("example").Type().(*types.Named).Method(0)))
+
+	pkgInfo, _ := loadSuitesPackage(t)
+	suiteType := pkgInfo.Pkg.Scope().Lookup("StoreSuite").Type()
+	for _, name := range []string{"TestPut", "SetupTest"} {
+		method, _, _ := types.LookupFieldOrMethod(suiteType, true, pkgInfo.Pkg, name)
+		assert.True(t, isTestFunc(method.(*types.Func)), "%s should count as a test", name)
+	}
+}
+
+func TestCallgraphAnalysis(t *testing.T) {
+	dispatchPkg := func(t *testing.T) {
+		actual, err := callgraphAnalysis(buildExamplePackagePath(t, "dispatch", true), constructedAlgorithm)
+
+		assert.Nil(t, err)
+		assert.Equal(t, callgraphEngine, actual.Engine)
+		assert.Equal(t, set.New("main.go:memStore.get", "main.go:closed", "main.go:deferred", "main.go:newCache", "main.go:cache.lookup"), actual.Called)
+		assert.Equal(t, []string{viaInterfaceCredit}, actual.DeclaredDetails["main.go:memStore.get"].Credits, "methods only reached through an interface should be marked as such")
+		assert.Equal(t, []string{whiteBoxCredit}, actual.DeclaredDetails["main.go:newCache"].Credits)
+	}
+	t.Run("dispatch", dispatchPkg)
+
+	external := func(t *testing.T) {
+		actual, err := callgraphAnalysis(buildExamplePackagePath(t, "external", true), constructedAlgorithm)
+
+		assert.Nil(t, err)
+		assert.Equal(t, set.New("main.go:Untested"), set.Difference(actual.Declared, actual.Called))
+		assert.Equal(t, []string{blackBoxCredit}, actual.DeclaredDetails["main.go:Exported"].Credits)
+		assert.Equal(t, []string{whiteBoxCredit}, actual.DeclaredDetails["main.go:newCounter"].Credits)
+	}
+	t.Run("external tests", external)
+
+	references := func(t *testing.T) {
+		creditReferences = true
+		defer func() { creditReferences = false }()
+		actual, err := callgraphAnalysis(buildExamplePackagePath(t, "references", true), constructedAlgorithm)
+
+		assert.Nil(t, err)
+		assert.Equal(t, set.New("main.go:Untested"), set.Difference(actual.Declared, actual.Called))
+		assert.Equal(t, []string{byReferenceCredit}, actual.DeclaredDetails["main.go:fetch"].Credits)
+		assert.Equal(t, []string{byReferenceCredit, whiteBoxCredit}, actual.DeclaredDetails["main.go:B"].Credits, "functions called through a value and referenced should get both credits")
+	}
+	t.Run("credit references", references)
+
+	tables := func(t *testing.T) {
+		actual, err := callgraphAnalysis(buildExamplePackagePath(t, "tables", true), constructedAlgorithm)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"negative", "positive"}, actual.DeclaredDetails["main.go:Add"].Cases)
+		assert.Equal(t, []string{"equal"}, actual.DeclaredDetails["main.go:Sub"].Cases)
+		assert.Equal(t, 1, actual.DeclaredDetails["main.go:Sub"].CaseCount)
+		assert.Equal(t, 2, actual.DeclaredDetails["main.go:Mul"].CaseCount)
+		assert.Equal(t, 0, actual.DeclaredDetails["main.go:Div"].CaseCount, "functions called outside of a table shouldn't be linked to one")
+	}
+	t.Run("table-driven tests", tables)
+
+	generics := func(t *testing.T) {
+		actual, err := callgraphAnalysis(buildExamplePackagePath(t, "generics", true), declaredAlgorithm)
+
+		assert.Nil(t, err)
+		assert.Equal(t, set.New("main.go:List.Len", "main.go:Filter"), set.Difference(actual.Declared, actual.Called))
+	}
+	t.Run("generics", generics)
+
+	suites := func(t *testing.T) {
+		actual, err := callgraphAnalysis(buildExamplePackagePath(t, "suites", true), staticAlgorithm)
+
+		assert.Nil(t, err)
+		assert.Equal(t, set.New("main.go:Cache.Hits", "main.go:Untested"), set.Difference(actual.Declared, actual.Called))
+		assert.Equal(t, []string{whiteBoxCredit}, actual.DeclaredDetails["main.go:memStore.Get"].Credits)
+	}
+	t.Run("testify suites", suites)
+
+	bdd := func(t *testing.T) {
+		actual, err := callgraphAnalysis(buildExamplePackagePath(t, "bdd", true), constructedAlgorithm)
+
+		assert.Nil(t, err)
+		assert.Equal(t, set.New("main.go:Store.Delete", "main.go:Store.Clear", "main.go:Parse", "main.go:Untested"), set.Difference(actual.Declared, actual.Called), "specs declared outside of any function should be credited too, unless they never run")
+		assert.Equal(t, []string{"Format › pads short keys"}, actual.DeclaredDetails["main.go:Format"].Specs)
+	}
+	t.Run("bdd specs", bdd)
+
+	testFuncs := func(t *testing.T) {
+		defer func() { countBenchmarks = true }()
+		countBenchmarks = false
+		actual, err := callgraphAnalysis(buildExamplePackagePath(t, "testfuncs", true), staticAlgorithm)
+
+		assert.Nil(t, err)
+		assert.Equal(t, set.New("main.go:Format", "main.go:Untested"), set.Difference(actual.Declared, actual.Called))
+		assert.Equal(t, []string{benchmarkFunc, testFunc}, actual.DeclaredDetails["main.go:Parse"].TestFuncs)
+		assert.Equal(t, []string{fuzzFunc}, actual.DeclaredDetails["main.go:Normalize"].TestFuncs)
+	}
+	t.Run("examples, benchmarks and fuzz targets", testFuncs)
+
+	helpers := func(t *testing.T) {
+		actual, err := callgraphAnalysis(buildExamplePackagePath(t, "helpers", true), staticAlgorithm)
+
+		assert.Nil(t, err)
+		assert.Equal(t, set.New("main.go:Abandoned", "main.go:Untested"), set.Difference(actual.Declared, actual.Called), "helpers no test calls shouldn't credit anything")
+		assert.Equal(t, []string{"fill"}, actual.DeclaredDetails["main.go:Store.Put"].Helpers)
+		assert.Equal(t, []string{"fixture.seed"}, actual.DeclaredDetails["main.go:Seed"].Helpers, "other functions declared in test files should be followed like helpers")
+	}
+	t.Run("test helpers", helpers)
+
+	nonexistent := func(t *testing.T) {
+		_, err := callgraphAnalysis(buildExamplePackagePath(t, "absolutelynosuchpackage", true), constructedAlgorithm)
+		assert.NotNil(t, err)
+	}
+	t.Run("nonexistent package", nonexistent)
+}
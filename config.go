@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/set"
+	"github.com/spf13/cast"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+)
+
+// configNames are the names of the configuration files tarp reads, in the order they're looked for in a
+// directory. Only the first one found in each directory is read.
+var configNames = []string{".tarp.yaml", ".tarp.yml", ".tarp.toml"}
+
+// envPrefix is the prefix of the environment variables tarp reads its settings from, like TARP_MAX_DEPTH.
+const envPrefix = "tarp"
+
+var (
+	// packageSettings are the names of the settings that decide how packages are analyzed, which are resolved
+	// from the directory of each package analyzed, rather than from the current one.
+	packageSettings = set.New()
+
+	// configuredFlags are the flags of the command being run, once configure has applied the configuration
+	// of the current directory to them.
+	configuredFlags *pflag.FlagSet
+)
+
+// listValue is the value of a flag holding a comma-separated list. Like pflag's string slices, passing the
+// flag more than once adds to the list, but the configuration can also replace it outright, which it has to
+// for every package with settings of its own.
+type listValue struct {
+	value   *[]string
+	changed bool
+}
+
+// newListValue returns a listValue that keeps its list in p, starting out with the given one.
+func newListValue(value []string, p *[]string) *listValue {
+	*p = value
+	return &listValue{value: p}
+}
+
+// readList reads a comma-separated list, which is written like a line of CSV, so values can be quoted.
+func readList(s string) ([]string, error) {
+	if s == "" {
+		return []string{}, nil
+	}
+	return csv.NewReader(strings.NewReader(s)).Read()
+}
+
+// Set adds the values in the given comma-separated list to the list, or replaces the default with them the
+// first time it's set.
+func (l *listValue) Set(s string) error {
+	values, err := readList(s)
+	if err != nil {
+		return err
+	}
+	if l.changed {
+		values = append(*l.value, values...)
+	}
+	*l.value, l.changed = values, true
+	return nil
+}
+
+// Type names the flag's type the way pflag names its string slices.
+func (l *listValue) Type() string {
+	return "stringSlice"
+}
+
+// String renders the list the way pflag renders its string slices, except that empty lists render as
+// nothing, so help doesn't list them as defaults.
+func (l *listValue) String() string {
+	if len(*l.value) == 0 {
+		return ""
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(*l.value)
+	w.Flush()
+	return "[" + strings.TrimSuffix(buf.String(), "\n") + "]"
+}
+
+// setFlag sets the given flag to the given value, as it would be given on the command line. Lists are
+// replaced rather than added to, since a setting stands in for the whole flag.
+func setFlag(f *pflag.Flag, value string) error {
+	if list, ok := f.Value.(*listValue); ok {
+		values, err := readList(value)
+		if err != nil {
+			return err
+		}
+		*list.value = values
+		return nil
+	}
+	return f.Value.Set(value)
+}
+
+// configFiles returns the configuration files that apply in dir: the one in the root of the module dir
+// belongs to, followed by those in every directory between the module root and dir, so the ones closest to
+// dir come last. Outside of a module, only the file in dir itself applies.
+func configFiles(dir string) []string {
+	root := dir
+	if mod, ok := findModule(dir); ok {
+		root = mod.Dir
+	}
+
+	dirs := []string{}
+	for d := dir; ; d = filepath.Dir(d) {
+		dirs = append([]string{d}, dirs...)
+		if d == root || filepath.Dir(d) == d {
+			break
+		}
+	}
+
+	files := []string{}
+	for _, d := range dirs {
+		for _, name := range configNames {
+			if _, err := os.Stat(filepath.Join(d, name)); err == nil {
+				files = append(files, filepath.Join(d, name))
+				break
+			}
+		}
+	}
+	return files
+}
+
+// loadConfig reads the settings that apply in dir from its configuration files, each of which overrides
+// the ones before it, and from TARP_* environment variables, which override them all. Settings are named
+// after the flags they stand in for, so TARP_MAX_DEPTH, or max-depth in a file, sets --max-depth.
+func loadConfig(dir string) *viper.Viper {
+	v := viper.New()
+	for _, file := range configFiles(dir) {
+		f, err := os.Open(file)
+		if err != nil {
+			fatalf(exitUsageError, "error reading %s: %v", file, err)
+		}
+		// viper can't merge files of different formats, so each one is read on its own, and its settings
+		// become the defaults the environment overrides
+		fileConfig := viper.New()
+		fileConfig.SetConfigType(strings.TrimPrefix(filepath.Ext(file), "."))
+		err = fileConfig.ReadConfig(f)
+		f.Close()
+		if err != nil {
+			fatalf(exitUsageError, "error reading %s: %v", file, err)
+		}
+		for _, key := range fileConfig.AllKeys() {
+			v.SetDefault(key, fileConfig.Get(key))
+		}
+	}
+	v.SetEnvPrefix(envPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	v.AutomaticEnv()
+	return v
+}
+
+// configValue turns a setting into what the flag it stands in for would be given on the command line. Lists
+// are joined with commas, like the values of slice flags.
+func configValue(value interface{}) string {
+	switch value.(type) {
+	case []interface{}, []string:
+		return strings.Join(cast.ToStringSlice(value), ",")
+	}
+	return cast.ToString(value)
+}
+
+// applyConfig sets each of the given flags that wasn't passed on the command line to the value the
+// configuration gives it, if any, so flags passed on the command line always take precedence.
+func applyConfig(v *viper.Viper, flags *pflag.FlagSet) {
+	flags.VisitAll(func(f *pflag.Flag) {
+		if f.Name == "help" || f.Changed || !v.IsSet(f.Name) {
+			return
+		}
+		if err := setFlag(f, configValue(v.Get(f.Name))); err != nil {
+			fatalf(exitUsageError, "invalid %s setting: %v", f.Name, err)
+		}
+	})
+}
+
+// configure applies the configuration that applies in the current directory to the flags of the command
+// being run, before it runs.
+func configure(cmd *cobra.Command, args []string) {
+	wd, err := os.Getwd()
+	if err != nil {
+		fatalf(exitLoadError, "error encountered getting current working directory: %v", err)
+	}
+	configuredFlags = cmd.Flags()
+	applyConfig(loadConfig(wd), configuredFlags)
+}
+
+// configurePackage applies the configuration that applies in the given package directory to the flags of the
+// command being run that decide how packages are analyzed, so configuration files in the directories beneath
+// the current one apply to the packages in them. It returns a function that puts the flags back the way they
+// were, ready for the next package.
+func configurePackage(pkgDir string) func() {
+	if configuredFlags == nil {
+		return func() {}
+	}
+	dir, err := filepath.Abs(pkgDir)
+	if err != nil {
+		fatalf(exitLoadError, "error encountered resolving %s: %v", pkgDir, err)
+	}
+
+	flags, saved := pflag.NewFlagSet("package", pflag.ContinueOnError), map[*pflag.Flag][]string{}
+	configuredFlags.VisitAll(func(f *pflag.Flag) {
+		if packageSettings.Has(f.Name) {
+			flags.AddFlag(f)
+			// every value is saved as a list, so lists can be restored without going through CSV
+			if list, ok := f.Value.(*listValue); ok {
+				saved[f] = append([]string{}, *list.value...)
+			} else {
+				saved[f] = []string{f.Value.String()}
+			}
+		}
+	})
+	applyConfig(loadConfig(dir), flags)
+
+	return func() {
+		for f, value := range saved {
+			if list, ok := f.Value.(*listValue); ok {
+				*list.value = value
+			} else if f.Value.String() != value[0] {
+				f.Value.Set(value[0])
+			}
+		}
+	}
+}
+
+// flagValue returns the value of the given flag as the type it holds, rather than as a string.
+func flagValue(f *pflag.Flag) interface{} {
+	s := f.Value.String()
+	switch f.Value.Type() {
+	case "bool":
+		return cast.ToBool(s)
+	case "int":
+		return cast.ToInt(s)
+	case "stringSlice":
+		// slice flags print their values as a line of CSV
+		values, _ := readList(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]"))
+		return values
+	}
+	return s
+}
+
+// renderConfig renders the values of the given flags as YAML, which can be used as a .tarp.yaml file as is,
+// preceded by a comment listing the configuration files they were read from.
+func renderConfig(files []string, flagSets ...*pflag.FlagSet) string {
+	settings := map[string]interface{}{}
+	for _, flags := range flagSets {
+		flags.VisitAll(func(f *pflag.Flag) {
+			if f.Name != "help" {
+				settings[f.Name] = flagValue(f)
+			}
+		})
+	}
+	// the settings are plain values, so they can always be marshalled
+	out, _ := yaml.Marshal(settings)
+
+	header := "# no configuration files found\n"
+	if len(files) > 0 {
+		header = fmt.Sprintf("# read from %s\n", strings.Join(files, ", "))
+	}
+	return header + string(out)
+}
+
+// showConfig prints the fully resolved configuration of every command in the current directory. The
+// configuration has already been applied to the flags of the command being run, which include the ones
+// every command shares, so it's only applied to the rest.
+func showConfig(cmd *cobra.Command, args []string) {
+	wd, err := os.Getwd()
+	if err != nil {
+		fatalf(exitLoadError, "error encountered getting current working directory: %v", err)
+	}
+
+	all, rest := pflag.NewFlagSet("all", pflag.ContinueOnError), pflag.NewFlagSet("rest", pflag.ContinueOnError)
+	all.AddFlagSet(cmd.Flags())
+	for _, flags := range []*pflag.FlagSet{analyzeCmd.Flags(), coverCmd.Flags()} {
+		flags.VisitAll(func(f *pflag.Flag) {
+			if all.Lookup(f.Name) == nil {
+				all.AddFlag(f)
+				rest.AddFlag(f)
+			}
+		})
+	}
+	applyConfig(loadConfig(wd), rest)
+	fmt.Print(renderConfig(configFiles(wd), all))
+}
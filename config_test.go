@@ -0,0 +1,308 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////
+//                                                    //
+//               Test Helper Functions                //
+//                                                    //
+////////////////////////////////////////////////////////
+
+// buildConfigTree writes a module with a .tarp.yaml file in its root, overridden by a .tarp.toml file in one
+// of its subdirectories, and returns the module's root directory.
+func buildConfigTree(t *testing.T) string {
+	t.Helper()
+	root, err := ioutil.TempDir("", "tarp_config")
+	if err != nil {
+		t.Logf("error encountered creating temp directory: %v", err)
+		t.FailNow()
+	}
+
+	files := map[string]string{
+		"go.mod": "module example.com/tarpconfig\n",
+		".tarp.yaml": `engine: ast
+max-depth: 2
+tags: [integration, e2e]
+`,
+		"legacy/.tarp.toml": `max-depth = 3
+fail-on-found = true
+`,
+		"legacy/handlers/handlers.go": "package handlers\n",
+	}
+	for name, contents := range files {
+		path := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Logf("error encountered writing %s: %v", path, err)
+			t.FailNow()
+		}
+	}
+	return root
+}
+
+// buildConfigFlags returns a flag set with a flag of every type tarp uses, along with the variables they set.
+func buildConfigFlags() (*pflag.FlagSet, *string, *int, *bool, *[]string) {
+	var engine string
+	var depth int
+	var fail bool
+	var tags []string
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.StringVar(&engine, "engine", typesEngine, "")
+	flags.IntVar(&depth, "max-depth", 1, "")
+	flags.BoolVar(&fail, "fail-on-found", false, "")
+	flags.StringSliceVar(&tags, "tags", nil, "")
+	return flags, &engine, &depth, &fail, &tags
+}
+
+////////////////////////////////////////////////////////
+//                                                    //
+//                   Actual Tests                     //
+//                                                    //
+////////////////////////////////////////////////////////
+
+func TestConfigFiles(t *testing.T) {
+	root := buildConfigTree(t)
+	defer os.RemoveAll(root)
+
+	expected := []string{filepath.Join(root, ".tarp.yaml"), filepath.Join(root, "legacy", ".tarp.toml")}
+	assert.Equal(t, expected, configFiles(filepath.Join(root, "legacy", "handlers")), "files closer to the directory should come last")
+	assert.Equal(t, expected[:1], configFiles(root))
+
+	os.Remove(filepath.Join(root, "go.mod"))
+	assert.Equal(t, expected[1:], configFiles(filepath.Join(root, "legacy")), "only the directory's own file should apply outside of a module")
+}
+
+func TestLoadConfig(t *testing.T) {
+	root := buildConfigTree(t)
+	defer os.RemoveAll(root)
+
+	actual := loadConfig(filepath.Join(root, "legacy", "handlers"))
+	assert.Equal(t, "ast", actual.GetString("engine"))
+	assert.Equal(t, 3, actual.GetInt("max-depth"), "nested files should override the ones above them, whatever their format")
+	assert.True(t, actual.GetBool("fail-on-found"))
+	assert.Equal(t, []string{"integration", "e2e"}, actual.GetStringSlice("tags"))
+	assert.False(t, actual.IsSet("algorithm"))
+
+	os.Setenv("TARP_MAX_DEPTH", "4")
+	defer os.Unsetenv("TARP_MAX_DEPTH")
+
+	actual = loadConfig(filepath.Join(root, "legacy", "handlers"))
+	assert.Equal(t, 4, actual.GetInt("max-depth"), "environment variables should override files")
+
+	ioutil.WriteFile(filepath.Join(root, ".tarp.yaml"), []byte("engine: [ast"), 0644)
+	assert.Panics(t, func() { loadConfig(root) }, "invalid files should be fatal")
+}
+
+func TestConfigValue(t *testing.T) {
+	assert.Equal(t, "integration,e2e", configValue([]interface{}{"integration", "e2e"}))
+	assert.Equal(t, "integration,e2e", configValue([]string{"integration", "e2e"}))
+	assert.Equal(t, "linux,darwin", configValue("linux,darwin"), "environment variables are already comma separated")
+	assert.Equal(t, "3", configValue(int64(3)))
+	assert.Equal(t, "true", configValue(true))
+}
+
+func TestApplyConfig(t *testing.T) {
+	root := buildConfigTree(t)
+	defer os.RemoveAll(root)
+
+	flags, engine, depth, fail, tags := buildConfigFlags()
+	flags.Parse([]string{"--engine", callgraphEngine})
+	applyConfig(loadConfig(filepath.Join(root, "legacy")), flags)
+
+	assert.Equal(t, callgraphEngine, *engine, "flags passed on the command line should take precedence")
+	assert.Equal(t, 3, *depth)
+	assert.True(t, *fail)
+	assert.Equal(t, []string{"integration", "e2e"}, *tags)
+
+	os.Setenv("TARP_MAX_DEPTH", "many")
+	defer os.Unsetenv("TARP_MAX_DEPTH")
+	assert.Panics(t, func() { applyConfig(loadConfig(root), flags) }, "invalid settings should be fatal")
+}
+
+func TestConfigure(t *testing.T) {
+	root := buildConfigTree(t)
+	defer os.RemoveAll(root)
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(root)
+
+	flags, engine, depth, _, _ := buildConfigFlags()
+	cmd := &cobra.Command{}
+	cmd.Flags().AddFlagSet(flags)
+	configure(cmd, nil)
+
+	assert.Equal(t, astEngine, *engine)
+	assert.Equal(t, 2, *depth)
+}
+
+func TestReadList(t *testing.T) {
+	actual, err := readList(`integration,"e2e,slow"`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"integration", "e2e,slow"}, actual)
+
+	actual, err = readList("")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, actual)
+
+	_, err = readList(`"integration`)
+	assert.Error(t, err)
+}
+
+func TestListValue(t *testing.T) {
+	var patterns []string
+	list := newListValue([]string{"*_mock.go"}, &patterns)
+	assert.Equal(t, []string{"*_mock.go"}, patterns)
+	assert.Equal(t, "stringSlice", list.Type())
+	assert.Equal(t, "[*_mock.go]", list.String())
+
+	assert.NoError(t, list.Set("*.pb.go"))
+	assert.Equal(t, []string{"*.pb.go"}, patterns, "the default should be replaced the first time the list is set")
+	assert.NoError(t, list.Set(`"a,b.go"`))
+	assert.Equal(t, []string{"*.pb.go", "a,b.go"}, patterns, "lists should be added to after that")
+	assert.Equal(t, `[*.pb.go,"a,b.go"]`, list.String())
+	assert.Error(t, list.Set(`"a.go`))
+
+	assert.Equal(t, "", newListValue(nil, &patterns).String(), "empty lists shouldn't be shown as defaults")
+}
+
+func TestSetFlag(t *testing.T) {
+	var patterns []string
+	flags, _, depth, _, _ := buildConfigFlags()
+	flags.Var(newListValue(nil, &patterns), "generated-pattern", "")
+	flags.Parse([]string{"--generated-pattern", "*_mock.go"})
+
+	assert.NoError(t, setFlag(flags.Lookup("generated-pattern"), "*.pb.go,*_string.go"))
+	assert.Equal(t, []string{"*.pb.go", "*_string.go"}, patterns, "lists should be replaced")
+	assert.Error(t, setFlag(flags.Lookup("generated-pattern"), `"*.pb.go`))
+
+	assert.NoError(t, setFlag(flags.Lookup("max-depth"), "3"))
+	assert.Equal(t, 3, *depth)
+	assert.Error(t, setFlag(flags.Lookup("max-depth"), "many"))
+}
+
+func TestConfigurePackage(t *testing.T) {
+	root := buildConfigTree(t)
+	defer os.RemoveAll(root)
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(root)
+	defer func() { configuredFlags = nil }()
+
+	unconfigured := func(t *testing.T) {
+		configuredFlags = nil
+		configurePackage("legacy")()
+	}
+	t.Run("without a command", unconfigured)
+
+	nested := func(t *testing.T) {
+		var patterns []string
+		flags, engine, depth, fail, _ := buildConfigFlags()
+		flags.Var(newListValue(nil, &patterns), "generated-pattern", "")
+		ioutil.WriteFile(filepath.Join(root, "legacy", ".tarp.toml"), []byte("max-depth = 3\nfail-on-found = true\ngenerated-pattern = [\"*_mock.go\"]\n"), 0644)
+		cmd := &cobra.Command{}
+		cmd.Flags().AddFlagSet(flags)
+		configure(cmd, nil)
+
+		restore := configurePackage(filepath.Join("legacy", "handlers"))
+		assert.Equal(t, 3, *depth, "the package's own configuration files should apply")
+		assert.Equal(t, []string{"*_mock.go"}, patterns)
+		assert.Equal(t, astEngine, *engine, "settings the package's files don't change should be left alone")
+		assert.False(t, *fail, "settings for the whole run should come from the current directory")
+
+		restore()
+		assert.Equal(t, 2, *depth)
+		assert.Empty(t, patterns)
+	}
+	t.Run("nested configuration", nested)
+
+	analyzed := func(t *testing.T) {
+		files := map[string]string{
+			"main.go":               "package tarpconfig\n\nfunc A() { b() }\n\nfunc b() { c() }\n\nfunc c() {}\n",
+			"main_test.go":          "package tarpconfig\n\nimport \"testing\"\n\nfunc TestA(t *testing.T) { A() }\n",
+			"legacy/legacy.go":      "package legacy\n\nfunc A() { b() }\n\nfunc b() { c() }\n\nfunc c() {}\n",
+			"legacy/legacy_test.go": "package legacy\n\nimport \"testing\"\n\nfunc TestA(t *testing.T) { A() }\n",
+		}
+		for name, contents := range files {
+			ioutil.WriteFile(filepath.Join(root, name), []byte(contents), 0644)
+		}
+		defer func() {
+			analysisEngine, maxDepth, failOnFound, buildTags = typesEngine, 1, false, nil
+		}()
+		// analyzeCmd's flags may have been given on the command line by other tests, which would take precedence
+		cmd := &cobra.Command{}
+		addAnalysisFlags(cmd.Flags())
+		configure(cmd, nil)
+
+		outputs := analyzePackages(expandPatterns([]string{"./..."}))
+		assert.Len(t, outputs, 3)
+		assert.Equal(t, 2, outputs[0].MaxDepth)
+		assert.Equal(t, 2, outputs[0].CalledCount, "c is three calls away from TestA")
+		assert.Equal(t, 3, outputs[1].MaxDepth, "legacy/.tarp.toml should apply to ./legacy under ./...")
+		assert.Empty(t, outputs[1].Details)
+		assert.Equal(t, 2, maxDepth, "the current directory's settings should be restored afterwards")
+	}
+	t.Run("analyzing ./...", analyzed)
+}
+
+func TestFlagValue(t *testing.T) {
+	flags, _, _, _, _ := buildConfigFlags()
+	flags.Parse([]string{"--tags", `integration,"e2e,slow"`})
+
+	assert.Equal(t, typesEngine, flagValue(flags.Lookup("engine")))
+	assert.Equal(t, 1, flagValue(flags.Lookup("max-depth")))
+	assert.Equal(t, false, flagValue(flags.Lookup("fail-on-found")))
+	assert.Equal(t, []string{"integration", "e2e,slow"}, flagValue(flags.Lookup("tags")))
+
+	flags, _, _, _, _ = buildConfigFlags()
+	assert.Equal(t, []string{}, flagValue(flags.Lookup("tags")))
+}
+
+func TestRenderConfig(t *testing.T) {
+	flags, _, _, _, _ := buildConfigFlags()
+	flags.Parse([]string{"--tags", "integration"})
+
+	expected := `# read from .tarp.yaml
+engine: types
+fail-on-found: false
+max-depth: 1
+tags:
+- integration
+`
+	assert.Equal(t, expected, renderConfig([]string{".tarp.yaml"}, flags))
+	assert.Contains(t, renderConfig(nil, flags), "# no configuration files found\n")
+}
+
+func TestShowConfig(t *testing.T) {
+	root := buildConfigTree(t)
+	defer os.RemoveAll(root)
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(filepath.Join(root, "legacy"))
+	defer func() {
+		analysisEngine, maxDepth, failOnFound, buildTags = typesEngine, 1, false, nil
+	}()
+
+	r, w, _ := os.Pipe()
+	stdout := os.Stdout
+	os.Stdout = w
+	showConfig(configShowCmd, nil)
+	os.Stdout = stdout
+	w.Close()
+	out, _ := ioutil.ReadAll(r)
+	actual := string(out)
+
+	assert.Contains(t, actual, "engine: ast\n")
+	assert.Contains(t, actual, "max-depth: 3\n")
+	assert.Contains(t, actual, "fail-on-found: true\n")
+	assert.Contains(t, actual, "html: \"\"\n", "every command's settings should be shown")
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+)
+
+// buildContext returns the go/build context used to decide which files make up a package, honoring the
+// --goos, --goarch and --tags flags the same way the go command honors GOOS, GOARCH and -tags.
+func buildContext() build.Context {
+	ctx := build.Default
+	if targetOS != "" {
+		ctx.GOOS = targetOS
+	}
+	if targetArch != "" {
+		ctx.GOARCH = targetArch
+	}
+	ctx.BuildTags = append([]string{}, buildTags...)
+	return ctx
+}
+
+// fileFilter returns a filter for parser.ParseDir that only accepts the files in dir whose names and
+// build constraints match the build context.
+func fileFilter(dir string) func(os.FileInfo) bool {
+	ctx := buildContext()
+	return func(fi os.FileInfo) bool {
+		ok, err := ctx.MatchFile(dir, fi.Name())
+		return err == nil && ok
+	}
+}
+
+// funcKey is what a declared function is known by in a tarpReport. Functions are keyed by the file that
+// declares them as well as their name, so that variants of a function declared for different platforms
+// are reported separately. Functions without a known file are keyed by name alone.
+func funcKey(tf tarpFunc) string {
+	if tf.Filename == "" {
+		return tf.Name
+	}
+	return filepath.Base(tf.Filename) + ":" + tf.Name
+}
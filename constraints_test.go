@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildContext(t *testing.T) {
+	defaults := func(t *testing.T) {
+		actual := buildContext()
+
+		assert.Equal(t, runtime.GOOS, actual.GOOS)
+		assert.Equal(t, runtime.GOARCH, actual.GOARCH)
+		assert.Empty(t, actual.BuildTags)
+	}
+	t.Run("defaults", defaults)
+
+	overridden := func(t *testing.T) {
+		buildTags, targetOS, targetArch = []string{"integration"}, "windows", "arm64"
+		defer func() { buildTags, targetOS, targetArch = nil, "", "" }()
+
+		actual := buildContext()
+
+		assert.Equal(t, "windows", actual.GOOS)
+		assert.Equal(t, "arm64", actual.GOARCH)
+		assert.Equal(t, []string{"integration"}, actual.BuildTags)
+	}
+	t.Run("overridden", overridden)
+}
+
+func TestFileFilter(t *testing.T) {
+	pkgDir := buildExamplePackagePath(t, "constraints", true)
+	matches := func(name string) bool {
+		fi, err := os.Stat(filepath.Join(pkgDir, name))
+		if err != nil {
+			t.Logf("error encountered reading example file: %v", err)
+			t.FailNow()
+		}
+		return fileFilter(pkgDir)(fi)
+	}
+
+	defer func() { buildTags, targetOS = nil, "" }()
+
+	targetOS = "linux"
+	assert.True(t, matches("main.go"))
+	assert.True(t, matches("file_linux.go"))
+	assert.False(t, matches("file_windows.go"), "files for other operating systems should be filtered out")
+	assert.False(t, matches("integration_test.go"), "files with unsatisfied build constraints should be filtered out")
+
+	targetOS, buildTags = "windows", []string{"integration"}
+	assert.False(t, matches("file_linux.go"))
+	assert.True(t, matches("file_windows.go"))
+	assert.True(t, matches("integration_test.go"))
+}
+
+func TestFuncKey(t *testing.T) {
+	assert.Equal(t, "file_linux.go:open", funcKey(tarpFunc{Name: "open", Filename: "/src/pkg/file_linux.go"}))
+	assert.Equal(t, "open", funcKey(tarpFunc{Name: "open"}), "functions without a file should be keyed by name")
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/fatih/set"
+)
+
+// conventionEngine is what --convention runs report as their engine, since they credit functions by the
+// names of the tests in their package rather than by what those tests call.
+const conventionEngine = "convention"
+
+// defaultConventionPatterns are the names gotests gives the tests it generates: TestFoo for a function Foo,
+// and TestType_Method for a method, or Test_foo and Test_type_method when they're unexported, any of which
+// may be followed by an underscore and a description.
+var defaultConventionPatterns = []string{
+	"Test{func}", "Test{func}_*", "Test{type}_{method}", "Test{type}_{method}_*",
+	"Test_{func}", "Test_{func}_*", "Test_{type}_{method}", "Test_{type}_{method}_*",
+}
+
+// conventionGlob returns what the name of a test for the given declared function has to match to follow
+// pattern, and whether pattern applies to the function at all. Patterns using {func} apply to plain
+// functions, and patterns using {type} or {method} to methods.
+func conventionGlob(pattern, name string) (string, bool) {
+	if i := strings.Index(name, "."); i >= 0 {
+		if !strings.Contains(pattern, "{type}") && !strings.Contains(pattern, "{method}") {
+			return "", false
+		}
+		return strings.NewReplacer("{type}", name[:i], "{method}", name[i+1:]).Replace(pattern), true
+	}
+	if !strings.Contains(pattern, "{func}") {
+		return "", false
+	}
+	return strings.Replace(pattern, "{func}", name, -1), true
+}
+
+// matchingTests returns the tests whose names follow any of the patterns for the given declared function.
+func matchingTests(name string, patterns []string, tests *set.Set) *set.Set {
+	out := set.New()
+	for _, pattern := range patterns {
+		glob, ok := conventionGlob(pattern, name)
+		if !ok {
+			continue
+		}
+		for _, test := range set.StringSlice(tests) {
+			if matched, _ := path.Match(glob, test); matched {
+				out.Add(test)
+			}
+		}
+	}
+	return out
+}
+
+// findTestNames adds the names of the Test functions declared in the given file to out. TestMain is left
+// out, since it sets up the other tests rather than testing anything itself.
+func findTestNames(in *ast.File, out *set.Set) {
+	for _, d := range in.Decls {
+		if f, ok := d.(*ast.FuncDecl); ok && f.Recv == nil && isTestName(f.Name.Name, "Test") && f.Name.Name != "TestMain" {
+			out.Add(f.Name.Name)
+		}
+	}
+}
+
+// conventionAnalysis builds a tarpReport for the package in pkgDir that credits each declared function with
+// a test named after it, following one of conventionPatterns, no matter what that test calls. Tests that
+// aren't named after any declared function are reported as orphaned.
+func conventionAnalysis(pkgDir string) tarpReport {
+	for _, pattern := range conventionPatterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			fatalf(exitUsageError, "invalid --convention-pattern %q: %v", pattern, err)
+		}
+	}
+
+	astPkg, err := parser.ParseDir(fileset, pkgDir, fileFilter(pkgDir), parser.AllErrors|parser.ParseComments)
+	if err != nil {
+		fatalf(exitLoadError, "%v", err)
+	}
+
+	if len(astPkg) == 0 {
+		fatalf(exitLoadError, "no go files found!")
+	}
+
+	declaredFuncInfo := map[string]tarpFunc{}
+	tests := set.New()
+	for _, pkg := range astPkg {
+		for filename, f := range pkg.Files {
+			if strings.HasSuffix(filename, "_test.go") {
+				findTestNames(f, tests)
+			} else {
+				getDeclaredNames(f, fileset, declaredFuncInfo)
+			}
+		}
+	}
+
+	calledFuncs, matched := set.New("init"), set.New()
+	for _, f := range declaredFuncInfo {
+		if m := matchingTests(f.Name, conventionPatterns, tests); m.Size() > 0 {
+			calledFuncs.Add(f.Name)
+			matched.Merge(m)
+		}
+	}
+
+	report := buildReport(declaredFuncInfo, calledFuncs, map[string]*set.Set{}, map[string]*set.Set{})
+	report.OrphanedTests = set.StringSlice(set.Difference(tests, matched))
+	sort.Strings(report.OrphanedTests)
+	report.Engine = conventionEngine
+	return report
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/fatih/set"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConventionGlob(t *testing.T) {
+	glob, ok := conventionGlob("Test{func}_*", "Parse")
+	assert.True(t, ok)
+	assert.Equal(t, "TestParse_*", glob)
+
+	glob, ok = conventionGlob("Test{type}_{method}", "Store.Get")
+	assert.True(t, ok)
+	assert.Equal(t, "TestStore_Get", glob)
+
+	_, ok = conventionGlob("Test{func}", "Store.Get")
+	assert.False(t, ok, "function patterns shouldn't apply to methods")
+	_, ok = conventionGlob("Test{type}_{method}", "Parse")
+	assert.False(t, ok, "method patterns shouldn't apply to functions")
+}
+
+func TestMatchingTests(t *testing.T) {
+	tests := set.New("TestParse", "TestParse_empty", "TestParser", "TestStore_Get", "TestRender")
+
+	assert.Equal(t, set.New("TestParse", "TestParse_empty"), matchingTests("Parse", defaultConventionPatterns, tests))
+	assert.Equal(t, set.New("TestStore_Get"), matchingTests("Store.Get", defaultConventionPatterns, tests))
+	assert.Equal(t, set.New(), matchingTests("Format", defaultConventionPatterns, tests))
+	assert.Equal(t, set.New("TestParse"), matchingTests("Parse", []string{"Test{func}"}, tests))
+
+	unexported := set.New("Test_parse", "Test_parse_empty", "Test_store_get", "Test_store_get_missing")
+	assert.Equal(t, set.New("Test_parse", "Test_parse_empty"), matchingTests("parse", defaultConventionPatterns, unexported), "unexported functions should follow gotests' Test_foo convention")
+	assert.Equal(t, set.New("Test_store_get", "Test_store_get_missing"), matchingTests("store.get", defaultConventionPatterns, unexported))
+}
+
+func TestFindTestNames(t *testing.T) {
+	p := parseChunkOfCode(t, `
+		package main
+		func TestMain(m *testing.M) {}
+		func TestParse(t *testing.T) {}
+		func Testify() {}
+		func BenchmarkParse(b *testing.B) {}
+		func (s *StoreSuite) TestGet() {}
+	`)
+
+	actual := set.New()
+	findTestNames(p, actual)
+	assert.Equal(t, set.New("TestParse"), actual)
+}
+
+func TestConventionAnalysis(t *testing.T) {
+	defaults := func(t *testing.T) {
+		actual := conventionAnalysis(buildExamplePackagePath(t, "convention", true))
+
+		assert.Equal(t, conventionEngine, actual.Engine)
+		assert.Equal(t, set.New("main.go:Store.Put", "main.go:Untested"), set.Difference(actual.Declared, actual.Called), "calling a function shouldn't count without a test named after it")
+		assert.Equal(t, []string{"TestRender"}, actual.OrphanedTests)
+	}
+	t.Run("default patterns", defaults)
+
+	custom := func(t *testing.T) {
+		defer func() { conventionPatterns = defaultConventionPatterns }()
+		conventionPatterns = []string{"Test{func}", "Test{type}_{method}"}
+		actual := conventionAnalysis(buildExamplePackagePath(t, "convention", true))
+
+		assert.Equal(t, set.New("main.go:Format", "main.go:Store.Put", "main.go:normalize", "main.go:cache.get", "main.go:Untested"), set.Difference(actual.Declared, actual.Called))
+		assert.Equal(t, []string{"TestFormat_empty", "TestRender", "Test_cache_get_missing", "Test_normalize"}, actual.OrphanedTests)
+	}
+	t.Run("custom patterns", custom)
+
+	invalidPattern := func(t *testing.T) {
+		defer func() { conventionPatterns = defaultConventionPatterns }()
+		conventionPatterns = []string{"Test[{func}"}
+		var fatalfCalled bool
+		defer func() {
+			// recovered from our monkey patched log.Fatalf
+			if r := recover(); r != nil {
+				fatalfCalled = true
+			}
+			assert.True(t, fatalfCalled, "conventionAnalysis should call log.Fatalf() when a pattern is malformed")
+		}()
+
+		conventionAnalysis(buildExamplePackagePath(t, "convention", true))
+	}
+	t.Run("invalid pattern", invalidPattern)
+}
@@ -0,0 +1,18 @@
+package bdd
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFormat(t *testing.T) {
+	Convey("Format", t, func() {
+		Convey("pads short keys", func() {
+			So(Format("a", 1), ShouldEqual, "a    = 1")
+		})
+		SkipConvey("round-trips through Parse", func() {
+			So(Parse(Format("a", 1)), ShouldEqual, "a")
+		})
+	})
+}
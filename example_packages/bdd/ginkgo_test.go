@@ -0,0 +1,42 @@
+package bdd
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+)
+
+func TestStore(t *testing.T) {
+	RunSpecs(t, "Store")
+}
+
+var _ = Describe("Store", func() {
+	var store *Store
+
+	BeforeEach(func() {
+		store = NewStore()
+	})
+
+	Describe("Get", func() {
+		It("returns not found", func() {
+			if _, err := store.Get("missing"); err == nil {
+				Fail("expected an error")
+			}
+		})
+	})
+
+	Context("Put", func() {
+		It("stores the value", func() {
+			store.Put("key", "value")
+		})
+		XIt("can be deleted", func() {
+			store.Delete("key")
+		})
+	})
+
+	PDescribe("Clear", func() {
+		It("removes every value", func() {
+			store.Clear()
+		})
+	})
+})
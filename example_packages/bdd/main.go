@@ -0,0 +1,46 @@
+package bdd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Store is an in-memory key-value store.
+type Store struct {
+	data map[string]string
+}
+
+func NewStore() *Store {
+	return &Store{data: map[string]string{}}
+}
+
+func (s *Store) Get(key string) (string, error) {
+	v, ok := s.data[key]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	return v, nil
+}
+
+func (s *Store) Put(key, value string) {
+	s.data[key] = value
+}
+
+func (s *Store) Delete(key string) {
+	delete(s.data, key)
+}
+
+func (s *Store) Clear() {
+	s.data = map[string]string{}
+}
+
+func Format(key string, value int) string {
+	return fmt.Sprintf("%-4s = %d", key, value)
+}
+
+func Parse(s string) string {
+	return strings.TrimSpace(strings.SplitN(s, "=", 2)[0])
+}
+
+func Untested() {}
@@ -0,0 +1,5 @@
+package constraints
+
+func open() string {
+	return "linux"
+}
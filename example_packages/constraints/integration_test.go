@@ -0,0 +1,11 @@
+//go:build integration
+
+package constraints
+
+import (
+	"testing"
+)
+
+func TestFlush(t *testing.T) {
+	flush()
+}
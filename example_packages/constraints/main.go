@@ -0,0 +1,7 @@
+package constraints
+
+func name() string {
+	return open()
+}
+
+func flush() {}
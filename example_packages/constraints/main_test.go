@@ -0,0 +1,9 @@
+package constraints
+
+import (
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	open()
+}
@@ -0,0 +1,38 @@
+package convention
+
+import "strings"
+
+// Store is a key-value store.
+type Store struct {
+	data map[string]string
+}
+
+func (s *Store) Get(key string) string {
+	return s.data[key]
+}
+
+func (s *Store) Put(key, value string) {
+	s.data[key] = value
+}
+
+func Parse(in string) []string {
+	return strings.Split(in, ",")
+}
+
+func Format(in []string) string {
+	return strings.Join(in, ",")
+}
+
+func normalize(in string) string {
+	return strings.ToLower(strings.TrimSpace(in))
+}
+
+type cache struct {
+	entries map[string]string
+}
+
+func (c *cache) get(key string) string {
+	return c.entries[normalize(key)]
+}
+
+func Untested() {}
@@ -0,0 +1,38 @@
+package convention
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(m.Run())
+}
+
+func TestParse(t *testing.T) {
+	Parse("a,b")
+}
+
+func TestFormat_empty(t *testing.T) {
+	Format(nil)
+}
+
+func TestStore_Get(t *testing.T) {
+	s := &Store{data: map[string]string{}}
+	s.Put("key", "value")
+	s.Get("key")
+}
+
+func Test_normalize(t *testing.T) {
+	normalize(" A ")
+}
+
+func Test_cache_get_missing(t *testing.T) {
+	c := &cache{entries: map[string]string{}}
+	c.get("key")
+}
+
+// Render was renamed to Format, but its test wasn't.
+func TestRender(t *testing.T) {
+	Format([]string{"a"})
+}
@@ -0,0 +1,17 @@
+package depth
+
+func top() string {
+	return middle()
+}
+
+func middle() string {
+	return bottom()
+}
+
+func bottom() string {
+	return "bottom"
+}
+
+func unreachable() string {
+	return "unreachable"
+}
@@ -0,0 +1,9 @@
+package depth
+
+import (
+	"testing"
+)
+
+func TestTop(t *testing.T) {
+	top()
+}
@@ -0,0 +1,33 @@
+package dispatch
+
+type store interface {
+	get(key string) string
+}
+
+type memStore struct{}
+
+func (m *memStore) get(key string) string {
+	return key
+}
+
+type diskStore struct{}
+
+func (d diskStore) get(key string) string {
+	return key
+}
+
+type cache struct {
+	store store
+}
+
+func newCache(s store) *cache {
+	return &cache{store: s}
+}
+
+func (c *cache) lookup(key string) string {
+	return c.store.get(key)
+}
+
+func closed() {}
+
+func deferred() {}
@@ -0,0 +1,26 @@
+package dispatch
+
+import (
+	"testing"
+)
+
+func TestInterface(t *testing.T) {
+	var s store = &memStore{}
+	s.get("x")
+}
+
+func TestClosure(t *testing.T) {
+	t.Run("closure", func(t *testing.T) {
+		closed()
+	})
+}
+
+func TestDefer(t *testing.T) {
+	defer deferred()
+}
+
+func TestMethodValue(t *testing.T) {
+	c := newCache(&memStore{})
+	lookup := c.lookup
+	lookup("x")
+}
@@ -0,0 +1,16 @@
+package external_test
+
+import (
+	"testing"
+
+	. "github.com/verygoodsoftwarenotvirus/tarp/example_packages/external"
+	ext "github.com/verygoodsoftwarenotvirus/tarp/example_packages/external"
+)
+
+func TestRenamed(t *testing.T) {
+	ext.Renamed()
+}
+
+func TestDotted(t *testing.T) {
+	Dotted()
+}
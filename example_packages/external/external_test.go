@@ -0,0 +1,13 @@
+package external_test
+
+import (
+	"testing"
+
+	"github.com/verygoodsoftwarenotvirus/tarp/example_packages/external"
+)
+
+func TestExported(t *testing.T) {
+	external.Exported()
+	c := external.NewCounter()
+	c.Increment()
+}
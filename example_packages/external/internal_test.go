@@ -0,0 +1,10 @@
+package external
+
+import (
+	"testing"
+)
+
+func TestInternal(t *testing.T) {
+	internal()
+	newCounter().Reset()
+}
@@ -0,0 +1,41 @@
+package external
+
+type counter struct {
+	count int
+}
+
+func newCounter() *counter {
+	return &counter{}
+}
+
+func (c *counter) Increment() {
+	c.count++
+}
+
+func (c *counter) Reset() {
+	c.count = 0
+}
+
+func internal() int {
+	return 1
+}
+
+func Exported() int {
+	return 2
+}
+
+func Renamed() int {
+	return 3
+}
+
+func Dotted() int {
+	return 4
+}
+
+func NewCounter() *counter {
+	return newCounter()
+}
+
+func Untested() int {
+	return 5
+}
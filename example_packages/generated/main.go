@@ -0,0 +1,18 @@
+package generated
+
+// Kind is the kind of a request.
+type Kind int
+
+const (
+	Get Kind = iota
+	Put
+)
+
+func Parse(s string) Kind {
+	if s == "put" {
+		return Put
+	}
+	return Get
+}
+
+func Untested() {}
@@ -0,0 +1,9 @@
+package generated
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	if Parse("put").String() != "Put" {
+		t.Fail()
+	}
+}
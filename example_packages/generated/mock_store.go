@@ -0,0 +1,8 @@
+package generated
+
+// MockStore is a hand-rolled mock, generated by nothing tarp can see.
+type MockStore struct{}
+
+func NewMockStore() *MockStore {
+	return &MockStore{}
+}
@@ -0,0 +1,64 @@
+package generics
+
+// List is a generic singly linked list.
+type List[T any] struct {
+	head *node[T]
+	size int
+}
+
+type node[T any] struct {
+	value T
+	next  *node[T]
+}
+
+func NewList[T any]() *List[T] {
+	return &List[T]{}
+}
+
+func (l *List[T]) Push(v T) {
+	l.head = &node[T]{value: v, next: l.head}
+	l.size++
+}
+
+func (l *List[T]) Pop() (T, bool) {
+	var zero T
+	if l.head == nil {
+		return zero, false
+	}
+	v := l.head.value
+	l.head = l.head.next
+	l.size--
+	return v, true
+}
+
+func (l List[T]) Len() int {
+	return l.size
+}
+
+// Pair holds two values of possibly different types.
+type Pair[K, V any] struct {
+	Key   K
+	Value V
+}
+
+func (p Pair[K, V]) Swap() Pair[V, K] {
+	return Pair[V, K]{Key: p.Value, Value: p.Key}
+}
+
+func Map[T, U any](in []T, f func(T) U) []U {
+	out := make([]U, 0, len(in))
+	for _, v := range in {
+		out = append(out, f(v))
+	}
+	return out
+}
+
+func Filter[T any](in []T, keep func(T) bool) []T {
+	out := []T{}
+	for _, v := range in {
+		if keep(v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,28 @@
+package generics
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestList(t *testing.T) {
+	l := NewList[int]()
+	l.Push(1)
+	if v, ok := l.Pop(); !ok || v != 1 {
+		t.Errorf("expected 1, got %d", v)
+	}
+}
+
+func TestPair(t *testing.T) {
+	p := Pair[string, int]{Key: "one", Value: 1}
+	if swapped := p.Swap(); swapped.Key != 1 {
+		t.Errorf("expected 1, got %d", swapped.Key)
+	}
+}
+
+func TestMap(t *testing.T) {
+	out := Map[int, string]([]int{1, 2}, strconv.Itoa)
+	if len(out) != 2 {
+		t.Errorf("expected 2 results, got %d", len(out))
+	}
+}
@@ -0,0 +1,39 @@
+package helpers
+
+import "testing"
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	s := NewStore()
+	fill(t)
+	return s
+}
+
+func fill(tb testing.TB) {
+	s := NewStore()
+	s.Put("key", "value")
+}
+
+// unused isn't called by any test, so what it calls isn't tested.
+func unused(t *testing.T) {
+	t.Helper()
+	Abandoned()
+}
+
+type fixture struct{}
+
+func (fixture) seed(s *Store) {
+	Seed(s)
+}
+
+// seedAll is neither a test nor a helper, but what it calls is still only reached through the tests that call it.
+func seedAll(s *Store) {
+	fixture{}.seed(s)
+}
+
+func TestStore(t *testing.T) {
+	s := newTestStore(t)
+	s.Get("key")
+	seedAll(s)
+	Reset(s)
+}
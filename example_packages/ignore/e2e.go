@@ -0,0 +1,7 @@
+//tarp:ignore-file reason="exercised by the e2e suite"
+
+package ignore
+
+func Start() {}
+
+func Stop() {}
@@ -0,0 +1,28 @@
+package ignore
+
+import "strconv"
+
+func Parse(s string) (int, error) {
+	return strconv.Atoi(s)
+}
+
+// MustParse is like Parse, but panics when s isn't a number.
+//
+//tarp:ignore reason="panicking wrapper around Parse"
+func MustParse(s string) int {
+	n, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+//tarp:ignore reason="covered by the e2e suite" until=2000-01-01
+func Serve() {}
+
+//tarp:ignore reason="trivial getter"
+func Name() string {
+	return "ignore"
+}
+
+func Untested() {}
@@ -0,0 +1,11 @@
+package ignore
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	Parse("1")
+}
+
+func TestName(t *testing.T) {
+	Name()
+}
@@ -0,0 +1,15 @@
+package initcalls
+
+var greeting string
+
+func init() {
+	greeting = helper()
+}
+
+func helper() string {
+	return "hello"
+}
+
+func Greet() string {
+	return greeting
+}
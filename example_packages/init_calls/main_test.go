@@ -0,0 +1,9 @@
+package initcalls
+
+import (
+	"testing"
+)
+
+func TestGreet(t *testing.T) {
+	Greet()
+}
@@ -0,0 +1,33 @@
+package references
+
+import (
+	"sort"
+)
+
+type handler struct{}
+
+func (h handler) ServeUser() string {
+	return "user"
+}
+
+func lessByName(a, b string) bool {
+	return a < b
+}
+
+func sortStrings(s []string, less func(a, b string) bool) {
+	sort.Slice(s, func(i, j int) bool { return less(s[i], s[j]) })
+}
+
+func fetch() error {
+	return nil
+}
+
+func retry(f func() error) error {
+	return f()
+}
+
+func B() string {
+	return "b"
+}
+
+func Untested() {}
@@ -0,0 +1,36 @@
+package references
+
+import (
+	"testing"
+)
+
+func TestSortStrings(t *testing.T) {
+	sortStrings([]string{"b", "a"}, lessByName)
+}
+
+func TestRetry(t *testing.T) {
+	retry(fetch)
+}
+
+func TestMethodValue(t *testing.T) {
+	h := handler{}
+	serve := h.ServeUser
+	serve()
+}
+
+func TestTable(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func() string
+	}{
+		{name: "b", fn: B},
+	}
+	for _, tc := range tests {
+		tc.fn()
+	}
+}
+
+func TestShadowed(t *testing.T) {
+	Untested := func() error { return nil }
+	retry(Untested)
+}
@@ -0,0 +1,47 @@
+package statements
+
+func ifInit() error                  { return nil }
+func ifCond() bool                   { return true }
+func elseBranch()                    {}
+func forInit() int                   { return 0 }
+func forCond() int                   { return 1 }
+func forPost() int                   { return 1 }
+func rangeExpr() []int               { return []int{1} }
+func switchInit() int                { return 0 }
+func switchTag() int                 { return 0 }
+func caseExpr() int                  { return 0 }
+func typeSwitchSubject() interface{} { return 0 }
+func deferArg() int                  { return 0 }
+func goArg() int                     { return 0 }
+func labeled()                       {}
+func block()                         {}
+func subtest()                       {}
+func mapKey() string                 { return "key" }
+func mapValue() int                  { return 0 }
+func sliceElement() int              { return 0 }
+func indexExpr() int                 { return 0 }
+func sliceBound() int                { return 0 }
+func binaryExpr() int                { return 0 }
+func unaryExpr() bool                { return false }
+func nestedLiteral() int             { return 0 }
+func keyedField() int                { return 0 }
+func sendValue() int                 { return 0 }
+func incDec() string                 { return "key" }
+func parenExpr() int                 { return 0 }
+func starExpr() *int                 { return new(int) }
+func typeAssertion() interface{}     { return 0 }
+func closureArg() int                { return 0 }
+func returnValue() int               { return 0 }
+func varDecl() int                   { return 0 }
+
+func receiveChan() chan int {
+	c := make(chan int, 1)
+	c <- 0
+	return c
+}
+
+func selectCase() chan int {
+	c := make(chan int, 1)
+	c <- 0
+	return c
+}
@@ -0,0 +1,91 @@
+package statements
+
+import (
+	"testing"
+)
+
+func use(...interface{}) {}
+
+func TestStatements(t *testing.T) {
+	if err := ifInit(); err != nil {
+		t.FailNow()
+	}
+
+	if ifCond() {
+		t.Log("if")
+	} else {
+		elseBranch()
+	}
+
+	for i := forInit(); i < forCond(); i += forPost() {
+		t.Log("for")
+	}
+
+	for range rangeExpr() {
+		t.Log("range")
+	}
+
+	switch x := switchInit(); switchTag() {
+	case caseExpr():
+		use(x)
+	}
+
+	switch typeSwitchSubject().(type) {
+	case int:
+		t.Log("type switch")
+	}
+
+	defer use(deferArg())
+
+	done := make(chan bool)
+	go func(int) {
+		done <- true
+	}(goArg())
+	<-done
+
+outer:
+	for {
+		labeled()
+		break outer
+	}
+
+	{
+		block()
+	}
+
+	t.Run("subtest", func(t *testing.T) {
+		subtest()
+	})
+
+	m := map[string]int{mapKey(): mapValue()}
+	s := []int{sliceElement()}
+	use(s[indexExpr()], s[:sliceBound()], 1+binaryExpr(), !unaryExpr())
+	use([][]int{{nestedLiteral()}}, struct{ f int }{f: keyedField()})
+
+	c := make(chan int, 1)
+	c <- sendValue()
+	<-receiveChan()
+
+	select {
+	case <-selectCase():
+		t.Log("select")
+	}
+
+	m[incDec()]++
+	use((parenExpr()), *starExpr())
+
+	if _, ok := typeAssertion().(int); !ok {
+		t.FailNow()
+	}
+
+	use(func() int {
+		return closureArg()
+	}())
+
+	use(func() int {
+		return returnValue()
+	})
+
+	var v = varDecl()
+	use(v)
+}
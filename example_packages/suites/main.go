@@ -0,0 +1,48 @@
+package suites
+
+// Store is a key-value store.
+type Store interface {
+	Get(key string) (string, bool)
+	Put(key, value string)
+}
+
+type memStore struct {
+	data map[string]string
+}
+
+func newMemStore() *memStore {
+	return &memStore{data: map[string]string{}}
+}
+
+func (m *memStore) Get(key string) (string, bool) {
+	v, ok := m.data[key]
+	return v, ok
+}
+
+func (m *memStore) Put(key, value string) {
+	m.data[key] = value
+}
+
+// Cache counts the lookups that find something in its store.
+type Cache struct {
+	store Store
+	hits  int
+}
+
+func NewCache(store Store) *Cache {
+	return &Cache{store: store}
+}
+
+func (c *Cache) Lookup(key string) string {
+	v, ok := c.store.Get(key)
+	if ok {
+		c.hits++
+	}
+	return v
+}
+
+func (c *Cache) Hits() int {
+	return c.hits
+}
+
+func Untested() {}
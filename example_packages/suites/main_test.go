@@ -0,0 +1,33 @@
+package suites
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type StoreSuite struct {
+	suite.Suite
+	store Store
+	cache *Cache
+}
+
+func (s *StoreSuite) SetupTest() {
+	s.store = newMemStore()
+	s.cache = NewCache(s.store)
+}
+
+func (s *StoreSuite) TestPut() {
+	s.store.Put("key", "value")
+	v, ok := s.store.Get("key")
+	s.True(ok)
+	s.Equal("value", v)
+}
+
+func (s *StoreSuite) TestLookup() {
+	s.Equal("", s.cache.Lookup("missing"))
+}
+
+func TestStoreSuite(t *testing.T) {
+	suite.Run(t, new(StoreSuite))
+}
@@ -0,0 +1,53 @@
+package tables
+
+import (
+	"testing"
+)
+
+var subTests = map[string]struct {
+	a, b, want int
+}{
+	"equal": {a: 1, b: 1, want: 0},
+}
+
+func TestAdd(t *testing.T) {
+	tests := []struct {
+		name       string
+		a, b, want int
+	}{
+		{name: "positive", a: 1, b: 2, want: 3},
+		{name: "negative", a: -1, b: -2, want: -3},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Add(tc.a, tc.b); got != tc.want {
+				t.Errorf("expected %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSub(t *testing.T) {
+	for name, tc := range subTests {
+		t.Run(name, func(t *testing.T) {
+			if got := Sub(tc.a, tc.b); got != tc.want {
+				t.Errorf("expected %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestMul(t *testing.T) {
+	for _, tc := range []struct{ a, b, want int }{{2, 3, 6}, {4, 5, 20}} {
+		if got := Mul(tc.a, tc.b); got != tc.want {
+			t.Errorf("expected %d, got %d", tc.want, got)
+		}
+	}
+}
+
+func TestDiv(t *testing.T) {
+	if got := Div(4, 2); got != 2 {
+		t.Errorf("expected 2, got %d", got)
+	}
+}
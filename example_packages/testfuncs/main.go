@@ -0,0 +1,30 @@
+package testfuncs
+
+import "strings"
+
+// Parse splits a comma separated list.
+func Parse(in string) []string {
+	return strings.Split(in, ",")
+}
+
+// Format joins a list with commas.
+func Format(in []string) string {
+	return strings.Join(in, ",")
+}
+
+// Normalize lowercases and trims its input.
+func Normalize(in string) string {
+	return strings.TrimSpace(strings.ToLower(in))
+}
+
+// Validate reports whether a list has no empty elements.
+func Validate(in []string) bool {
+	for _, s := range in {
+		if s == "" {
+			return false
+		}
+	}
+	return true
+}
+
+func Untested() {}
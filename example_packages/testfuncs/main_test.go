@@ -0,0 +1,33 @@
+package testfuncs
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	Parse("a,b")
+}
+
+func BenchmarkParse(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		Parse("a,b")
+	}
+}
+
+func BenchmarkFormat(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		Format([]string{"a", "b"})
+	}
+}
+
+func ExampleValidate() {
+	fmt.Println(Validate([]string{"a", "b"}))
+	// Output: true
+}
+
+func FuzzNormalize(f *testing.F) {
+	f.Fuzz(func(t *testing.T, in string) {
+		Normalize(in)
+	})
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"log"
+	"os"
+)
+
+// exit codes tell CI wrappers why tarp failed. Finding functions without direct tests, or breaching one of
+// the thresholds, exits with 1, like log.Fatal would. The others follow sysexits.h, which keeps them clear of
+// 2, the code the Go runtime exits with when tarp crashes.
+const (
+	exitUntested   = 1
+	exitUsageError = 64
+	exitLoadError  = 66
+)
+
+// fatalf logs a message like log.Fatalf does, but exits with the given code instead of 1. Problems finding,
+// reading or parsing code, or reading or writing files like the baseline, exit with exitLoadError, and
+// invalid flags, arguments or settings, including malformed baselines, exit with exitUsageError.
+func fatalf(code int, format string, v ...interface{}) {
+	log.Printf(format, v...)
+	os.Exit(code)
+}
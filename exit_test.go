@@ -0,0 +1,19 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFatalf(t *testing.T) {
+	exitWith := func(code int, format string, v ...interface{}) (out interface{}) {
+		// recovered from our monkey patched os.Exit
+		defer func() { out = recover() }()
+		fatalf(code, format, v...)
+		return nil
+	}
+
+	assert.Equal(t, "os.Exit(64)", exitWith(exitUsageError, "unknown analysis engine: %q", "nope"))
+	assert.Equal(t, "os.Exit(66)", exitWith(exitLoadError, "no go files found!"))
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"go/ast"
+	"path"
+	"path/filepath"
+)
+
+// validateGeneratedPatterns makes sure every --generated-pattern is a valid pattern.
+func validateGeneratedPatterns() {
+	for _, pattern := range generatedPatterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			fatalf(exitUsageError, "invalid --generated-pattern %q: %v", pattern, err)
+		}
+	}
+}
+
+// isGeneratedFile reports whether the given file was generated, either because it starts with the standard
+// "// Code generated ... DO NOT EDIT." comment, or because its name matches one of the --generated-pattern
+// patterns.
+func isGeneratedFile(in *ast.File, filename string) bool {
+	if ast.IsGenerated(in) {
+		return true
+	}
+	for _, pattern := range generatedPatterns {
+		if matched, _ := path.Match(pattern, filepath.Base(filename)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// excludedFromGrade reports whether the given function is left out of its package's grade, either because
+// it's ignored by a directive that hasn't expired, or because it's generated and --count-generated isn't set.
+func excludedFromGrade(tf tarpFunc) bool {
+	if tf.Ignore != nil && !tf.Ignore.Expired {
+		return true
+	}
+	return tf.Generated && !countGenerated
+}
+
+// applyGenerated leaves the functions declared in generated files out of the report's declared and called
+// functions, unless --count-generated is set. They're still reported, with a score of their own.
+func applyGenerated(report tarpReport) tarpReport {
+	if countGenerated {
+		return report
+	}
+	for key, f := range report.DeclaredDetails {
+		if f.Generated {
+			report.Declared.Remove(key)
+			report.Called.Remove(key)
+		}
+	}
+	return report
+}
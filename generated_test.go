@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/fatih/set"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateGeneratedPatterns(t *testing.T) {
+	defer func() { generatedPatterns = nil }()
+
+	generatedPatterns = []string{"*.pb.go", "mock_*.go"}
+	assert.NotPanics(t, func() { validateGeneratedPatterns() })
+
+	generatedPatterns = []string{"[mock_*.go"}
+	assert.Panics(t, func() { validateGeneratedPatterns() }, "invalid patterns should be fatal")
+}
+
+func TestIsGeneratedFile(t *testing.T) {
+	defer func() { generatedPatterns = nil }()
+
+	stringer := parseCommentedChunkOfCode(t, `
+		// Code generated by "stringer -type=Kind"; DO NOT EDIT.
+
+		package main
+	`)
+	handwritten := parseCommentedChunkOfCode(t, `
+		// Package main does things by hand.
+		package main
+	`)
+
+	assert.True(t, isGeneratedFile(stringer, "kind_string.go"))
+	assert.False(t, isGeneratedFile(handwritten, "mock_store.go"))
+
+	generatedPatterns = []string{"mock_*.go"}
+	assert.True(t, isGeneratedFile(handwritten, "example_packages/generated/mock_store.go"), "patterns should match file names")
+	assert.False(t, isGeneratedFile(handwritten, "main.go"))
+}
+
+func TestExcludedFromGrade(t *testing.T) {
+	assert.False(t, excludedFromGrade(tarpFunc{Name: "a"}))
+	assert.True(t, excludedFromGrade(tarpFunc{Name: "a", Ignore: &tarpIgnore{}}))
+	assert.False(t, excludedFromGrade(tarpFunc{Name: "a", Ignore: &tarpIgnore{Expired: true}}), "expired directives don't exclude anything")
+	assert.True(t, excludedFromGrade(tarpFunc{Name: "a", Generated: true}))
+
+	countGenerated = true
+	defer func() { countGenerated = false }()
+	assert.False(t, excludedFromGrade(tarpFunc{Name: "a", Generated: true}))
+}
+
+func TestApplyGenerated(t *testing.T) {
+	buildGeneratedReport := func() tarpReport {
+		return tarpReport{
+			DeclaredDetails: map[string]tarpFunc{
+				"kind_string.go:Kind.String": {Name: "Kind.String", Distance: 1, Generated: true},
+				"main.go:Parse":              {Name: "Parse", Distance: 1},
+			},
+			Declared: set.New("kind_string.go:Kind.String", "main.go:Parse"),
+			Called:   set.New("kind_string.go:Kind.String", "main.go:Parse"),
+		}
+	}
+
+	actual := applyGenerated(buildGeneratedReport())
+	assert.Equal(t, set.New("main.go:Parse"), actual.Declared)
+	assert.Equal(t, set.New("main.go:Parse"), actual.Called)
+	assert.Len(t, actual.DeclaredDetails, 2, "generated functions should still be reported")
+
+	countGenerated = true
+	defer func() { countGenerated = false }()
+
+	actual = applyGenerated(buildGeneratedReport())
+	assert.Equal(t, set.New("kind_string.go:Kind.String", "main.go:Parse"), actual.Declared)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+	"sort"
+
+	"github.com/fatih/set"
+)
+
+// isTestHelper reports whether a function declared in a _test.go file is a test helper: a plain function,
+// other than a test, example, benchmark or fuzz target, that calls t.Helper() or takes a testing.TB. What
+// a helper calls only counts as tested when a test calls the helper.
+func isTestHelper(f *ast.FuncDecl) bool {
+	if f.Recv != nil || f.Body == nil || isTestName(f.Name.Name, "Test") || funcKind(f.Name.Name, false) != testFunc {
+		return false
+	}
+
+	for _, param := range f.Type.Params.List {
+		if sel, ok := param.Type.(*ast.SelectorExpr); ok && sel.Sel.Name == "TB" {
+			if pkg, ok := sel.X.(*ast.Ident); ok && pkg.Name == "testing" {
+				return true
+			}
+		}
+	}
+
+	found := false
+	ast.Inspect(f.Body, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok && len(call.Args) == 0 {
+			if sel, ok := call.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Helper" {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// findTestHelpers adds the names of the test helpers declared in the given file to out.
+func findTestHelpers(in *ast.File, out *set.Set) {
+	for _, d := range in.Decls {
+		if f, ok := d.(*ast.FuncDecl); ok && isTestHelper(f) {
+			out.Add(f.Name.Name)
+		}
+	}
+}
+
+// getHelperNames adds the names of the test helpers in helpers that are called anywhere in the given node to
+// out. Unlike getCalledNames, it doesn't skip helpers that return something.
+func getHelperNames(in ast.Node, helpers *set.Set, out *set.Set) {
+	ast.Inspect(in, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			if id, ok := call.Fun.(*ast.Ident); ok && helpers.Has(id.Name) {
+				out.Add(id.Name)
+			}
+		}
+		return true
+	})
+}
+
+// getResolvedHelperNames adds the names of the test helpers in helpers that are called anywhere in the given
+// node to out. Helpers are looked for in pkg, the package the node belongs to, which for black-box tests
+// isn't the package under test.
+func getResolvedHelperNames(in ast.Node, info *types.Info, pkg *types.Package, helpers *set.Set, out *set.Set) {
+	ast.Inspect(in, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			if fn := calleeOf(call, info); fn != nil && fn.Pkg() == pkg && helpers.Has(fn.Name()) {
+				out.Add(fn.Name())
+			}
+		}
+		return true
+	})
+}
+
+// creditHelpers credits the functions called by the test helpers in helperCalls, keyed by helper name, to the
+// tests that call those helpers, whether directly or through other helpers. The kinds of test function that
+// call each helper are taken from kinds, which is expected to already hold the calls the tests themselves
+// made. Functions that tests in out don't call directly are recorded in carriers along with the helpers that
+// carried their credit.
+func creditHelpers(helperCalls map[string]*set.Set, out *set.Set, kinds map[string]*set.Set, carriers map[string]*set.Set) {
+	direct := set.New()
+	direct.Merge(out)
+
+	// helpers called by helpers inherit the kinds of test function that call the helpers calling them
+	for changed := true; changed; {
+		changed = false
+		for helper, called := range helperCalls {
+			k, ok := kinds[helper]
+			if !ok {
+				continue
+			}
+			for _, name := range set.StringSlice(called) {
+				if _, ok := helperCalls[name]; !ok {
+					continue
+				}
+				if _, ok := kinds[name]; !ok {
+					kinds[name] = set.New()
+				}
+				if !kinds[name].Has(k.List()...) {
+					kinds[name].Merge(k)
+					changed = true
+				}
+			}
+		}
+	}
+
+	for helper, called := range helperCalls {
+		k, ok := kinds[helper]
+		if !ok {
+			continue
+		}
+		credited := set.New()
+		for _, name := range set.StringSlice(called) {
+			if _, ok := helperCalls[name]; !ok {
+				credited.Add(name)
+			}
+		}
+		counted := false
+		for _, kind := range set.StringSlice(k) {
+			recordFuncKind(kind, credited, out, kinds)
+			counted = counted || countsAsTest(kind)
+		}
+		if !counted {
+			continue
+		}
+		for _, name := range set.StringSlice(credited) {
+			if !direct.Has(name) {
+				if _, ok := carriers[name]; !ok {
+					carriers[name] = set.New()
+				}
+				carriers[name].Add(helper)
+			}
+		}
+	}
+}
+
+// applyHelperCredits records the helpers that carried the credit for each declared function tests only reach
+// through helpers.
+func applyHelperCredits(declaredFuncInfo map[string]tarpFunc, carriers map[string]*set.Set) {
+	for key, f := range declaredFuncInfo {
+		if helpers, ok := carriers[f.Name]; ok {
+			f.Helpers = set.StringSlice(helpers)
+			sort.Strings(f.Helpers)
+			declaredFuncInfo[key] = f
+		}
+	}
+}
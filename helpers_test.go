@@ -0,0 +1,123 @@
+package main
+
+import (
+	"go/ast"
+	"testing"
+
+	"github.com/fatih/set"
+	"github.com/stretchr/testify/assert"
+)
+
+const helpersChunkOfCode = `
+	package main
+
+	func newTestStore(t *testing.T) *Store {
+		t.Helper()
+		return NewStore()
+	}
+
+	func fill(tb testing.TB, s *Store) {
+		s.Put("key", "value")
+	}
+
+	func plain(s *Store) {}
+
+	func TestStore(t *testing.T) {
+		t.Helper()
+		fill(t, newTestStore(t))
+	}
+
+	func BenchmarkStore(b *testing.B) {
+		b.Helper()
+	}
+
+	func (s *StoreSuite) helper() {
+		s.T().Helper()
+	}
+`
+
+func TestIsTestHelper(t *testing.T) {
+	p := parseChunkOfCode(t, helpersChunkOfCode)
+
+	expected := map[string]bool{
+		"newTestStore":      true,
+		"fill":              true,
+		"plain":             false,
+		"TestStore":         false,
+		"BenchmarkStore":    false,
+		"StoreSuite.helper": false,
+	}
+	for name, helper := range expected {
+		assert.Equal(t, helper, isTestHelper(findFuncDecl([]*ast.File{p}, name)), name)
+	}
+}
+
+func TestFindTestHelpers(t *testing.T) {
+	p := parseChunkOfCode(t, helpersChunkOfCode)
+
+	actual := set.New()
+	findTestHelpers(p, actual)
+	assert.Equal(t, set.New("newTestStore", "fill"), actual)
+}
+
+func TestGetHelperNames(t *testing.T) {
+	p := parseChunkOfCode(t, helpersChunkOfCode)
+
+	actual := set.New()
+	getHelperNames(findFuncDecl([]*ast.File{p}, "TestStore"), set.New("newTestStore", "fill"), actual)
+	assert.Equal(t, set.New("newTestStore", "fill"), actual)
+}
+
+func TestGetResolvedHelperNames(t *testing.T) {
+	prog, _, err := loadPackage(buildExamplePackagePath(t, "helpers", true))
+	assert.Nil(t, err)
+
+	pkgInfo := prog.InitialPackages()[0]
+	actual := set.New()
+	getResolvedHelperNames(findFuncDecl(pkgInfo.Files, "newTestStore"), &pkgInfo.Info, pkgInfo.Pkg, set.New("fill"), actual)
+	assert.Equal(t, set.New("fill"), actual, "only helpers should be returned")
+}
+
+func TestCreditHelpers(t *testing.T) {
+	transitive := func(t *testing.T) {
+		helperCalls := map[string]*set.Set{
+			"newTestStore": set.New("NewStore", "fill"),
+			"fill":         set.New("Store.Put"),
+			"unused":       set.New("Abandoned"),
+		}
+		out := set.New("newTestStore", "NewStore")
+		kinds := map[string]*set.Set{"newTestStore": set.New(testFunc), "NewStore": set.New(testFunc)}
+		carriers := map[string]*set.Set{}
+		creditHelpers(helperCalls, out, kinds, carriers)
+
+		assert.Equal(t, set.New("newTestStore", "NewStore", "Store.Put"), out)
+		assert.Equal(t, set.New(testFunc), kinds["Store.Put"])
+		assert.Equal(t, map[string]*set.Set{"Store.Put": set.New("fill")}, carriers, "functions tests call directly shouldn't be credited to helpers")
+	}
+	t.Run("transitive", transitive)
+
+	uncounted := func(t *testing.T) {
+		defer func() { countBenchmarks = true }()
+		countBenchmarks = false
+
+		out, carriers := set.New(), map[string]*set.Set{}
+		kinds := map[string]*set.Set{"fill": set.New(benchmarkFunc)}
+		creditHelpers(map[string]*set.Set{"fill": set.New("Store.Put")}, out, kinds, carriers)
+
+		assert.Equal(t, set.New(), out, "helpers only called by benchmarks shouldn't count")
+		assert.Equal(t, set.New(benchmarkFunc), kinds["Store.Put"])
+		assert.Empty(t, carriers)
+	}
+	t.Run("uncounted kinds", uncounted)
+}
+
+func TestApplyHelperCredits(t *testing.T) {
+	declaredFuncInfo := map[string]tarpFunc{
+		"main.go:NewStore": {Name: "NewStore"},
+		"main.go:Untested": {Name: "Untested"},
+	}
+	applyHelperCredits(declaredFuncInfo, map[string]*set.Set{"NewStore": set.New("newTestStore", "fill")})
+
+	assert.Equal(t, []string{"fill", "newTestStore"}, declaredFuncInfo["main.go:NewStore"].Helpers)
+	assert.Nil(t, declaredFuncInfo["main.go:Untested"].Helpers)
+}
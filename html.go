@@ -22,10 +22,12 @@ import (
 	"math"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"runtime"
 	"strings"
 
+	"github.com/fatih/set"
 	"golang.org/x/tools/cover"
 )
 
@@ -138,9 +140,14 @@ type templateFile struct {
 	Coverage float64
 }
 
-// findFile finds the location of the named file in GOROOT, GOPATH etc.
+// findFile finds the location of the named file in the current module, GOROOT, GOPATH etc.
 func findFile(path string) (string, error) {
 	dir, file := filepath.Split(path)
+	if wd, err := os.Getwd(); err == nil {
+		if pkgDir, ok := resolveImportPath(filepath.Clean(dir), wd); ok {
+			return filepath.Join(pkgDir, file), nil
+		}
+	}
 	pkg, err := build.Import(dir, ".", build.FindOnly)
 	if err != nil {
 		return "", fmt.Errorf("can't find %q: %v", file, err)
@@ -151,7 +158,8 @@ func findFile(path string) (string, error) {
 // htmlOutput reads the profile data from profile and generates an HTML
 // coverage report, writing it to outfile. If outfile is empty,
 // it writes the report to a temporary file and opens it in a web browser.
-func htmlOutput(profilePath, outfile string, report tarpReport) error {
+// reports holds the analysis of each package in the profile, keyed by import path.
+func htmlOutput(profilePath, outfile string, reports map[string]tarpReport) error {
 	profiles, err := cover.ParseProfiles(profilePath)
 	if err != nil {
 		return err
@@ -173,9 +181,14 @@ func htmlOutput(profilePath, outfile string, report tarpReport) error {
 			return fmt.Errorf("can't read %q: %v", fn, err)
 		}
 
+		report, ok := reports[path.Dir(fn)]
+		if !ok {
+			report = tarpReport{Called: set.New()}
+		}
+
 		boundaries := profile.Boundaries(src)
 		var buf bytes.Buffer
-		err = htmlGen(&buf, src, fn, boundaries, report)
+		err = htmlGen(&buf, src, file, boundaries, report)
 		if err != nil {
 			return err
 		}
@@ -254,7 +267,7 @@ func htmlGen(w io.Writer, src []byte, filename string, boundaries []cover.Bounda
 						}
 					}
 				}
-				relevantFuncCalled := report.Called.Has(relevantFunc.Name)
+				relevantFuncCalled := report.Called.Has(funcKey(relevantFunc))
 
 				n := 0
 				if b.Count > 0 {
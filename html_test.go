@@ -56,37 +56,50 @@ func TestFindFile(t *testing.T) {
 		assert.Nil(t, err)
 	}
 	t.Run("should succeed", shouldSucceed)
+
+	inModule := func(t *testing.T) {
+		modDir := buildModuleTree(t)
+		defer os.RemoveAll(filepath.Dir(modDir))
+		wd, _ := os.Getwd()
+		defer os.Chdir(wd)
+		os.Chdir(modDir)
+
+		actual, err := findFile("example.com/tarpmod/lib/lib.go")
+		assert.Nil(t, err)
+		assert.Equal(t, filepath.Join(modDir, "lib", "lib.go"), actual)
+	}
+	t.Run("in a module", inModule)
 }
 
 func TestHTMLOutput(t *testing.T) {
 	simpleMainPath := fmt.Sprintf("%s/main.go", buildExamplePackagePath(t, "simple", true))
 	simpleCountPath := buildExampleFileAbsPath(t, "example_files/simple_count.coverprofile")
 	exampleReport := tarpReport{
-		Called:   set.New("a", "c", "wrapper"),
-		Declared: set.New("a", "b", "c", "wrapper"),
+		Called:   set.New("main.go:a", "main.go:c", "main.go:wrapper"),
+		Declared: set.New("main.go:a", "main.go:b", "main.go:c", "main.go:wrapper"),
 		DeclaredDetails: map[string]tarpFunc{
-			"a": {
+			"main.go:a": {
 				Name:      "a",
 				Filename:  simpleMainPath,
 				DeclPos:   token.Position{Filename: simpleMainPath, Offset: 16, Line: 3, Column: 1},
 				RBracePos: token.Position{Filename: simpleMainPath, Offset: 32, Line: 3, Column: 17},
 				LBracePos: token.Position{Filename: simpleMainPath, Offset: 46, Line: 5, Column: 1},
 			},
-			"b": {
+			"main.go:b": {
 				Name:      "b",
 				Filename:  simpleMainPath,
 				DeclPos:   token.Position{Filename: simpleMainPath, Offset: 49, Line: 7, Column: 1},
 				RBracePos: token.Position{Filename: simpleMainPath, Offset: 65, Line: 7, Column: 17},
 				LBracePos: token.Position{Filename: simpleMainPath, Offset: 79, Line: 9, Column: 1},
 			},
-			"c": {
+			"main.go:c": {
 				Name:      "c",
 				Filename:  simpleMainPath,
 				DeclPos:   token.Position{Filename: simpleMainPath, Offset: 82, Line: 11, Column: 1},
 				RBracePos: token.Position{Filename: simpleMainPath, Offset: 98, Line: 11, Column: 17},
 				LBracePos: token.Position{Filename: simpleMainPath, Offset: 112, Line: 13, Column: 1},
 			},
-			"wrapper": {
+			"main.go:wrapper": {
 				Name:      "wrapper",
 				Filename:  simpleMainPath,
 				DeclPos:   token.Position{Filename: simpleMainPath, Offset: 115, Line: 15, Column: 1},
@@ -96,15 +109,17 @@ func TestHTMLOutput(t *testing.T) {
 		},
 	}
 
+	exampleReports := map[string]tarpReport{buildExamplePackagePath(t, "simple", false): exampleReport}
+
 	withFailureToParseProfile := func(t *testing.T) {
-		err := htmlOutput("", "", tarpReport{})
+		err := htmlOutput("", "", map[string]tarpReport{})
 		assert.NotNil(t, err)
 	}
 	t.Run("with failure to parse profile", withFailureToParseProfile)
 
 	withFailureToFindFile := func(t *testing.T) {
 		exampleProfilePath := buildExampleFileAbsPath(t, "example_files/nonexistent_file.coverprofile")
-		err := htmlOutput(exampleProfilePath, "", tarpReport{})
+		err := htmlOutput(exampleProfilePath, "", map[string]tarpReport{})
 		assert.NotNil(t, err)
 	}
 	t.Run("with failure to find src file", withFailureToFindFile)
@@ -113,7 +128,7 @@ func TestHTMLOutput(t *testing.T) {
 		monkey.Patch(ioutil.ReadFile, func(string) ([]byte, error) { return []byte{}, errors.New("pineapple on pizza") })
 
 		exampleProfilePath := simpleCountPath
-		err := htmlOutput(exampleProfilePath, "", tarpReport{})
+		err := htmlOutput(exampleProfilePath, "", map[string]tarpReport{})
 		assert.NotNil(t, err)
 
 		monkey.Unpatch(ioutil.ReadFile)
@@ -126,7 +141,7 @@ func TestHTMLOutput(t *testing.T) {
 		})
 
 		exampleProfilePath := simpleCountPath
-		err := htmlOutput(exampleProfilePath, "", tarpReport{})
+		err := htmlOutput(exampleProfilePath, "", map[string]tarpReport{})
 		assert.NotNil(t, err)
 
 		monkey.Unpatch(htmlGen)
@@ -138,7 +153,7 @@ func TestHTMLOutput(t *testing.T) {
 
 		exampleProfilePath := simpleCountPath
 
-		err := htmlOutput(exampleProfilePath, "", exampleReport)
+		err := htmlOutput(exampleProfilePath, "", exampleReports)
 		assert.Nil(t, err)
 
 		monkey.Unpatch(startBrowser)
@@ -149,7 +164,7 @@ func TestHTMLOutput(t *testing.T) {
 		monkey.Patch(ioutil.TempDir, func(string, string) (string, error) { return "", errors.New("pineapple on pizza") })
 
 		exampleProfilePath := simpleCountPath
-		err := htmlOutput(exampleProfilePath, "", exampleReport)
+		err := htmlOutput(exampleProfilePath, "", exampleReports)
 		assert.NotNil(t, err)
 
 		monkey.Unpatch(ioutil.TempDir)
@@ -160,7 +175,7 @@ func TestHTMLOutput(t *testing.T) {
 		monkey.Patch(os.Create, func(string) (*os.File, error) { return nil, errors.New("pineapple on pizza") })
 
 		exampleProfilePath := simpleCountPath
-		err := htmlOutput(exampleProfilePath, "", exampleReport)
+		err := htmlOutput(exampleProfilePath, "", exampleReports)
 		assert.NotNil(t, err)
 
 		monkey.Unpatch(os.Create)
@@ -171,7 +186,7 @@ func TestHTMLOutput(t *testing.T) {
 		monkey.Patch(os.Create, func(string) (*os.File, error) { return nil, nil })
 
 		exampleProfilePath := simpleCountPath
-		err := htmlOutput(exampleProfilePath, "", exampleReport)
+		err := htmlOutput(exampleProfilePath, "", exampleReports)
 		assert.NotNil(t, err)
 
 		monkey.Unpatch(os.Create)
@@ -187,7 +202,7 @@ func TestHTMLOutput(t *testing.T) {
 		})
 
 		exampleProfilePath := simpleCountPath
-		err := htmlOutput(exampleProfilePath, "", exampleReport)
+		err := htmlOutput(exampleProfilePath, "", exampleReports)
 		assert.Nil(t, err)
 		assert.True(t, fmtFprintfCalled)
 
@@ -200,7 +215,7 @@ func TestHTMLOutput(t *testing.T) {
 		exampleProfilePath := simpleCountPath
 		tmpFile := buildExampleFileAbsPath(t, "temp.html")
 
-		err := htmlOutput(exampleProfilePath, tmpFile, exampleReport)
+		err := htmlOutput(exampleProfilePath, tmpFile, exampleReports)
 		if err != nil {
 			log.Println("htmlOutput should not return an error")
 			t.FailNow()
@@ -343,7 +358,7 @@ func wrapper() <span class="cov1" title="1">{
 		exampleProfilePath := buildExampleFileAbsPath(t, "example_files/simple_set.coverprofile")
 		tmpFile := buildExampleFileAbsPath(t, "temp.html")
 
-		err := htmlOutput(exampleProfilePath, tmpFile, exampleReport)
+		err := htmlOutput(exampleProfilePath, tmpFile, exampleReports)
 		if err != nil {
 			log.Println("htmlOutput should not return an error")
 			t.FailNow()
@@ -478,10 +493,10 @@ func TestHTMLGen(t *testing.T) {
 	simple := func(t *testing.T) {
 		simpleMainPath := fmt.Sprintf("%s/main.go", buildExamplePackagePath(t, "simple", true))
 		exampleReport := tarpReport{
-			Called:   set.New("a", "c", "wrapper"),
-			Declared: set.New("a", "b", "c", "wrapper"),
+			Called:   set.New("main.go:a", "main.go:c", "main.go:wrapper"),
+			Declared: set.New("main.go:a", "main.go:b", "main.go:c", "main.go:wrapper"),
 			DeclaredDetails: map[string]tarpFunc{
-				"a": {
+				"main.go:a": {
 					Name:     "a",
 					Filename: simpleMainPath,
 					DeclPos: token.Position{
@@ -503,7 +518,7 @@ func TestHTMLGen(t *testing.T) {
 						Column:   1,
 					},
 				},
-				"b": {
+				"main.go:b": {
 					Name:     "b",
 					Filename: simpleMainPath,
 					DeclPos: token.Position{
@@ -525,7 +540,7 @@ func TestHTMLGen(t *testing.T) {
 						Column:   1,
 					},
 				},
-				"c": {
+				"main.go:c": {
 					Name:     "c",
 					Filename: simpleMainPath,
 					DeclPos: token.Position{
@@ -547,7 +562,7 @@ func TestHTMLGen(t *testing.T) {
 						Column:   1,
 					},
 				},
-				"wrapper": {
+				"main.go:wrapper": {
 					Name:     "wrapper",
 					Filename: simpleMainPath,
 					DeclPos: token.Position{
@@ -618,10 +633,10 @@ func wrapper() <span class="cov1" title="1">{
 	withConditionals := func(t *testing.T) {
 		simpleMainPath := fmt.Sprintf("%s/main.go", buildExamplePackagePath(t, "conditionals", true))
 		exampleReport := tarpReport{
-			Called:   set.New("a", "c", "wrapper"),
-			Declared: set.New("a", "b", "c", "wrapper"),
+			Called:   set.New("main.go:a", "main.go:c", "main.go:wrapper"),
+			Declared: set.New("main.go:a", "main.go:b", "main.go:c", "main.go:wrapper"),
 			DeclaredDetails: map[string]tarpFunc{
-				"a": {
+				"main.go:a": {
 					Name:     "a",
 					Filename: simpleMainPath,
 					DeclPos: token.Position{
@@ -643,7 +658,7 @@ func wrapper() <span class="cov1" title="1">{
 						Column:   1,
 					},
 				},
-				"b": {
+				"main.go:b": {
 					Name:     "b",
 					Filename: simpleMainPath,
 					DeclPos: token.Position{
@@ -665,7 +680,7 @@ func wrapper() <span class="cov1" title="1">{
 						Column:   1,
 					},
 				},
-				"c": {
+				"main.go:c": {
 					Name:     "c",
 					Filename: simpleMainPath,
 					DeclPos: token.Position{
@@ -687,7 +702,7 @@ func wrapper() <span class="cov1" title="1">{
 						Column:   1,
 					},
 				},
-				"wrapper": {
+				"main.go:wrapper": {
 					Name:     "wrapper",
 					Filename: simpleMainPath,
 					DeclPos: token.Position{
@@ -761,10 +776,10 @@ func wrapper() <span class="cov8" title="1">{
 	withExecutedConditionals := func(t *testing.T) {
 		simpleMainPath := fmt.Sprintf("%s/main.go", buildExamplePackagePath(t, "executed_conditionals", true))
 		exampleReport := tarpReport{
-			Called:   set.New("b", "c", "wrapper"),
-			Declared: set.New("a", "b", "c", "wrapper"),
+			Called:   set.New("main.go:b", "main.go:c", "main.go:wrapper"),
+			Declared: set.New("main.go:a", "main.go:b", "main.go:c", "main.go:wrapper"),
 			DeclaredDetails: map[string]tarpFunc{
-				"a": {
+				"main.go:a": {
 					Name:     "a",
 					Filename: simpleMainPath,
 					DeclPos: token.Position{
@@ -786,7 +801,7 @@ func wrapper() <span class="cov8" title="1">{
 						Column:   1,
 					},
 				},
-				"b": {
+				"main.go:b": {
 					Name:     "b",
 					Filename: simpleMainPath,
 					DeclPos: token.Position{
@@ -808,7 +823,7 @@ func wrapper() <span class="cov8" title="1">{
 						Column:   1,
 					},
 				},
-				"c": {
+				"main.go:c": {
 					Name:     "c",
 					Filename: simpleMainPath,
 					DeclPos: token.Position{
@@ -830,7 +845,7 @@ func wrapper() <span class="cov8" title="1">{
 						Column:   1,
 					},
 				},
-				"wrapper": {
+				"main.go:wrapper": {
 					Name:     "wrapper",
 					Filename: simpleMainPath,
 					DeclPos: token.Position{
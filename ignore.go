@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ignore directives leave functions out of the report, either one at a time with a //tarp:ignore comment in
+// a function's doc comment, or for every function in a file with a //tarp:ignore-file comment anywhere in it.
+// Both take an optional reason="..." and an optional until=YYYY-MM-DD, after which they stop applying.
+const (
+	ignoreDirective     = "//tarp:ignore"
+	ignoreFileDirective = "//tarp:ignore-file"
+	untilLayout         = "2006-01-02"
+)
+
+// directiveOptions returns what follows directive in the given comment, and whether the comment is that
+// directive at all. //tarp:ignore-file isn't a //tarp:ignore directive.
+func directiveOptions(comment, directive string) (string, bool) {
+	if !strings.HasPrefix(comment, directive) {
+		return "", false
+	}
+	rest := comment[len(directive):]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return "", false
+	}
+	return strings.TrimSpace(rest), true
+}
+
+// parseIgnoreOptions parses the reason="..." and until=YYYY-MM-DD options that follow an ignore directive.
+func parseIgnoreOptions(options string) (*tarpIgnore, error) {
+	ignore := &tarpIgnore{}
+	for options = strings.TrimSpace(options); options != ""; options = strings.TrimSpace(options) {
+		i := strings.Index(options, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("expected key=value, got %q", options)
+		}
+		key, rest := options[:i], options[i+1:]
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			quoted, err := strconv.QuotedPrefix(rest)
+			if err != nil {
+				return nil, fmt.Errorf("unterminated %s", key)
+			}
+			value, _ = strconv.Unquote(quoted)
+			options = rest[len(quoted):]
+		} else {
+			end := strings.IndexAny(rest, " \t")
+			if end < 0 {
+				end = len(rest)
+			}
+			value, options = rest[:end], rest[end:]
+		}
+
+		switch key {
+		case "reason":
+			ignore.Reason = value
+		case "until":
+			if _, err := time.Parse(untilLayout, value); err != nil {
+				return nil, fmt.Errorf("until should be a date like 2027-01-01, got %q", value)
+			}
+			ignore.Until = value
+		default:
+			return nil, fmt.Errorf("unknown option %q", key)
+		}
+	}
+	return ignore, nil
+}
+
+// findIgnore returns the first of the given directive in the given comments, or nil if there isn't one.
+func findIgnore(comments []*ast.CommentGroup, directive string, fileset *token.FileSet) *tarpIgnore {
+	for _, group := range comments {
+		if group == nil {
+			continue
+		}
+		for _, c := range group.List {
+			options, ok := directiveOptions(c.Text, directive)
+			if !ok {
+				continue
+			}
+			ignore, err := parseIgnoreOptions(options)
+			if err != nil {
+				fatalf(exitLoadError, "invalid %s directive at %s: %v", directive, fileset.Position(c.Pos()), err)
+			}
+			return ignore
+		}
+	}
+	return nil
+}
+
+// applyIgnores leaves the functions ignored by directives out of the report's declared and called functions,
+// unless their directive has expired, and marks the directives that have expired or gone stale. A directive
+// is stale once the function it ignores is called directly by a test.
+func applyIgnores(report tarpReport) tarpReport {
+	today := time.Now().Format(untilLayout)
+	for key, f := range report.DeclaredDetails {
+		if f.Ignore == nil {
+			continue
+		}
+		// functions ignored by the same //tarp:ignore-file directive share it
+		ignore := *f.Ignore
+		ignore.Expired = ignore.Until != "" && ignore.Until < today
+		ignore.Stale = f.Distance == 1
+		f.Ignore = &ignore
+		report.DeclaredDetails[key] = f
+
+		if !ignore.Expired {
+			report.Declared.Remove(key)
+			report.Called.Remove(key)
+		}
+	}
+	return report
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/fatih/set"
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////
+//                                                    //
+//               Test Helper Functions                //
+//                                                    //
+////////////////////////////////////////////////////////
+
+const ignoreChunkOfCode = `
+	//tarp:ignore-file reason="exercised by the e2e suite"
+
+	package main
+
+	func Start() {}
+
+	// MustParse is like Parse, but panics.
+	//
+	//tarp:ignore reason="panicking wrapper" until=2027-01-01
+	func MustParse(s string) int { return 0 }
+`
+
+// parseCommentedChunkOfCode is like parseChunkOfCode, but keeps the comments directives are written in.
+func parseCommentedChunkOfCode(t *testing.T, chunkOfCode string) *ast.File {
+	p, err := parser.ParseFile(token.NewFileSet(), "example.go", chunkOfCode, parser.AllErrors|parser.ParseComments)
+	if err != nil {
+		t.Logf("failing because ParseFile returned error: %v", err)
+		t.FailNow()
+	}
+	return p
+}
+
+////////////////////////////////////////////////////////
+//                                                    //
+//                   Actual Tests                     //
+//                                                    //
+////////////////////////////////////////////////////////
+
+func TestDirectiveOptions(t *testing.T) {
+	options, ok := directiveOptions(`//tarp:ignore reason="trivial"`, ignoreDirective)
+	assert.True(t, ok)
+	assert.Equal(t, `reason="trivial"`, options)
+
+	options, ok = directiveOptions("//tarp:ignore", ignoreDirective)
+	assert.True(t, ok)
+	assert.Equal(t, "", options)
+
+	_, ok = directiveOptions("//tarp:ignore-file", ignoreDirective)
+	assert.False(t, ok, "//tarp:ignore-file isn't a //tarp:ignore directive")
+
+	_, ok = directiveOptions("// tarp:ignore", ignoreDirective)
+	assert.False(t, ok, "directives don't have a space after the slashes")
+}
+
+func TestParseIgnoreOptions(t *testing.T) {
+	actual, err := parseIgnoreOptions(`reason="covered by the \"e2e\" suite" until=2027-01-01`)
+	assert.Nil(t, err)
+	assert.Equal(t, &tarpIgnore{Reason: `covered by the "e2e" suite`, Until: "2027-01-01"}, actual)
+
+	actual, err = parseIgnoreOptions("")
+	assert.Nil(t, err)
+	assert.Equal(t, &tarpIgnore{}, actual)
+
+	actual, err = parseIgnoreOptions("until=2027-01-01 reason=trivial")
+	assert.Nil(t, err)
+	assert.Equal(t, &tarpIgnore{Reason: "trivial", Until: "2027-01-01"}, actual)
+
+	_, err = parseIgnoreOptions("until=next-year")
+	assert.NotNil(t, err)
+	_, err = parseIgnoreOptions(`reason="trivial`)
+	assert.NotNil(t, err)
+	_, err = parseIgnoreOptions("because=trivial")
+	assert.NotNil(t, err)
+	_, err = parseIgnoreOptions("trivial")
+	assert.NotNil(t, err)
+}
+
+func TestFindIgnore(t *testing.T) {
+	p := parseCommentedChunkOfCode(t, ignoreChunkOfCode)
+
+	assert.Equal(t, &tarpIgnore{Reason: "exercised by the e2e suite"}, findIgnore(p.Comments, ignoreFileDirective, token.NewFileSet()))
+	assert.Equal(t, &tarpIgnore{Reason: "panicking wrapper", Until: "2027-01-01"}, findIgnore([]*ast.CommentGroup{findFuncDecl([]*ast.File{p}, "MustParse").Doc}, ignoreDirective, token.NewFileSet()))
+	assert.Nil(t, findIgnore([]*ast.CommentGroup{findFuncDecl([]*ast.File{p}, "Start").Doc}, ignoreDirective, token.NewFileSet()))
+
+	p = parseCommentedChunkOfCode(t, `
+		package main
+
+		//tarp:ignore until=someday
+		func Start() {}
+	`)
+	assert.Panics(t, func() { findIgnore(p.Comments, ignoreDirective, token.NewFileSet()) }, "invalid directives should be fatal")
+}
+
+func TestApplyIgnores(t *testing.T) {
+	report := tarpReport{
+		DeclaredDetails: map[string]tarpFunc{
+			"main.go:MustParse": {Name: "MustParse", Distance: untestedDistance, Ignore: &tarpIgnore{Reason: "panicking wrapper"}},
+			"main.go:Serve":     {Name: "Serve", Distance: untestedDistance, Ignore: &tarpIgnore{Until: "2000-01-01"}},
+			"main.go:Name":      {Name: "Name", Distance: 1, Ignore: &tarpIgnore{Until: "2999-01-01"}},
+			"main.go:Parse":     {Name: "Parse", Distance: 1},
+		},
+		Declared: set.New("main.go:MustParse", "main.go:Serve", "main.go:Name", "main.go:Parse"),
+		Called:   set.New("main.go:Name", "main.go:Parse"),
+	}
+
+	actual := applyIgnores(report)
+
+	assert.Equal(t, set.New("main.go:Serve", "main.go:Parse"), actual.Declared, "expired directives shouldn't leave functions out")
+	assert.Equal(t, set.New("main.go:Parse"), actual.Called)
+	assert.True(t, actual.DeclaredDetails["main.go:Serve"].Ignore.Expired)
+	assert.False(t, actual.DeclaredDetails["main.go:Name"].Ignore.Expired)
+	assert.True(t, actual.DeclaredDetails["main.go:Name"].Ignore.Stale)
+	assert.False(t, actual.DeclaredDetails["main.go:MustParse"].Ignore.Stale)
+	assert.Nil(t, actual.DeclaredDetails["main.go:Parse"].Ignore)
+}
@@ -16,19 +16,75 @@ import (
 	"github.com/fatih/color"
 	"github.com/fatih/set"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"golang.org/x/tools/cover"
-	"path/filepath"
+	"path"
 	"strings"
 )
 
 const (
-	differenceReportTmpl = `{{$len := .LongestFunctionNameLength}}Functions without direct unit tests:{{range $filename, $missing := .Details}}
-in {{colorizer $filename "white" true}}:{{range $missing}}
+	indirectReportTmpl = `{{$len := .LongestFunctionNameLength}}{{if .IndirectDetails}}Functions tested within {{.MaxDepth}} calls of a test:{{range $filename, $indirect := .IndirectDetails}}
+in {{colorizer $filename "white" true}}:{{range $indirect}}
+	{{pad .Name $len}} on line {{.DeclPos.Line}} (distance {{distance .Distance}}){{end}}{{end}}
+
+{{end}}`
+	referenceReportTmpl = `{{if .ReferenceDetails}}Functions credited only by reference:{{range $filename, $referenced := .ReferenceDetails}}
+in {{colorizer $filename "white" true}}:{{range $referenced}}
 	{{pad .Name $len}} on line {{.DeclPos.Line}}{{end}}{{end}}
 
-Grade: {{grader .Score}} ({{.CalledCount}}/{{.DeclaredCount}} functions)
+{{end}}`
+	singleCaseReportTmpl = `{{if .SingleCaseDetails}}Functions only exercised by single-case tables:{{range $filename, $single := .SingleCaseDetails}}
+in {{colorizer $filename "white" true}}:{{range $single}}
+	{{pad .Name $len}} on line {{.DeclPos.Line}}{{end}}{{end}}
+
+{{end}}`
+	specReportTmpl = `{{if .SpecDetails}}Functions exercised by specs:{{range $filename, $specced := .SpecDetails}}
+in {{colorizer $filename "white" true}}:{{range $specced}}
+	{{pad .Name $len}} on line {{.DeclPos.Line}}{{range .Specs}}
+		{{.}}{{end}}{{end}}{{end}}
+
+{{end}}`
+	nonTestReportTmpl = `{{if .NonTestDetails}}Functions only exercised by examples, benchmarks or fuzz targets:{{range $filename, $nonTest := .NonTestDetails}}
+in {{colorizer $filename "white" true}}:{{range $nonTest}}
+	{{pad .Name $len}} on line {{.DeclPos.Line}} ({{join .TestFuncs ", "}}){{end}}{{end}}
+
+{{end}}`
+	helperReportTmpl = `{{if .HelperDetails}}Functions only called through test helpers:{{range $filename, $helped := .HelperDetails}}
+in {{colorizer $filename "white" true}}:{{range $helped}}
+	{{pad .Name $len}} on line {{.DeclPos.Line}} (through {{join .Helpers ", "}}){{end}}{{end}}
+
+{{end}}`
+	ignoredReportTmpl = `{{if .IgnoredDetails}}Ignored functions:{{range $filename, $ignored := .IgnoredDetails}}
+in {{colorizer $filename "white" true}}:{{range $ignored}}
+	{{pad .Name $len}} on line {{.DeclPos.Line}}{{with .Ignore}}{{if .Reason}} ({{.Reason}}){{end}}{{if .Expired}} {{colorizer (printf "expired on %s" .Until) "red" false}}{{end}}{{if .Stale}} {{colorizer "stale, now has a direct test" "yellow" false}}{{end}}{{end}}{{end}}{{end}}
+
+{{end}}`
+	generatedReportTmpl = `{{if .GeneratedDeclaredCount}}{{if .GeneratedDetails}}Generated functions without direct unit tests:{{range $filename, $generated := .GeneratedDetails}}
+in {{colorizer $filename "white" true}}:{{range $generated}}
+	{{pad .Name $len}} on line {{.DeclPos.Line}}{{if ne $.Engine "` + conventionEngine + `"}} (distance {{distance .Distance}}){{end}}{{end}}{{end}}
+
+{{end}}Generated code: {{grader .GeneratedScore}} ({{.GeneratedCalledCount}}/{{.GeneratedDeclaredCount}} functions)
+
+{{end}}`
+	orphanedReportTmpl = `{{if .OrphanedTests}}Tests not named after any function:{{range .OrphanedTests}}
+	{{.}}{{end}}
+
+{{end}}`
+	testKindTmpl = `{{if or .BlackBoxCount .ViaInterfaceCount .ByReferenceCount}}
+{{.WhiteBoxCount}} functions tested by white-box tests, {{.BlackBoxCount}} by black-box tests{{if .ViaInterfaceCount}}, {{.ViaInterfaceCount}} via interface{{end}}{{if .ByReferenceCount}}, {{.ByReferenceCount}} by reference{{end}}{{end}}`
+	differenceReportTmpl = indirectReportTmpl + referenceReportTmpl + singleCaseReportTmpl + specReportTmpl + nonTestReportTmpl + helperReportTmpl + ignoredReportTmpl + generatedReportTmpl + orphanedReportTmpl + `{{if eq .Engine "` + conventionEngine + `"}}Functions without a test named after them:{{else if gt .MaxDepth 1}}Functions not tested within {{.MaxDepth}} calls of a test:{{else}}Functions without direct unit tests:{{end}}{{range $filename, $missing := .Details}}
+in {{colorizer $filename "white" true}}:{{range $missing}}
+	{{pad .Name $len}} on line {{.DeclPos.Line}}{{if ne $.Engine "` + conventionEngine + `"}} (distance {{distance .Distance}}){{end}}{{end}}{{end}}
+
+Grade: {{grader .Score}} ({{.CalledCount}}/{{.DeclaredCount}} functions)` + testKindTmpl + `
 `
-	perfectScoreTmpl = `Grade: {{grader .Score}} ({{.CalledCount}}/{{.DeclaredCount}} functions)`
+	perfectScoreTmpl  = indirectReportTmpl + referenceReportTmpl + singleCaseReportTmpl + specReportTmpl + nonTestReportTmpl + helperReportTmpl + ignoredReportTmpl + generatedReportTmpl + orphanedReportTmpl + `Grade: {{grader .Score}} ({{.CalledCount}}/{{.DeclaredCount}} functions)` + testKindTmpl
+	packageHeaderTmpl = `{{colorizer (printf "Package %s" .Package) "white" true}}
+`
+	moduleTotalTmpl  = `Total: {{grader .Score}} ({{.CalledCount}}/{{.DeclaredCount}} functions across {{len .Packages}} packages)`
+	moduleHeaderTmpl = `{{colorizer (printf "Module %s" .Module) "white" true}}
+`
+	workspaceTotalTmpl = `Workspace total: {{grader .Score}} ({{.CalledCount}}/{{.DeclaredCount}} functions across {{len .Modules}} modules)`
 )
 
 var (
@@ -36,21 +92,49 @@ var (
 	debug bool
 
 	// analyze flags
-	failOnFound    bool
-	outputAsJSON   bool
-	analyzePackage string
+	failOnFound        bool
+	minScore           int
+	packageMinScores   []string
+	maxUntested        int
+	outputAsJSON       bool
+	analyzePackage     string
+	analysisEngine     string
+	callgraphAlgorithm string
+	maxDepth           int
+	analyzeWorkspace   bool
+	matrix             []string
+	creditInterfaces   bool
+	creditReferences   bool
+	countExamples      bool
+	countBenchmarks    bool
+	countFuzz          bool
+	convention         bool
+	conventionPatterns []string
+	countGenerated     bool
+	generatedPatterns  []string
+	baselineFile       string
+	updateBaseline     bool
+
+	// baseline flags
+	baselineOutput string
 
 	// cover flags
 	coverprofile string
 
+	// build flags, shared by every command that reads packages
+	buildTags  []string
+	targetOS   string
+	targetArch string
+
 	// helper variables
 	fileset *token.FileSet
 
 	// commands
 	rootCmd = &cobra.Command{
-		Use:   "tarp",
-		Short: "tarp is a coverage helper tool",
-		Long:  `tarp is a tool that helps you catch functions which don't have direct unit tests in your Go libraries`,
+		Use:              "tarp",
+		Short:            "tarp is a coverage helper tool",
+		Long:             `tarp is a tool that helps you catch functions which don't have direct unit tests in your Go libraries`,
+		PersistentPreRun: configure,
 	}
 
 	colors = map[string]color.Attribute{
@@ -75,6 +159,13 @@ var (
 			}
 			return color.New(arguments...).SprintfFunc()(s)
 		},
+		"join": strings.Join,
+		"distance": func(distance int) string {
+			if distance == untestedDistance {
+				return "∞"
+			}
+			return strconv.Itoa(distance)
+		},
 		"grader": func(score int) string {
 			gradeMap := map[int]string{
 				6:  "magenta",
@@ -93,33 +184,97 @@ var (
 	}
 
 	analyzeCmd = &cobra.Command{
-		Use:   "analyze",
+		Use:   "analyze [packages]",
 		Short: "Analyze a given package",
-		Long:  "Analyze takes a given package and determines which functions lack direct unit tests.",
+		Long:  "Analyze takes the given packages and determines which functions lack direct unit tests. Packages can be import paths, directories relative to the current one, or patterns like ./... that match every package beneath a directory.",
 		Run: func(cmd *cobra.Command, args []string) {
-			report := analyze(analyzePackage)
-			diff := set.StringSlice(set.Difference(report.Declared, report.Called))
-			diffReport := generateDiffReport(diff, report.DeclaredDetails, report.Declared.Size(), report.Called.Size())
+			// thresholds are checked after the analysis, but there's no point analyzing anything when they're invalid
+			minimums := parsePackageMinScores(packageMinScores)
+			outputs, breaches := []tarpOutput{}, []string{}
+			if len(matrix) > 0 {
+				configs, err := parseBuildConfigs(matrix)
+				if err != nil {
+					fatalf(exitUsageError, "%v", err)
+				}
+				patterns := args
+				if len(patterns) == 0 {
+					patterns = []string{analyzePackage}
+				}
 
-			if outputAsJSON {
-				json.NewEncoder(os.Stdout).Encode(diffReport)
-			} else {
-				var templateToUse string
-				if len(diff) > 0 {
-					templateToUse = differenceReportTmpl
+				matrixReport := analyzeMatrix(patterns, configs)
+				if outputAsJSON {
+					json.NewEncoder(os.Stdout).Encode(matrixReport)
+				} else {
+					fmt.Println(renderMatrixOutput(matrixReport))
+				}
+				for _, configName := range matrixReport.Order {
+					outputs = append(outputs, matrixReport.Configurations[configName].Packages...)
+				}
+				breaches = checkMatrixThresholds(matrixReport, minimums)
+			} else if analyzeWorkspace {
+				wd, err := os.Getwd()
+				if err != nil {
+					fatalf(exitLoadError, "error encountered getting current working directory: %v", err)
+				}
+				ws, ok := findWorkspace(wd)
+				if !ok {
+					fatalf(exitUsageError, "no go.work file found in %s or any of its parents", wd)
+				}
+
+				workspaceReport := analyzeModules(ws)
+				if outputAsJSON {
+					json.NewEncoder(os.Stdout).Encode(workspaceReport)
 				} else {
-					templateToUse = perfectScoreTmpl
+					fmt.Println(renderWorkspaceOutput(workspaceReport))
+				}
+				for _, moduleReport := range workspaceReport.Modules {
+					outputs = append(outputs, moduleReport.Packages...)
+				}
+				breaches = checkThresholds(outputs, minimums)
+			} else {
+				patterns := args
+				if len(patterns) == 0 {
+					patterns = []string{analyzePackage}
+				}
+				pkgDirs := expandPatterns(patterns)
+				if len(pkgDirs) == 0 {
+					fatalf(exitUsageError, "no packages matched %s", strings.Join(patterns, " "))
 				}
 
-				var tpl bytes.Buffer
-				// see above re: the error this function returns
-				t, _ := template.New("t").Funcs(templateFuncMap).Parse(templateToUse)
-				t.Execute(&tpl, diffReport)
-				fmt.Println(tpl.String())
+				outputs = analyzePackages(pkgDirs)
+				if len(outputs) == 1 {
+					if outputAsJSON {
+						json.NewEncoder(os.Stdout).Encode(outputs[0])
+					} else {
+						fmt.Println(renderOutput(outputs[0]))
+					}
+				} else {
+					moduleReport := aggregateReports(outputs)
+					if outputAsJSON {
+						json.NewEncoder(os.Stdout).Encode(moduleReport)
+					} else {
+						fmt.Println(renderModuleOutput(moduleReport))
+					}
+				}
+				breaches = checkThresholds(outputs, minimums)
 			}
 
-			if len(diff) > 0 && failOnFound {
-				os.Exit(1)
+			if baselineFile != "" {
+				breaches = append(breaches, checkBaseline(baselineFile, outputs)...)
+			}
+			if len(breaches) > 0 {
+				for _, breach := range breaches {
+					log.Print(breach)
+				}
+				os.Exit(exitUntested)
+			}
+			// with a baseline, only the functions it doesn't record fail
+			if failOnFound && baselineFile == "" {
+				for _, output := range outputs {
+					if len(output.Details) > 0 {
+						os.Exit(exitUntested)
+					}
+				}
 			}
 		},
 	}
@@ -131,61 +286,443 @@ var (
 		Run: func(cmd *cobra.Command, args []string) {
 			profiles, err := cover.ParseProfiles(coverprofile)
 			if err != nil {
-				log.Fatal(err)
+				fatalf(exitLoadError, "%v", err)
+			}
+			// profiles from go test runs over several packages (or modules) need each package analyzed
+			reports := map[string]tarpReport{}
+			for _, profile := range profiles {
+				pkgPath := path.Dir(profile.FileName)
+				if _, ok := reports[pkgPath]; !ok {
+					reports[pkgPath] = analyze(pkgPath)
+				}
 			}
-			pkgPath := filepath.Dir(profiles[0].FileName)
 
-			report := analyze(pkgPath)
-			err = htmlOutput(coverprofile, "", report)
+			err = htmlOutput(coverprofile, "", reports)
 			if err != nil {
-				log.Fatal(err)
+				fatalf(exitLoadError, "%v", err)
+			}
+		},
+	}
+)
+
+var (
+	baselineCmd = &cobra.Command{
+		Use:   "baseline",
+		Short: "Record the functions without direct unit tests",
+		Long:  "A baseline records the functions without direct unit tests at the time it was written, so analyze --baseline only fails on the ones added or changed since.",
+	}
+
+	baselineWriteCmd = &cobra.Command{
+		Use:   "write [packages]",
+		Short: "Write a baseline of the given packages",
+		Long:  "Write records every function without direct unit tests in the given packages in a baseline file, meant to be committed. Each function is fingerprinted by its qualified name and a hash of its body, ignoring comments and formatting. Entries for packages that aren't given are kept.",
+		Run: func(cmd *cobra.Command, args []string) {
+			patterns := args
+			if len(patterns) == 0 {
+				patterns = []string{"."}
 			}
+			pkgDirs := expandPatterns(patterns)
+			if len(pkgDirs) == 0 {
+				fatalf(exitUsageError, "no packages matched %s", strings.Join(patterns, " "))
+			}
+
+			existing := tarpBaseline{}
+			if _, err := os.Stat(baselineOutput); err == nil {
+				existing = readBaseline(baselineOutput)
+			}
+			baseline := buildBaseline(existing, analyzePackages(pkgDirs))
+			writeBaseline(baselineOutput, baseline)
+			fmt.Printf("wrote %d functions without direct unit tests to %s\n", len(baseline.Untested), baselineOutput)
 		},
 	}
+
+	configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Inspect tarp's configuration",
+		Long:  "Every flag can also be set in a .tarp.yaml or .tarp.toml file in the module root, or in any directory between it and the current one, with files closer to the current directory overriding those further up, or with a TARP_* environment variable, like TARP_MAX_DEPTH for --max-depth. Environment variables override files, and flags override both. The settings of the flags that decide how packages are analyzed, like --max-depth, are resolved from the directory of each package analyzed instead.",
+	}
+
+	configShowCmd = &cobra.Command{
+		Use:   "show",
+		Short: "Print the fully resolved configuration",
+		Long:  "Show prints the value every flag of every command resolves to in the current directory, as YAML that can be used as a .tarp.yaml file.",
+		Run:   showConfig,
+	}
 )
 
 func init() {
 	rootCmd.Flags().BoolVarP(&debug, "debug", "d", false, "Print select debug information")
+	rootCmd.PersistentFlags().StringSliceVar(&buildTags, "tags", nil, "Comma-separated list of build tags to consider satisfied when selecting files, like go build -tags")
+	rootCmd.PersistentFlags().StringVar(&targetOS, "goos", "", "Operating system to select files for. Defaults to $GOOS or the host's.")
+	rootCmd.PersistentFlags().StringVar(&targetArch, "goarch", "", "Architecture to select files for. Defaults to $GOARCH or the host's.")
 	fileset = token.NewFileSet()
 
 	rootCmd.AddCommand(analyzeCmd)
 	analyzeCmd.Flags().BoolVarP(&outputAsJSON, "json", "j", false, "Render results as a JSON blob")
 	analyzeCmd.Flags().BoolVarP(&failOnFound, "fail-on-found", "F", false, "Call os.Exit(1) when functions without direct tests are found")
+	analyzeCmd.Flags().IntVar(&minScore, "min-score", 0, "Call os.Exit(1) when the score across every package analyzed is below this")
+	analyzeCmd.Flags().StringSliceVar(&packageMinScores, "package-min-score", nil, "Comma-separated list of minimum scores for the packages matching a pattern, written pattern=score, like ./legacy/...=60. Calls os.Exit(1) when a package scores below its minimum. The longest matching pattern wins.")
+	analyzeCmd.Flags().IntVar(&maxUntested, "max-untested", -1, "Call os.Exit(1) when more than this many functions without direct tests are found across every package analyzed. -1 allows any number.")
 	analyzeCmd.Flags().StringVarP(&analyzePackage, "package", "p", ".", "Package to run analyze on. Defaults to the current directory.")
+	analyzeCmd.Flags().BoolVarP(&analyzeWorkspace, "workspace", "w", false, "Analyze every module in the go.work file governing the current directory")
+	analyzeCmd.Flags().StringVar(&baselineFile, "baseline", "", "Baseline file written by tarp baseline write. Only the functions without direct tests it doesn't record call os.Exit(1), and --fail-on-found is ignored.")
+	analyzeCmd.Flags().BoolVar(&updateBaseline, "update-baseline", false, "Remove the functions that have been tested, changed or removed since it was written from the --baseline file")
+	analyzeCmd.Flags().StringSliceVar(&matrix, "matrix", nil, "Comma-separated list of configurations to analyze and compare, each written goos[/goarch][:tag+tag], like linux,darwin/arm64,linux:integration")
+	addAnalysisFlags(analyzeCmd.Flags())
+
+	rootCmd.AddCommand(baselineCmd)
+	baselineCmd.AddCommand(baselineWriteCmd)
+	baselineWriteCmd.Flags().StringVar(&baselineOutput, "baseline", defaultBaselineFile, "Baseline file to write")
+	addAnalysisFlags(baselineWriteCmd.Flags())
 
 	rootCmd.AddCommand(coverCmd)
 	coverCmd.Flags().StringVarP(&coverprofile, "html", "c", "", "coverprofile to generate HTML for.")
+
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configShowCmd)
+}
+
+// addAnalysisFlags adds the flags that decide how packages are analyzed to the given flag set, so every
+// command that analyzes packages shares them. Their settings are resolved from the directory of each package.
+func addAnalysisFlags(flags *pflag.FlagSet) {
+	analysis := pflag.NewFlagSet("analysis", pflag.ContinueOnError)
+	analysis.StringVarP(&analysisEngine, "engine", "e", typesEngine, "Analysis engine to use: \"types\" resolves calls with the type checker, \"ast\" guesses from the syntax tree alone, and \"callgraph\" credits whatever a Test function has a call graph edge to. The types and callgraph engines fall back to ast when the package can't be type-checked.")
+	analysis.IntVar(&maxDepth, "max-depth", 1, "Count a function as tested when it's reachable from a test within this many calls. 1 only counts functions called directly by a test.")
+	analysis.BoolVar(&creditInterfaces, "credit-interfaces", false, "With the types engine, credit the implementations a test can reach by calling methods through an interface, marking them \"via interface\". Like the callgraph engine's constructed algorithm, which this uses, every implementation whose type is constructed in code reachable from a test counts, rather than only the ones pointer analysis would show the interface holding. The callgraph engine's declared and constructed algorithms always do this.")
+	analysis.BoolVar(&creditReferences, "credit-references", false, "Count functions and methods a test refers to without calling, like those passed as arguments or stored in tables, as directly tested, marking them \"by reference\"")
+	analysis.BoolVar(&countExamples, "count-examples", true, "Count functions called by Example functions as directly tested")
+	analysis.BoolVar(&countBenchmarks, "count-benchmarks", true, "Count functions called by Benchmark functions as directly tested")
+	analysis.BoolVar(&countFuzz, "count-fuzz", true, "Count functions called by Fuzz functions as directly tested")
+	analysis.BoolVar(&convention, "convention", false, "Count a function as tested only when a test is named after it, following one of the --convention-pattern patterns, and report the tests that aren't named after any function")
+	analysis.Var(newListValue(defaultConventionPatterns, &conventionPatterns), "convention-pattern", "Comma-separated list of the names tests have to match in --convention mode. {func} stands for a function's name, {type} and {method} for a method's type and name, and * for anything.")
+	analysis.BoolVar(&countGenerated, "count-generated", false, "Count functions declared in generated files towards the grade, rather than only giving them a grade of their own")
+	analysis.Var(newListValue(nil, &generatedPatterns), "generated-pattern", "Comma-separated list of file name patterns, like *_mock.go, to treat as generated on top of those with a \"Code generated ... DO NOT EDIT.\" comment")
+	analysis.StringVarP(&callgraphAlgorithm, "algorithm", "a", constructedAlgorithm, "Call graph construction algorithm used by the callgraph engine: \"static\" only follows calls known at compile time, \"declared\" also follows interface and function value calls to every declared type's methods and every referenced function that could satisfy them, and \"constructed\" only to those of the types constructed and functions referenced in code reachable from a test. These approximate CHA and RTA from the type checker's output, rather than building SSA like golang.org/x/tools/go/callgraph.")
+
+	analysis.VisitAll(func(f *pflag.Flag) {
+		packageSettings.Add(f.Name)
+	})
+	flags.AddFlagSet(analysis)
 }
 
 func generateDiffReport(diff []string, declaredFuncInfo map[string]tarpFunc, declaredFuncCount int, calledFuncCount int) tarpOutput {
 	longestFunctionNameLength := 0
 	missingFuncs := &tarpDetails{}
 	for _, s := range diff {
-		if utf8.RuneCountInString(s) > longestFunctionNameLength {
-			longestFunctionNameLength = len(s)
+		tf := declaredFuncInfo[s]
+		if utf8.RuneCountInString(tf.Name) > longestFunctionNameLength {
+			longestFunctionNameLength = len(tf.Name)
 		}
-		*missingFuncs = append(*missingFuncs, declaredFuncInfo[s])
+		*missingFuncs = append(*missingFuncs, tf)
 	}
 	sort.Sort(missingFuncs)
 	byFilename := map[string][]tarpFunc{}
 	for _, tf := range *missingFuncs {
 		byFilename[tf.Filename] = append(byFilename[tf.Filename], tf)
 	}
-	score := float64(calledFuncCount) / float64(declaredFuncCount)
 
+	// functions that are only tested by way of other functions get listed separately
+	missing := set.New()
+	for _, s := range diff {
+		missing.Add(s)
+	}
+	distances, credits := map[string]int{}, map[string][]string{}
+	whiteBoxCount, blackBoxCount, viaInterfaceCount, byReferenceCount := 0, 0, 0, 0
+	indirectFuncs, referenceFuncs, singleCaseFuncs, specFuncs := &tarpDetails{}, &tarpDetails{}, &tarpDetails{}, &tarpDetails{}
+	cases, caseCounts, singleCase, specs := map[string][]string{}, map[string]int{}, []string{}, map[string][]string{}
+	testFuncs, nonTestFuncs := map[string][]string{}, &tarpDetails{}
+	helpers, helperFuncs := map[string][]string{}, &tarpDetails{}
+	ignored, ignoredFuncs := map[string]tarpIgnore{}, &tarpDetails{}
+	generatedDeclaredCount, generatedCalledCount, generated, generatedFuncs := 0, 0, []string{}, &tarpDetails{}
+	for name, tf := range declaredFuncInfo {
+		// functions left out of the grade aren't compared across --matrix configurations either
+		if !excludedFromGrade(tf) {
+			distances[name] = tf.Distance
+		}
+		if len(tf.Credits) > 0 {
+			credits[name] = tf.Credits
+		}
+		for _, credit := range tf.Credits {
+			switch credit {
+			case whiteBoxCredit:
+				whiteBoxCount++
+			case blackBoxCredit:
+				blackBoxCount++
+			case viaInterfaceCredit:
+				viaInterfaceCount++
+			case byReferenceCredit:
+				byReferenceCount++
+			}
+		}
+		if len(tf.Cases) > 0 {
+			cases[name] = tf.Cases
+		}
+		if tf.CaseCount > 0 {
+			caseCounts[name] = tf.CaseCount
+		}
+		if tf.CaseCount == 1 {
+			if utf8.RuneCountInString(tf.Name) > longestFunctionNameLength {
+				longestFunctionNameLength = len(tf.Name)
+			}
+			*singleCaseFuncs = append(*singleCaseFuncs, tf)
+			singleCase = append(singleCase, name)
+		}
+		if len(tf.Specs) > 0 {
+			if utf8.RuneCountInString(tf.Name) > longestFunctionNameLength {
+				longestFunctionNameLength = len(tf.Name)
+			}
+			*specFuncs = append(*specFuncs, tf)
+			specs[name] = tf.Specs
+		}
+		if len(tf.TestFuncs) > 0 {
+			testFuncs[name] = tf.TestFuncs
+			calledByTest := false
+			for _, kind := range tf.TestFuncs {
+				calledByTest = calledByTest || kind == testFunc
+			}
+			if !calledByTest {
+				if utf8.RuneCountInString(tf.Name) > longestFunctionNameLength {
+					longestFunctionNameLength = len(tf.Name)
+				}
+				*nonTestFuncs = append(*nonTestFuncs, tf)
+			}
+		}
+		if len(tf.Helpers) > 0 {
+			if utf8.RuneCountInString(tf.Name) > longestFunctionNameLength {
+				longestFunctionNameLength = len(tf.Name)
+			}
+			*helperFuncs = append(*helperFuncs, tf)
+			helpers[name] = tf.Helpers
+		}
+		if tf.Ignore != nil {
+			if utf8.RuneCountInString(tf.Name) > longestFunctionNameLength {
+				longestFunctionNameLength = len(tf.Name)
+			}
+			*ignoredFuncs = append(*ignoredFuncs, tf)
+			ignored[name] = *tf.Ignore
+		}
+		// generated code gets a score of its own, which functions ignored by a directive don't count towards
+		if tf.Generated && (tf.Ignore == nil || tf.Ignore.Expired) {
+			generatedDeclaredCount++
+			if tf.Distance != untestedDistance && tf.Distance <= maxDepth {
+				generatedCalledCount++
+			} else {
+				if utf8.RuneCountInString(tf.Name) > longestFunctionNameLength {
+					longestFunctionNameLength = len(tf.Name)
+				}
+				*generatedFuncs = append(*generatedFuncs, tf)
+				generated = append(generated, name)
+			}
+		}
+		if len(tf.Credits) == 1 && tf.Credits[0] == byReferenceCredit {
+			if utf8.RuneCountInString(tf.Name) > longestFunctionNameLength {
+				longestFunctionNameLength = len(tf.Name)
+			}
+			*referenceFuncs = append(*referenceFuncs, tf)
+		}
+		if tf.Distance > 1 && !missing.Has(name) {
+			if utf8.RuneCountInString(tf.Name) > longestFunctionNameLength {
+				longestFunctionNameLength = len(tf.Name)
+			}
+			*indirectFuncs = append(*indirectFuncs, tf)
+		}
+	}
+	sort.Sort(indirectFuncs)
+	indirectByFilename := map[string][]tarpFunc{}
+	for _, tf := range *indirectFuncs {
+		indirectByFilename[tf.Filename] = append(indirectByFilename[tf.Filename], tf)
+	}
+	sort.Strings(singleCase)
+	sort.Sort(singleCaseFuncs)
+	singleCaseByFilename := map[string][]tarpFunc{}
+	for _, tf := range *singleCaseFuncs {
+		singleCaseByFilename[tf.Filename] = append(singleCaseByFilename[tf.Filename], tf)
+	}
+	sort.Sort(specFuncs)
+	specsByFilename := map[string][]tarpFunc{}
+	for _, tf := range *specFuncs {
+		specsByFilename[tf.Filename] = append(specsByFilename[tf.Filename], tf)
+	}
+	sort.Sort(nonTestFuncs)
+	nonTestByFilename := map[string][]tarpFunc{}
+	for _, tf := range *nonTestFuncs {
+		nonTestByFilename[tf.Filename] = append(nonTestByFilename[tf.Filename], tf)
+	}
+	sort.Sort(helperFuncs)
+	helpersByFilename := map[string][]tarpFunc{}
+	for _, tf := range *helperFuncs {
+		helpersByFilename[tf.Filename] = append(helpersByFilename[tf.Filename], tf)
+	}
+	sort.Sort(ignoredFuncs)
+	ignoredByFilename := map[string][]tarpFunc{}
+	for _, tf := range *ignoredFuncs {
+		ignoredByFilename[tf.Filename] = append(ignoredByFilename[tf.Filename], tf)
+	}
+	sort.Strings(generated)
+	sort.Sort(generatedFuncs)
+	generatedByFilename := map[string][]tarpFunc{}
+	for _, tf := range *generatedFuncs {
+		generatedByFilename[tf.Filename] = append(generatedByFilename[tf.Filename], tf)
+	}
+	sort.Sort(referenceFuncs)
+	referenceByFilename := map[string][]tarpFunc{}
+	for _, tf := range *referenceFuncs {
+		referenceByFilename[tf.Filename] = append(referenceByFilename[tf.Filename], tf)
+	}
 	report := tarpOutput{
 		DeclaredCount:             declaredFuncCount,
 		CalledCount:               calledFuncCount,
-		Score:                     int(score * 100),
+		Score:                     percentage(calledFuncCount, declaredFuncCount),
+		MaxDepth:                  maxDepth,
+		WhiteBoxCount:             whiteBoxCount,
+		BlackBoxCount:             blackBoxCount,
+		ViaInterfaceCount:         viaInterfaceCount,
+		ByReferenceCount:          byReferenceCount,
+		Distances:                 distances,
+		Credits:                   credits,
+		Cases:                     cases,
+		CaseCounts:                caseCounts,
+		SingleCase:                singleCase,
+		Specs:                     specs,
+		TestFuncs:                 testFuncs,
+		Helpers:                   helpers,
+		Ignored:                   ignored,
+		GeneratedDeclaredCount:    generatedDeclaredCount,
+		GeneratedCalledCount:      generatedCalledCount,
+		GeneratedScore:            percentage(generatedCalledCount, generatedDeclaredCount),
+		Generated:                 generated,
 		Details:                   byFilename,
+		IndirectDetails:           indirectByFilename,
+		ReferenceDetails:          referenceByFilename,
+		SingleCaseDetails:         singleCaseByFilename,
+		SpecDetails:               specsByFilename,
+		NonTestDetails:            nonTestByFilename,
+		HelperDetails:             helpersByFilename,
+		IgnoredDetails:            ignoredByFilename,
+		GeneratedDetails:          generatedByFilename,
 		LongestFunctionNameLength: longestFunctionNameLength,
 	}
 
 	return report
 }
 
+// percentage returns the score for a number of called functions out of a number of declared ones.
+// Having nothing to test is a perfect score.
+func percentage(calledFuncCount, declaredFuncCount int) int {
+	if declaredFuncCount == 0 {
+		return 100
+	}
+	return int(float64(calledFuncCount) / float64(declaredFuncCount) * 100)
+}
+
+// analyzePackages analyzes each of the package directories given, in order, with the settings that apply in
+// each one's directory.
+func analyzePackages(pkgDirs []string) []tarpOutput {
+	outputs := []tarpOutput{}
+	for _, pkgDir := range pkgDirs {
+		restore := configurePackage(pkgDir)
+		report := applyGenerated(applyIgnores(analyzeDir(pkgDir)))
+		diff := set.StringSlice(set.Difference(report.Declared, report.Called))
+		diffReport := generateDiffReport(diff, report.DeclaredDetails, report.Declared.Size(), report.Called.Size())
+
+		diffReport.Engine = report.Engine
+		diffReport.OrphanedTests = report.OrphanedTests
+		diffReport.Package = packageName(pkgDir)
+		outputs = append(outputs, diffReport)
+		restore()
+	}
+	return outputs
+}
+
+// renderOutput renders the text report for a single package.
+func renderOutput(output tarpOutput) string {
+	templateToUse := perfectScoreTmpl
+	if len(output.Details) > 0 {
+		templateToUse = differenceReportTmpl
+	}
+
+	var tpl bytes.Buffer
+	// the templates are constants, so neither parsing nor executing them can fail
+	t, _ := template.New("t").Funcs(templateFuncMap).Parse(templateToUse)
+	t.Execute(&tpl, output)
+	return tpl.String()
+}
+
+// aggregateReports combines the reports for several packages into a single report with module-wide totals.
+func aggregateReports(outputs []tarpOutput) tarpModuleOutput {
+	moduleReport := tarpModuleOutput{
+		MaxDepth: maxDepth,
+		Packages: outputs,
+	}
+	for _, output := range outputs {
+		if moduleReport.Engine == "" {
+			moduleReport.Engine = output.Engine
+		}
+		moduleReport.DeclaredCount += output.DeclaredCount
+		moduleReport.CalledCount += output.CalledCount
+	}
+
+	moduleReport.Score = percentage(moduleReport.CalledCount, moduleReport.DeclaredCount)
+	return moduleReport
+}
+
+// renderModuleOutput renders the text report for several packages, one section per package followed by
+// the module-wide total.
+func renderModuleOutput(moduleReport tarpModuleOutput) string {
+	var tpl bytes.Buffer
+	header, _ := template.New("header").Funcs(templateFuncMap).Parse(packageHeaderTmpl)
+	for _, output := range moduleReport.Packages {
+		header.Execute(&tpl, output)
+		tpl.WriteString(strings.TrimRight(renderOutput(output), "\n"))
+		tpl.WriteString("\n\n")
+	}
+
+	total, _ := template.New("total").Funcs(templateFuncMap).Parse(moduleTotalTmpl)
+	total.Execute(&tpl, moduleReport)
+	return tpl.String()
+}
+
+// analyzeModules analyzes every package in every module of a workspace, with totals for each module
+// and for the workspace as a whole.
+func analyzeModules(ws goWorkspace) tarpWorkspaceOutput {
+	workspaceReport := tarpWorkspaceOutput{MaxDepth: maxDepth}
+	for _, mod := range ws.Modules {
+		pkgDirs := expandPatterns([]string{mod.Dir + "/..."})
+		if len(pkgDirs) == 0 {
+			continue
+		}
+
+		moduleReport := aggregateReports(analyzePackages(pkgDirs))
+		moduleReport.Module = mod.Path
+		if workspaceReport.Engine == "" {
+			workspaceReport.Engine = moduleReport.Engine
+		}
+		workspaceReport.DeclaredCount += moduleReport.DeclaredCount
+		workspaceReport.CalledCount += moduleReport.CalledCount
+		workspaceReport.Modules = append(workspaceReport.Modules, moduleReport)
+	}
+	workspaceReport.Score = percentage(workspaceReport.CalledCount, workspaceReport.DeclaredCount)
+	return workspaceReport
+}
+
+// renderWorkspaceOutput renders the text report for a workspace, one section per module followed by the
+// workspace-wide total.
+func renderWorkspaceOutput(workspaceReport tarpWorkspaceOutput) string {
+	var tpl bytes.Buffer
+	header, _ := template.New("header").Funcs(templateFuncMap).Parse(moduleHeaderTmpl)
+	for _, moduleReport := range workspaceReport.Modules {
+		header.Execute(&tpl, moduleReport)
+		tpl.WriteString(renderModuleOutput(moduleReport))
+		tpl.WriteString("\n\n")
+	}
+
+	total, _ := template.New("total").Funcs(templateFuncMap).Parse(workspaceTotalTmpl)
+	total.Execute(&tpl, workspaceReport)
+	return tpl.String()
+}
+
 func main() {
 	if err := rootCmd.Execute(); err != nil {
-		log.Fatal(err)
+		fatalf(exitUsageError, "%v", err)
 	}
 }
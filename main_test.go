@@ -1,17 +1,20 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"go/token"
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/bouk/monkey"
 	"github.com/fatih/set"
+	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -30,6 +33,14 @@ func buildExamplePackagePath(t *testing.T, packageName string, abs bool) string
 	return strings.Join([]string{"github.com", "verygoodsoftwarenotvirus", "tarp", "example_packages", packageName}, "/")
 }
 
+// patchExit makes os.Exit panic, like our patched log.Fatal and log.Fatalf do, so the tests can recover from
+// tarp exiting. Tests that patch os.Exit themselves call it again when they're done.
+func patchExit() {
+	monkey.Patch(os.Exit, func(code int) {
+		panic(fmt.Sprintf("os.Exit(%d)", code))
+	})
+}
+
 ////////////////////////////////////////////////////////
 //                                                    //
 //                   Actual Tests                     //
@@ -48,6 +59,15 @@ func init() {
 	})
 }
 
+// TestMain keeps os.Exit patched while the tests run, but not once they're done, since that's how go test
+// reports whether they passed.
+func TestMain(m *testing.M) {
+	patchExit()
+	code := m.Run()
+	monkey.Unpatch(os.Exit)
+	os.Exit(code)
+}
+
 func TestGenerateDiffReport(t *testing.T) {
 	simpleMainPath := fmt.Sprintf("%s/main.go", buildExamplePackagePath(t, "simple", true))
 	exampleReport := tarpReport{
@@ -55,6 +75,7 @@ func TestGenerateDiffReport(t *testing.T) {
 			"A": {
 				Name:     "A",
 				Filename: simpleMainPath,
+				Distance: 1,
 				DeclPos: token.Position{
 					Filename: simpleMainPath,
 					Offset:   16,
@@ -77,6 +98,7 @@ func TestGenerateDiffReport(t *testing.T) {
 			"B": {
 				Name:     "B",
 				Filename: simpleMainPath,
+				Distance: untestedDistance,
 				DeclPos: token.Position{
 					Filename: simpleMainPath,
 					Offset:   49,
@@ -99,6 +121,7 @@ func TestGenerateDiffReport(t *testing.T) {
 			"C": {
 				Name:     "C",
 				Filename: simpleMainPath,
+				Distance: 1,
 				DeclPos: token.Position{
 					Filename: simpleMainPath,
 					Offset:   82,
@@ -121,6 +144,7 @@ func TestGenerateDiffReport(t *testing.T) {
 			"wrapper": {
 				Name:     "wrapper",
 				Filename: simpleMainPath,
+				Distance: 1,
 				DeclPos: token.Position{
 					Filename: simpleMainPath,
 					Offset:   115,
@@ -152,11 +176,24 @@ func TestGenerateDiffReport(t *testing.T) {
 		DeclaredCount:             4,
 		CalledCount:               3,
 		Score:                     75,
+		MaxDepth:                  1,
+		Distances:                 map[string]int{"A": 1, "B": untestedDistance, "C": 1, "wrapper": 1},
+		Credits:                   map[string][]string{},
+		Cases:                     map[string][]string{},
+		CaseCounts:                map[string]int{},
+		SingleCase:                []string{},
+		Specs:                     map[string][]string{},
+		TestFuncs:                 map[string][]string{},
+		Helpers:                   map[string][]string{},
+		Ignored:                   map[string]tarpIgnore{},
+		GeneratedScore:            100,
+		Generated:                 []string{},
 		Details: map[string][]tarpFunc{
 			simpleMainPath: {
 				tarpFunc{
 					Name:     "B",
 					Filename: simpleMainPath,
+					Distance: untestedDistance,
 					DeclPos: token.Position{
 						Filename: simpleMainPath,
 						Offset:   49,
@@ -178,10 +215,314 @@ func TestGenerateDiffReport(t *testing.T) {
 				},
 			},
 		},
+		IndirectDetails:   map[string][]tarpFunc{},
+		ReferenceDetails:  map[string][]tarpFunc{},
+		SingleCaseDetails: map[string][]tarpFunc{},
+		SpecDetails:       map[string][]tarpFunc{},
+		NonTestDetails:    map[string][]tarpFunc{},
+		HelperDetails:     map[string][]tarpFunc{},
+		IgnoredDetails:    map[string][]tarpFunc{},
+		GeneratedDetails:  map[string][]tarpFunc{},
 	}
 	actual := generateDiffReport(diff, exampleReport.DeclaredDetails, exampleReport.Declared.Size(), exampleReport.Called.Size())
 
 	assert.Equal(t, expected, actual, "expected and actual diff reports should match.")
+
+	// functions tested by way of other functions should be listed separately from the untested ones
+	indirect := exampleReport.DeclaredDetails["B"]
+	indirect.Distance = 2
+	exampleReport.DeclaredDetails["B"] = indirect
+	actual = generateDiffReport([]string{}, exampleReport.DeclaredDetails, 4, 4)
+
+	assert.Empty(t, actual.Details)
+	assert.Equal(t, map[string][]tarpFunc{simpleMainPath: {indirect}}, actual.IndirectDetails)
+	assert.Equal(t, 2, actual.Distances["B"])
+
+	// each kind of credit should be counted separately
+	credited := exampleReport.DeclaredDetails["A"]
+	credited.Credits = []string{blackBoxCredit, whiteBoxCredit}
+	exampleReport.DeclaredDetails["A"] = credited
+	credited = exampleReport.DeclaredDetails["C"]
+	credited.Credits = []string{viaInterfaceCredit, whiteBoxCredit}
+	exampleReport.DeclaredDetails["C"] = credited
+	actual = generateDiffReport([]string{}, exampleReport.DeclaredDetails, 4, 4)
+
+	assert.Equal(t, 2, actual.WhiteBoxCount)
+	assert.Equal(t, 1, actual.BlackBoxCount)
+	assert.Equal(t, 1, actual.ViaInterfaceCount)
+	assert.Empty(t, actual.ReferenceDetails)
+	assert.Equal(t, map[string][]string{"A": {blackBoxCredit, whiteBoxCredit}, "C": {viaInterfaceCredit, whiteBoxCredit}}, actual.Credits)
+
+	// functions only called by examples, benchmarks or fuzz targets should be listed separately
+	benchmarked := exampleReport.DeclaredDetails["A"]
+	benchmarked.TestFuncs = []string{benchmarkFunc}
+	exampleReport.DeclaredDetails["A"] = benchmarked
+	tested := exampleReport.DeclaredDetails["C"]
+	tested.TestFuncs = []string{exampleFunc, testFunc}
+	exampleReport.DeclaredDetails["C"] = tested
+	actual = generateDiffReport([]string{}, exampleReport.DeclaredDetails, 4, 4)
+
+	assert.Equal(t, map[string][]string{"A": {benchmarkFunc}, "C": {exampleFunc, testFunc}}, actual.TestFuncs)
+	assert.Equal(t, map[string][]tarpFunc{simpleMainPath: {benchmarked}}, actual.NonTestDetails)
+}
+
+func TestRenderOutput(t *testing.T) {
+	perfect := func(t *testing.T) {
+		actual := renderOutput(tarpOutput{DeclaredCount: 2, CalledCount: 2, Score: 100})
+		assert.Contains(t, actual, "(2/2 functions)")
+		assert.NotContains(t, actual, "Functions without direct unit tests")
+	}
+	t.Run("perfect score", perfect)
+
+	missing := func(t *testing.T) {
+		output := tarpOutput{
+			DeclaredCount:             2,
+			CalledCount:               1,
+			Score:                     50,
+			MaxDepth:                  1,
+			LongestFunctionNameLength: 1,
+			Details: map[string][]tarpFunc{
+				"main.go": {{Name: "b", Filename: "main.go", DeclPos: token.Position{Line: 7}, Distance: untestedDistance}},
+			},
+		}
+
+		actual := renderOutput(output)
+		assert.Contains(t, actual, "Functions without direct unit tests")
+		assert.Contains(t, actual, "b on line 7 (distance ∞)")
+		assert.Contains(t, actual, "(1/2 functions)")
+		assert.NotContains(t, actual, "black-box", "test kinds are only worth mentioning when there are black-box tests")
+	}
+	t.Run("missing functions", missing)
+
+	blackBox := func(t *testing.T) {
+		actual := renderOutput(tarpOutput{DeclaredCount: 3, CalledCount: 3, Score: 100, WhiteBoxCount: 1, BlackBoxCount: 2})
+		assert.Contains(t, actual, "1 functions tested by white-box tests, 2 by black-box tests")
+	}
+	t.Run("black-box tests", blackBox)
+
+	viaInterface := func(t *testing.T) {
+		actual := renderOutput(tarpOutput{DeclaredCount: 3, CalledCount: 3, Score: 100, WhiteBoxCount: 2, ViaInterfaceCount: 1})
+		assert.Contains(t, actual, "2 functions tested by white-box tests, 0 by black-box tests, 1 via interface")
+	}
+	t.Run("via interface", viaInterface)
+
+	byReference := func(t *testing.T) {
+		referenced := tarpFunc{Name: "B", Filename: "main.go", DeclPos: token.Position{Line: 7}, Distance: 1, Credits: []string{byReferenceCredit}}
+		output := generateDiffReport([]string{}, map[string]tarpFunc{"main.go:B": referenced}, 1, 1)
+
+		assert.Equal(t, 1, output.ByReferenceCount)
+		assert.Equal(t, map[string][]tarpFunc{"main.go": {referenced}}, output.ReferenceDetails)
+
+		actual := renderOutput(output)
+		assert.Contains(t, actual, "Functions credited only by reference:")
+		assert.Contains(t, actual, "B on line 7")
+		assert.Contains(t, actual, "0 functions tested by white-box tests, 0 by black-box tests, 1 by reference")
+	}
+	t.Run("by reference", byReference)
+
+	singleCase := func(t *testing.T) {
+		tabled := tarpFunc{Name: "Sub", Filename: "main.go", DeclPos: token.Position{Line: 7}, Distance: 1, Cases: []string{"equal"}, CaseCount: 1}
+		output := generateDiffReport([]string{}, map[string]tarpFunc{"main.go:Sub": tabled}, 1, 1)
+
+		assert.Equal(t, map[string][]string{"main.go:Sub": {"equal"}}, output.Cases)
+		assert.Equal(t, map[string]int{"main.go:Sub": 1}, output.CaseCounts)
+		assert.Equal(t, []string{"main.go:Sub"}, output.SingleCase)
+
+		actual := renderOutput(output)
+		assert.Contains(t, actual, "Functions only exercised by single-case tables:")
+		assert.Contains(t, actual, "Sub on line 7")
+	}
+	t.Run("single-case tables", singleCase)
+
+	conventionMode := func(t *testing.T) {
+		output := tarpOutput{
+			Engine:                    conventionEngine,
+			DeclaredCount:             2,
+			CalledCount:               1,
+			Score:                     50,
+			MaxDepth:                  1,
+			LongestFunctionNameLength: 1,
+			OrphanedTests:             []string{"TestRender"},
+			Details: map[string][]tarpFunc{
+				"main.go": {{Name: "b", Filename: "main.go", DeclPos: token.Position{Line: 7}, Distance: untestedDistance}},
+			},
+		}
+
+		actual := renderOutput(output)
+		assert.Contains(t, actual, "Tests not named after any function:\n\tTestRender")
+		assert.Contains(t, actual, "Functions without a test named after them:")
+		assert.NotContains(t, actual, "Functions without direct unit tests")
+		assert.Contains(t, actual, "b on line 7\n")
+		assert.NotContains(t, actual, "distance", "distances mean nothing when functions are credited by name")
+	}
+	t.Run("convention mode", conventionMode)
+
+	helpers := func(t *testing.T) {
+		helped := tarpFunc{Name: "NewStore", Filename: "main.go", DeclPos: token.Position{Line: 8}, Distance: 1, Helpers: []string{"fill", "newTestStore"}}
+		output := generateDiffReport([]string{}, map[string]tarpFunc{"main.go:NewStore": helped}, 1, 1)
+
+		assert.Equal(t, map[string][]string{"main.go:NewStore": {"fill", "newTestStore"}}, output.Helpers)
+
+		actual := renderOutput(output)
+		assert.Contains(t, actual, "Functions only called through test helpers:")
+		assert.Contains(t, actual, "NewStore on line 8 (through fill, newTestStore)")
+	}
+	t.Run("test helpers", helpers)
+
+	ignored := func(t *testing.T) {
+		stale := tarpFunc{Name: "Name", Filename: "main.go", DeclPos: token.Position{Line: 24}, Distance: 1, Ignore: &tarpIgnore{Reason: "trivial getter", Stale: true}}
+		expired := tarpFunc{Name: "Serve", Filename: "main.go", DeclPos: token.Position{Line: 21}, Distance: untestedDistance, Ignore: &tarpIgnore{Until: "2000-01-01", Expired: true}}
+		output := generateDiffReport([]string{"main.go:Serve"}, map[string]tarpFunc{"main.go:Name": stale, "main.go:Serve": expired}, 1, 0)
+
+		assert.Equal(t, map[string]tarpIgnore{"main.go:Name": *stale.Ignore, "main.go:Serve": *expired.Ignore}, output.Ignored)
+
+		actual := renderOutput(output)
+		assert.Contains(t, actual, "Ignored functions:")
+		assert.Contains(t, actual, "Name on line 24 (trivial getter) stale, now has a direct test")
+		assert.Contains(t, actual, "Serve on line 21 expired on 2000-01-01")
+	}
+	t.Run("ignored functions", ignored)
+
+	generated := func(t *testing.T) {
+		stringer := tarpFunc{Name: "Kind.String", Filename: "kind_string.go", DeclPos: token.Position{Line: 11}, Distance: 1, Generated: true}
+		getter := tarpFunc{Name: "Request.GetName", Filename: "request.pb.go", DeclPos: token.Position{Line: 10}, Distance: untestedDistance, Generated: true}
+		ignored := tarpFunc{Name: "Request.Reset", Filename: "request.pb.go", DeclPos: token.Position{Line: 17}, Distance: untestedDistance, Generated: true, Ignore: &tarpIgnore{}}
+		output := generateDiffReport([]string{}, map[string]tarpFunc{"kind_string.go:Kind.String": stringer, "request.pb.go:Request.GetName": getter, "request.pb.go:Request.Reset": ignored}, 0, 0)
+
+		assert.Equal(t, 2, output.GeneratedDeclaredCount, "functions ignored by a directive shouldn't count towards the generated score")
+		assert.Equal(t, 1, output.GeneratedCalledCount)
+		assert.Equal(t, 50, output.GeneratedScore)
+		assert.Equal(t, []string{"request.pb.go:Request.GetName"}, output.Generated)
+
+		actual := renderOutput(output)
+		assert.Contains(t, actual, "Generated functions without direct unit tests:")
+		assert.Contains(t, actual, "Request.GetName on line 10 (distance ∞)")
+		assert.Contains(t, actual, "Generated code: 50% (1/2 functions)")
+	}
+	t.Run("generated code", generated)
+}
+
+func TestAggregateReports(t *testing.T) {
+	outputs := []tarpOutput{
+		{Package: "a", Engine: typesEngine, DeclaredCount: 4, CalledCount: 3},
+		{Package: "b", Engine: typesEngine, DeclaredCount: 6, CalledCount: 6},
+	}
+
+	expected := tarpModuleOutput{
+		Engine:        typesEngine,
+		DeclaredCount: 10,
+		CalledCount:   9,
+		Score:         90,
+		MaxDepth:      1,
+		Packages:      outputs,
+	}
+
+	assert.Equal(t, expected, aggregateReports(outputs))
+	assert.Equal(t, 100, aggregateReports([]tarpOutput{{Package: "empty"}}).Score, "packages without functions shouldn't be penalized")
+}
+
+func TestRenderModuleOutput(t *testing.T) {
+	moduleReport := aggregateReports([]tarpOutput{
+		{Package: "a", DeclaredCount: 4, CalledCount: 4, Score: 100},
+		{Package: "b", DeclaredCount: 6, CalledCount: 5, Score: 83},
+	})
+
+	actual := renderModuleOutput(moduleReport)
+	assert.Contains(t, actual, "Package a")
+	assert.Contains(t, actual, "Package b")
+	assert.Contains(t, actual, "(4/4 functions)")
+	assert.Contains(t, actual, "(9/10 functions across 2 packages)")
+}
+
+func TestPercentage(t *testing.T) {
+	assert.Equal(t, 75, percentage(3, 4))
+	assert.Equal(t, 33, percentage(1, 3))
+	assert.Equal(t, 100, percentage(0, 0), "having nothing to test should be a perfect score")
+}
+
+func TestAddAnalysisFlags(t *testing.T) {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	addAnalysisFlags(flags)
+
+	assert.Equal(t, typesEngine, flags.Lookup("engine").DefValue)
+	assert.Equal(t, "1", flags.Lookup("max-depth").DefValue)
+	assert.NotNil(t, flags.Lookup("generated-pattern"))
+	assert.Nil(t, flags.Lookup("fail-on-found"), "flags that don't decide how packages are analyzed should be left out")
+}
+
+func TestAnalyzePackages(t *testing.T) {
+	actual := analyzePackages([]string{buildExamplePackagePath(t, "simple", true), buildExamplePackagePath(t, "perfect", true)})
+
+	assert.Len(t, actual, 2)
+	assert.Equal(t, buildExamplePackagePath(t, "simple", false), actual[0].Package)
+	assert.Equal(t, 75, actual[0].Score)
+	assert.Equal(t, typesEngine, actual[0].Engine)
+	assert.Equal(t, buildExamplePackagePath(t, "perfect", false), actual[1].Package)
+	assert.Equal(t, 100, actual[1].Score)
+
+	convention = true
+	defer func() { convention = false }()
+	actual = analyzePackages([]string{buildExamplePackagePath(t, "convention", true)})
+
+	assert.Equal(t, conventionEngine, actual[0].Engine)
+	assert.Equal(t, []string{"TestRender"}, actual[0].OrphanedTests)
+
+	convention = false
+	actual = analyzePackages([]string{buildExamplePackagePath(t, "ignore", true)})
+
+	assert.Equal(t, 3, actual[0].DeclaredCount, "ignored functions shouldn't be counted unless their directive has expired")
+	assert.Equal(t, 1, actual[0].CalledCount)
+	assert.Len(t, actual[0].Ignored, 5)
+
+	actual = analyzePackages([]string{buildExamplePackagePath(t, "generated", true)})
+
+	assert.Equal(t, 3, actual[0].DeclaredCount, "generated functions shouldn't be counted by default")
+	assert.Equal(t, 2, actual[0].GeneratedDeclaredCount)
+	assert.Equal(t, 1, actual[0].GeneratedCalledCount)
+
+	generatedPatterns = []string{"mock_*.go"}
+	defer func() { generatedPatterns = nil }()
+	actual = analyzePackages([]string{buildExamplePackagePath(t, "generated", true)})
+
+	assert.Equal(t, 2, actual[0].DeclaredCount)
+	assert.Equal(t, 3, actual[0].GeneratedDeclaredCount)
+}
+
+func TestAnalyzeModules(t *testing.T) {
+	root := buildWorkspaceTree(t)
+	defer os.RemoveAll(root)
+	data, _ := ioutil.ReadFile(root + "/go.work")
+
+	actual := analyzeModules(parseGoWork(data, root))
+
+	assert.Len(t, actual.Modules, 2)
+	assert.Equal(t, "example.com/lib", actual.Modules[0].Module)
+	assert.Equal(t, 50, actual.Modules[0].Score)
+	assert.Equal(t, "example.com/svc", actual.Modules[1].Module)
+	assert.Equal(t, 100, actual.Modules[1].Score)
+	assert.Equal(t, typesEngine, actual.Modules[1].Engine, "cross-module imports should be resolved so the package can be type-checked")
+	assert.Equal(t, 4, actual.DeclaredCount)
+	assert.Equal(t, 3, actual.CalledCount)
+	assert.Equal(t, 75, actual.Score)
+}
+
+func TestRenderWorkspaceOutput(t *testing.T) {
+	workspaceReport := tarpWorkspaceOutput{
+		DeclaredCount: 10,
+		CalledCount:   9,
+		Score:         90,
+		Modules: []tarpModuleOutput{
+			aggregateReports([]tarpOutput{{Package: "a/pkg", DeclaredCount: 4, CalledCount: 4, Score: 100}}),
+			aggregateReports([]tarpOutput{{Package: "b/pkg", DeclaredCount: 6, CalledCount: 5, Score: 83}}),
+		},
+	}
+	workspaceReport.Modules[0].Module = "a"
+	workspaceReport.Modules[1].Module = "b"
+
+	actual := renderWorkspaceOutput(workspaceReport)
+	assert.Contains(t, actual, "Module a")
+	assert.Contains(t, actual, "Package b/pkg")
+	assert.Contains(t, actual, "(9/10 functions across 2 modules)")
 }
 
 func TestFuncMain(t *testing.T) {
@@ -191,6 +532,7 @@ func TestFuncMain(t *testing.T) {
 		monkey.Patch(os.Getwd, func() (string, error) {
 			return "", errors.New("pineapple on pizza")
 		})
+		defer monkey.Unpatch(os.Getwd)
 
 		var fatalfCalled bool
 		defer func() {
@@ -198,6 +540,8 @@ func TestFuncMain(t *testing.T) {
 				// recovered from our monkey patched log.Fatalf
 				fatalfCalled = true
 			}
+			os.Args = originalArgs
+			assert.True(t, fatalfCalled, "main should call log.Fatalf() when it can't manage to retrieve the current directory")
 		}()
 
 		os.Args = []string{
@@ -206,9 +550,6 @@ func TestFuncMain(t *testing.T) {
 		}
 
 		main()
-		os.Args = originalArgs
-		assert.True(t, fatalfCalled, "main should call log.Fatalf() when it can't manage to retrieve the current directory")
-		monkey.Unpatch(os.Getwd)
 	}
 	t.Run("test", directoryWoes)
 
@@ -370,10 +711,113 @@ func TestFuncMain(t *testing.T) {
 		main()
 		assert.True(t, exitCalled, "main should call log.Fatal() when --fail-on-found is passed in and extras are found")
 		os.Args = originalArgs
-		monkey.Unpatch(os.Exit)
+		patchExit()
 	}
 	t.Run("fails with --fail-on-found", failsWhenInstructed)
 
+	failsBelowThresholds := func(t *testing.T) {
+		failOnFound = false
+		os.Args = []string{
+			originalArgs[0],
+			"analyze",
+			fmt.Sprintf("--package=%s", buildExamplePackagePath(t, "simple", false)),
+			"--min-score=80",
+		}
+		defer func() { minScore = 0 }()
+		var exitCode int
+
+		monkey.Patch(os.Exit, func(code int) {
+			exitCode = code
+		})
+
+		main()
+		assert.Equal(t, exitUntested, exitCode, "main should exit with exitUntested when a threshold is breached")
+		os.Args = originalArgs
+		patchExit()
+	}
+	t.Run("fails below thresholds", failsBelowThresholds)
+
+	invalidThresholds := func(t *testing.T) {
+		os.Args = []string{
+			originalArgs[0],
+			"analyze",
+			fmt.Sprintf("--package=%s", buildExamplePackagePath(t, "simple", false)),
+			"--package-min-score=./...",
+		}
+		defer func() { packageMinScores = nil }()
+
+		var exitCalled bool
+		defer func() {
+			// recovered from our monkey patched os.Exit
+			if r := recover(); r != nil {
+				exitCalled = true
+			}
+			os.Args = originalArgs
+			assert.True(t, exitCalled, "main should exit when a threshold is invalid")
+		}()
+
+		main()
+	}
+	t.Run("invalid thresholds", invalidThresholds)
+
+	baselineTest := func(t *testing.T) {
+		f, _ := ioutil.TempFile("", "tarp_baseline")
+		f.Close()
+		// baseline write creates the file
+		os.Remove(f.Name())
+		defer os.Remove(f.Name())
+		defer func() {
+			baselineFile, baselineOutput, failOnFound = "", defaultBaselineFile, false
+		}()
+
+		os.Args = []string{
+			originalArgs[0],
+			"baseline",
+			"write",
+			fmt.Sprintf("--baseline=%s", f.Name()),
+			buildExamplePackagePath(t, "simple", false),
+		}
+		main()
+		assert.Len(t, readBaseline(f.Name()).Untested, 1, "the untested function should be recorded")
+
+		os.Args = []string{
+			originalArgs[0],
+			"analyze",
+			fmt.Sprintf("--package=%s", buildExamplePackagePath(t, "simple", false)),
+			fmt.Sprintf("--baseline=%s", f.Name()),
+			"--fail-on-found",
+		}
+		main()
+		os.Args = originalArgs
+	}
+	t.Run("baseline", baselineTest)
+
+	failsWithBaseline := func(t *testing.T) {
+		f, _ := ioutil.TempFile("", "tarp_baseline")
+		f.Close()
+		defer os.Remove(f.Name())
+		writeBaseline(f.Name(), tarpBaseline{Untested: []baselineEntry{}})
+		defer func() { baselineFile = "" }()
+
+		os.Args = []string{
+			originalArgs[0],
+			"analyze",
+			fmt.Sprintf("--package=%s", buildExamplePackagePath(t, "simple", false)),
+			fmt.Sprintf("--baseline=%s", f.Name()),
+		}
+		var exitCode int
+
+		monkey.Patch(os.Exit, func(code int) {
+			exitCode = code
+		})
+
+		main()
+		assert.Equal(t, exitUntested, exitCode, "main should exit with exitUntested when a function isn't in the baseline")
+		os.Args = originalArgs
+		patchExit()
+	}
+	t.Run("fails with --baseline", failsWithBaseline)
+
 	padTest := func(t *testing.T) {
 		failOnFound = false
 		os.Args = []string{
@@ -401,6 +845,217 @@ func TestFuncMain(t *testing.T) {
 	}
 	t.Run("JSON test", jsonTest)
 
+	maxDepthTest := func(t *testing.T) {
+		defer func() { maxDepth = 1 }()
+		os.Args = []string{
+			originalArgs[0],
+			"analyze",
+			"--max-depth=2",
+			fmt.Sprintf("--package=%s", buildExamplePackagePath(t, "depth", false)),
+		}
+
+		main()
+		os.Args = originalArgs
+		assert.Equal(t, 2, maxDepth)
+	}
+	t.Run("max depth", maxDepthTest)
+
+	patternTest := func(t *testing.T) {
+		os.Args = []string{
+			originalArgs[0],
+			"analyze",
+			buildExamplePackagePath(t, "simple", false),
+			buildExamplePackagePath(t, "...", false),
+		}
+
+		main()
+		os.Args = originalArgs
+	}
+	t.Run("package patterns", patternTest)
+
+	patternJSONTest := func(t *testing.T) {
+		os.Args = []string{
+			originalArgs[0],
+			"analyze",
+			"--json",
+			buildExamplePackagePath(t, "simple", false),
+			buildExamplePackagePath(t, "perfect", false),
+		}
+
+		main()
+		os.Args = originalArgs
+	}
+	t.Run("package patterns as JSON", patternJSONTest)
+
+	noMatchingPackages := func(t *testing.T) {
+		os.Args = []string{
+			originalArgs[0],
+			"analyze",
+			buildExamplePackagePath(t, "no_go_files/...", false),
+		}
+
+		var fatalfCalled bool
+		defer func() {
+			// recovered from our monkey patched log.Fatalf
+			if r := recover(); r != nil {
+				fatalfCalled = true
+			}
+			os.Args = originalArgs
+			assert.True(t, fatalfCalled, "main should call log.Fatalf() when no packages match the given patterns")
+		}()
+
+		main()
+	}
+	t.Run("no matching packages", noMatchingPackages)
+
+	workspaceTest := func(t *testing.T) {
+		root := buildWorkspaceTree(t)
+		defer os.RemoveAll(root)
+		wd, _ := os.Getwd()
+		defer os.Chdir(wd)
+		os.Chdir(filepath.Join(root, "svc"))
+		defer func() { analyzeWorkspace = false }()
+
+		for _, format := range []string{"--json=false", "--json"} {
+			os.Args = []string{
+				originalArgs[0],
+				"analyze",
+				"--workspace",
+				format,
+			}
+
+			main()
+		}
+		outputAsJSON = false
+		os.Args = originalArgs
+	}
+	t.Run("workspace", workspaceTest)
+
+	configShow := func(t *testing.T) {
+		root := buildConfigTree(t)
+		defer os.RemoveAll(root)
+		wd, _ := os.Getwd()
+		defer os.Chdir(wd)
+		os.Chdir(filepath.Join(root, "legacy"))
+		defer func() {
+			analysisEngine, maxDepth, failOnFound, buildTags = typesEngine, 1, false, nil
+		}()
+
+		os.Args = []string{
+			originalArgs[0],
+			"config",
+			"show",
+		}
+
+		main()
+		os.Args = originalArgs
+	}
+	t.Run("config show", configShow)
+
+	matrixTest := func(t *testing.T) {
+		defer func() { matrix = nil }()
+
+		rendered := map[string]string{}
+		for _, format := range []string{"--json=false", "--json"} {
+			os.Args = []string{
+				originalArgs[0],
+				"analyze",
+				"--matrix=linux,windows:integration",
+				format,
+				buildExamplePackagePath(t, "constraints", false),
+			}
+			// slice flags add to their values when they're given again, which they are on every run of main
+			matrix = nil
+
+			r, w, _ := os.Pipe()
+			stdout := os.Stdout
+			os.Stdout = w
+			main()
+			os.Stdout = stdout
+			w.Close()
+			out, _ := ioutil.ReadAll(r)
+			rendered[format] = string(out)
+		}
+		outputAsJSON = false
+		os.Args = originalArgs
+
+		assert.Regexp(t, `(?m)^function\s+linux\s+windows:integration\s*$`, rendered["--json=false"], "every configuration should get a column, once")
+		assert.Contains(t, rendered["--json=false"], "functions untested in every configuration")
+
+		actual := tarpMatrixOutput{}
+		assert.Nil(t, json.Unmarshal([]byte(rendered["--json"]), &actual))
+		assert.Contains(t, actual.Configurations, "linux")
+		assert.Contains(t, actual.Configurations, "windows:integration")
+		assert.Len(t, actual.Configurations, 2)
+		assert.NotEmpty(t, actual.DeclaredInSome)
+		for name, declared := range actual.DeclaredInSome {
+			assert.Len(t, declared, 1, "%s should only be declared in one configuration", name)
+		}
+	}
+	t.Run("matrix", matrixTest)
+
+	duplicateMatrix := func(t *testing.T) {
+		os.Args = []string{
+			originalArgs[0],
+			"analyze",
+			"--matrix=linux,linux",
+			buildExamplePackagePath(t, "constraints", false),
+		}
+		defer func() {
+			matrix = nil
+			os.Args = originalArgs
+			assert.Equal(t, "os.Exit(64)", recover(), "main should exit with a usage error when a configuration is given twice")
+		}()
+
+		main()
+	}
+	t.Run("duplicate matrix", duplicateMatrix)
+
+	invalidMatrix := func(t *testing.T) {
+		os.Args = []string{
+			originalArgs[0],
+			"analyze",
+			"--matrix=linux/amd64/extra",
+			buildExamplePackagePath(t, "constraints", false),
+		}
+
+		var fatalCalled bool
+		defer func() {
+			// recovered from our monkey patched log.Fatal
+			if r := recover(); r != nil {
+				fatalCalled = true
+			}
+			matrix = nil
+			os.Args = originalArgs
+			assert.True(t, fatalCalled, "main should call log.Fatal() when given an invalid --matrix configuration")
+		}()
+
+		main()
+	}
+	t.Run("invalid matrix", invalidMatrix)
+
+	noWorkspace := func(t *testing.T) {
+		os.Args = []string{
+			originalArgs[0],
+			"analyze",
+			"--workspace",
+		}
+
+		var fatalfCalled bool
+		defer func() {
+			// recovered from our monkey patched log.Fatalf
+			if r := recover(); r != nil {
+				fatalfCalled = true
+			}
+			analyzeWorkspace = false
+			os.Args = originalArgs
+			assert.True(t, fatalfCalled, "main should call log.Fatalf() when --workspace is passed outside of a workspace")
+		}()
+
+		main()
+	}
+	t.Run("no workspace", noWorkspace)
+
 	coverTest := func(t *testing.T) {
 		monkey.Patch(startBrowser, func(url, os string) bool { return true })
 		os.Args = []string{
@@ -437,7 +1092,7 @@ func TestFuncMain(t *testing.T) {
 	t.Run("cover fails when it cannot parse the profile", coverTestWithErrorParsingProfiles)
 
 	coverTestWithErrorGeneratingHTMLOutput := func(t *testing.T) {
-		monkey.Patch(htmlOutput, func(string, string, tarpReport) error { return errors.New("pineapple on pizza") })
+		monkey.Patch(htmlOutput, func(string, string, map[string]tarpReport) error { return errors.New("pineapple on pizza") })
 
 		var fatalCalled bool
 		defer func() {
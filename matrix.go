@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/set"
+)
+
+// buildConfig is one configuration of a --matrix run: a platform plus the build tags to satisfy. An empty
+// GOOS or GOARCH means the default for the host.
+type buildConfig struct {
+	Name   string
+	GOOS   string
+	GOARCH string
+	Tags   []string
+}
+
+// parseBuildConfig parses a configuration given to --matrix, which looks like goos[/goarch][:tag+tag].
+// Either half can be left out, so "linux", "darwin/arm64", "linux:integration" and ":purego" are all valid.
+func parseBuildConfig(s string) (buildConfig, error) {
+	config := buildConfig{Name: s}
+	platform, tags := s, ""
+	if i := strings.Index(s, ":"); i >= 0 {
+		platform, tags = s[:i], s[i+1:]
+	}
+
+	parts := strings.Split(platform, "/")
+	if len(parts) > 2 || (len(parts) == 2 && (parts[0] == "" || parts[1] == "")) {
+		return buildConfig{}, fmt.Errorf("invalid platform %q in configuration %q, expected goos or goos/goarch", platform, s)
+	}
+	config.GOOS = parts[0]
+	if len(parts) == 2 {
+		config.GOARCH = parts[1]
+	}
+
+	for _, tag := range strings.Split(tags, "+") {
+		if tag != "" {
+			config.Tags = append(config.Tags, tag)
+		}
+	}
+	if platform == "" && len(config.Tags) == 0 {
+		return buildConfig{}, fmt.Errorf("empty configuration %q", s)
+	}
+	return config, nil
+}
+
+// parseBuildConfigs parses every configuration given to --matrix. Configurations are told apart by name, so
+// each one can only be given once.
+func parseBuildConfigs(values []string) ([]buildConfig, error) {
+	configs, seen := []buildConfig{}, set.New()
+	for _, s := range values {
+		if seen.Has(s) {
+			return nil, fmt.Errorf("configuration %q is given more than once", s)
+		}
+		seen.Add(s)
+
+		config, err := parseBuildConfig(s)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, config)
+	}
+	return configs, nil
+}
+
+// matrixFuncName identifies a function across configurations by its package as well as its file and name.
+func matrixFuncName(pkg, key string) string {
+	return pkg + "/" + key
+}
+
+// analyzeMatrix runs the analysis of the packages matching patterns once for every configuration, and
+// compares the results. Functions are sorted into those untested in every configuration that declares them,
+// those tested in some configurations but not others, and those only declared in some configurations.
+func analyzeMatrix(patterns []string, configs []buildConfig) tarpMatrixOutput {
+	originalTags, originalOS, originalArch := buildTags, targetOS, targetArch
+	defer func() { buildTags, targetOS, targetArch = originalTags, originalOS, originalArch }()
+
+	matrixReport := tarpMatrixOutput{
+		MaxDepth:           maxDepth,
+		Configurations:     map[string]tarpModuleOutput{},
+		UntestedEverywhere: []string{},
+		UntestedInSome:     map[string][]string{},
+		DeclaredInSome:     map[string][]string{},
+	}
+
+	declaredIn, testedIn := map[string][]string{}, map[string][]string{}
+	for _, config := range configs {
+		buildTags, targetOS, targetArch = config.Tags, config.GOOS, config.GOARCH
+		matrixReport.Order = append(matrixReport.Order, config.Name)
+
+		moduleReport := aggregateReports(analyzePackages(expandPatterns(patterns)))
+		if matrixReport.Engine == "" {
+			matrixReport.Engine = moduleReport.Engine
+		}
+		matrixReport.Configurations[config.Name] = moduleReport
+
+		for _, output := range moduleReport.Packages {
+			for key, distance := range output.Distances {
+				name := matrixFuncName(output.Package, key)
+				declaredIn[name] = append(declaredIn[name], config.Name)
+				// packages can have a --max-depth of their own
+				if distance != untestedDistance && distance <= output.MaxDepth {
+					testedIn[name] = append(testedIn[name], config.Name)
+				}
+			}
+		}
+	}
+
+	for name, declared := range declaredIn {
+		if len(declared) < len(configs) {
+			matrixReport.DeclaredInSome[name] = declared
+		}
+
+		tested := testedIn[name]
+		switch {
+		case len(tested) == 0:
+			matrixReport.UntestedEverywhere = append(matrixReport.UntestedEverywhere, name)
+		case len(tested) < len(declared):
+			untested := []string{}
+			testedSet := set.New()
+			for _, configName := range tested {
+				testedSet.Add(configName)
+			}
+			for _, configName := range declared {
+				if !testedSet.Has(configName) {
+					untested = append(untested, configName)
+				}
+			}
+			matrixReport.UntestedInSome[name] = untested
+		}
+	}
+	sort.Strings(matrixReport.UntestedEverywhere)
+	return matrixReport
+}
+
+// matrixCell renders whether a function is tested, untested, or not declared at all in a configuration.
+func matrixCell(name, configName string, matrixReport tarpMatrixOutput) string {
+	output := matrixReport.Configurations[configName]
+	for _, pkg := range output.Packages {
+		if key := strings.TrimPrefix(name, pkg.Package+"/"); key != name {
+			if distance, ok := pkg.Distances[key]; ok {
+				if distance != untestedDistance && distance <= pkg.MaxDepth {
+					return "tested"
+				}
+				return "untested"
+			}
+		}
+	}
+	return "-"
+}
+
+// renderMatrixOutput renders a table with a row for every function that isn't tested in every
+// configuration, and a column for every configuration, followed by each configuration's score.
+func renderMatrixOutput(matrixReport tarpMatrixOutput) string {
+	rows := set.New()
+	for _, name := range matrixReport.UntestedEverywhere {
+		rows.Add(name)
+	}
+	for name := range matrixReport.UntestedInSome {
+		rows.Add(name)
+	}
+	for name := range matrixReport.DeclaredInSome {
+		rows.Add(name)
+	}
+	names := set.StringSlice(rows)
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "function\t%s\t\n", strings.Join(matrixReport.Order, "\t"))
+	for _, name := range names {
+		cells := []string{}
+		for _, configName := range matrixReport.Order {
+			cells = append(cells, matrixCell(name, configName, matrixReport))
+		}
+		fmt.Fprintf(w, "%s\t%s\t\n", name, strings.Join(cells, "\t"))
+	}
+
+	scores := []string{}
+	for _, configName := range matrixReport.Order {
+		output := matrixReport.Configurations[configName]
+		scores = append(scores, fmt.Sprintf("%d%% (%d/%d)", output.Score, output.CalledCount, output.DeclaredCount))
+	}
+	fmt.Fprintf(w, "score\t%s\t\n", strings.Join(scores, "\t"))
+	w.Flush()
+
+	summary := fmt.Sprintf("\n%d functions untested in every configuration, %d untested in only some, %d declared in only some",
+		len(matrixReport.UntestedEverywhere), len(matrixReport.UntestedInSome), len(matrixReport.DeclaredInSome))
+	return buf.String() + summary
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBuildConfig(t *testing.T) {
+	valid := map[string]buildConfig{
+		"linux":                     {Name: "linux", GOOS: "linux"},
+		"darwin/arm64":              {Name: "darwin/arm64", GOOS: "darwin", GOARCH: "arm64"},
+		"linux:integration":         {Name: "linux:integration", GOOS: "linux", Tags: []string{"integration"}},
+		":purego":                   {Name: ":purego", Tags: []string{"purego"}},
+		"linux/amd64:purego+netgo+": {Name: "linux/amd64:purego+netgo+", GOOS: "linux", GOARCH: "amd64", Tags: []string{"purego", "netgo"}},
+	}
+	for input, expected := range valid {
+		actual, err := parseBuildConfig(input)
+		assert.Nil(t, err, "unexpected error parsing %q", input)
+		assert.Equal(t, expected, actual)
+	}
+
+	for _, input := range []string{"", ":", "linux/amd64/extra", "/amd64", "linux/"} {
+		_, err := parseBuildConfig(input)
+		assert.NotNil(t, err, "expected an error parsing %q", input)
+	}
+}
+
+func TestParseBuildConfigs(t *testing.T) {
+	actual, err := parseBuildConfigs([]string{"linux", "darwin/arm64"})
+	assert.Nil(t, err)
+	assert.Equal(t, []buildConfig{{Name: "linux", GOOS: "linux"}, {Name: "darwin/arm64", GOOS: "darwin", GOARCH: "arm64"}}, actual)
+
+	_, err = parseBuildConfigs([]string{"linux", "darwin", "linux"})
+	assert.EqualError(t, err, `configuration "linux" is given more than once`)
+
+	_, err = parseBuildConfigs([]string{"linux", "/amd64"})
+	assert.NotNil(t, err, "invalid configurations should be rejected")
+}
+
+func TestAnalyzeMatrix(t *testing.T) {
+	configs := []buildConfig{
+		{Name: "linux", GOOS: "linux"},
+		{Name: "windows", GOOS: "windows"},
+		{Name: "linux:integration", GOOS: "linux", Tags: []string{"integration"}},
+	}
+	pkg := buildExamplePackagePath(t, "constraints", false)
+
+	actual := analyzeMatrix([]string{pkg}, configs)
+
+	assert.Equal(t, []string{"linux", "windows", "linux:integration"}, actual.Order)
+	assert.Len(t, actual.Configurations, 3)
+	assert.Equal(t, 1, actual.Configurations["linux"].CalledCount)
+	assert.Equal(t, 2, actual.Configurations["linux:integration"].CalledCount)
+	assert.Equal(t, []string{matrixFuncName(pkg, "main.go:name")}, actual.UntestedEverywhere)
+	assert.Equal(t, map[string][]string{matrixFuncName(pkg, "main.go:flush"): {"linux", "windows"}}, actual.UntestedInSome)
+	expectedDeclaredInSome := map[string][]string{
+		matrixFuncName(pkg, "file_linux.go:open"):   {"linux", "linux:integration"},
+		matrixFuncName(pkg, "file_windows.go:open"): {"windows"},
+	}
+	assert.Equal(t, expectedDeclaredInSome, actual.DeclaredInSome)
+	assert.Equal(t, "", targetOS, "the build flags should be restored after the matrix is analyzed")
+	assert.Nil(t, buildTags)
+}
+
+func TestAnalyzeMatrixExclusions(t *testing.T) {
+	configs := []buildConfig{{Name: "linux", GOOS: "linux"}, {Name: "darwin", GOOS: "darwin"}}
+
+	ignored := buildExamplePackagePath(t, "ignore", false)
+	actual := analyzeMatrix([]string{ignored}, configs)
+	expected := []string{matrixFuncName(ignored, "main.go:Serve"), matrixFuncName(ignored, "main.go:Untested")}
+	assert.Equal(t, expected, actual.UntestedEverywhere, "functions ignored by a directive that hasn't expired should be left out")
+
+	generated := buildExamplePackagePath(t, "generated", false)
+	actual = analyzeMatrix([]string{generated}, configs)
+	for _, name := range actual.UntestedEverywhere {
+		assert.NotContains(t, name, ".pb.go", "generated functions should be left out unless --count-generated is set")
+	}
+}
+
+func TestMatrixCell(t *testing.T) {
+	matrixReport := tarpMatrixOutput{
+		Configurations: map[string]tarpModuleOutput{
+			"linux": {
+				Packages: []tarpOutput{
+					{Package: "pkg", MaxDepth: 1, Distances: map[string]int{"a.go:tested": 1, "a.go:far": 3, "a.go:untested": untestedDistance}},
+					{Package: "deep", MaxDepth: 3, Distances: map[string]int{"a.go:far": 3}},
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, "tested", matrixCell("pkg/a.go:tested", "linux", matrixReport))
+	assert.Equal(t, "untested", matrixCell("pkg/a.go:far", "linux", matrixReport), "functions beyond --max-depth aren't tested")
+	assert.Equal(t, "untested", matrixCell("pkg/a.go:untested", "linux", matrixReport))
+	assert.Equal(t, "-", matrixCell("pkg/b.go:missing", "linux", matrixReport))
+	assert.Equal(t, "-", matrixCell("other/a.go:tested", "linux", matrixReport))
+	assert.Equal(t, "tested", matrixCell("deep/a.go:far", "linux", matrixReport), "packages can have a --max-depth of their own")
+}
+
+func TestRenderMatrixOutput(t *testing.T) {
+	matrixReport := tarpMatrixOutput{
+		Order: []string{"linux", "windows"},
+		Configurations: map[string]tarpModuleOutput{
+			"linux": {
+				CalledCount: 1, DeclaredCount: 2, Score: 50,
+				Packages: []tarpOutput{{Package: "pkg", MaxDepth: 1, Distances: map[string]int{"a_linux.go:open": 1, "a.go:name": untestedDistance}}},
+			},
+			"windows": {
+				CalledCount: 0, DeclaredCount: 1, Score: 0,
+				Packages: []tarpOutput{{Package: "pkg", MaxDepth: 1, Distances: map[string]int{"a.go:name": untestedDistance}}},
+			},
+		},
+		UntestedEverywhere: []string{"pkg/a.go:name"},
+		UntestedInSome:     map[string][]string{},
+		DeclaredInSome:     map[string][]string{"pkg/a_linux.go:open": {"linux"}},
+	}
+
+	actual := renderMatrixOutput(matrixReport)
+
+	assert.Contains(t, actual, "function             linux      windows")
+	assert.Contains(t, actual, "pkg/a.go:name        untested   untested")
+	assert.Contains(t, actual, "pkg/a_linux.go:open  tested     -")
+	assert.Contains(t, actual, "score                50% (1/2)  0% (0/1)")
+	assert.Contains(t, actual, "1 functions untested in every configuration, 0 untested in only some, 1 declared in only some")
+}
+
+func TestAnalyzeMatrixPackageConfig(t *testing.T) {
+	root := buildConfigTree(t)
+	defer os.RemoveAll(root)
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(root)
+	defer func() {
+		analysisEngine, maxDepth, failOnFound, buildTags, configuredFlags = typesEngine, 1, false, nil, nil
+	}()
+
+	files := map[string]string{
+		"platform/.tarp.toml":         "max-depth = 3\n",
+		"platform/platform.go":        "package platform\n\nfunc A() { b() }\n\nfunc b() { open() }\n",
+		"platform/platform_linux.go":  "package platform\n\nfunc open() {}\n",
+		"platform/platform_darwin.go": "package platform\n\nfunc open() {}\n",
+		"platform/platform_test.go":   "package platform\n\nimport \"testing\"\n\nfunc TestA(t *testing.T) { A() }\n",
+	}
+	for name, contents := range files {
+		os.MkdirAll(filepath.Dir(filepath.Join(root, name)), 0755)
+		ioutil.WriteFile(filepath.Join(root, name), []byte(contents), 0644)
+	}
+	// analyzeCmd's flags may have been given on the command line by other tests, which would take precedence
+	cmd := &cobra.Command{}
+	addAnalysisFlags(cmd.Flags())
+	configure(cmd, nil)
+
+	configs := []buildConfig{{Name: "linux", GOOS: "linux"}, {Name: "darwin", GOOS: "darwin"}}
+	actual := renderMatrixOutput(analyzeMatrix([]string{"./platform"}, configs))
+
+	assert.Equal(t, 2, maxDepth, "the module root's --max-depth should apply outside of the package")
+	assert.Regexp(t, `platform/platform_linux.go:open\s+tested\s+-`, actual, "open is three calls away from TestA, within platform's own --max-depth")
+	assert.Regexp(t, `platform/platform_darwin.go:open\s+-\s+tested`, actual)
+	assert.Contains(t, actual, "100% (3/3)  100% (3/3)")
+}
@@ -6,18 +6,96 @@ import (
 	"github.com/fatih/set"
 )
 
+// untestedDistance is the distance given to functions that can't be reached from any test.
+const untestedDistance = -1
+
+// credits record what kind of test a function was credited by. White-box tests live in the package
+// itself, while black-box tests live in an external foo_test package. Functions a test only reaches by
+// calling a method through an interface, or refers to without calling, are credited as such, whichever
+// package the test lives in.
+const (
+	whiteBoxCredit     = "white-box"
+	blackBoxCredit     = "black-box"
+	viaInterfaceCredit = "via interface"
+	byReferenceCredit  = "by reference"
+)
+
 type tarpOutput struct {
+	Package                   string                `json:"package,omitempty"`
+	Engine                    string                `json:"engine"`
 	DeclaredCount             int                   `json:"declared"`
 	CalledCount               int                   `json:"called"`
 	Score                     int                   `json:"score"`
+	MaxDepth                  int                   `json:"max_depth"`
+	WhiteBoxCount             int                   `json:"white_box"`
+	BlackBoxCount             int                   `json:"black_box"`
+	ViaInterfaceCount         int                   `json:"via_interface"`
+	ByReferenceCount          int                   `json:"by_reference"`
+	Distances                 map[string]int        `json:"distances"`
+	Credits                   map[string][]string   `json:"credits"`
+	Cases                     map[string][]string   `json:"cases"`
+	CaseCounts                map[string]int        `json:"case_counts"`
+	SingleCase                []string              `json:"single_case"`
+	Specs                     map[string][]string   `json:"specs"`
+	TestFuncs                 map[string][]string   `json:"test_funcs"`
+	Helpers                   map[string][]string   `json:"helpers"`
+	Ignored                   map[string]tarpIgnore `json:"ignored"`
+	GeneratedDeclaredCount    int                   `json:"generated_declared"`
+	GeneratedCalledCount      int                   `json:"generated_called"`
+	GeneratedScore            int                   `json:"generated_score"`
+	Generated                 []string              `json:"generated"`
+	OrphanedTests             []string              `json:"orphaned_tests,omitempty"`
 	Details                   map[string][]tarpFunc `json:"-"`
+	IndirectDetails           map[string][]tarpFunc `json:"-"`
+	ReferenceDetails          map[string][]tarpFunc `json:"-"`
+	SingleCaseDetails         map[string][]tarpFunc `json:"-"`
+	SpecDetails               map[string][]tarpFunc `json:"-"`
+	NonTestDetails            map[string][]tarpFunc `json:"-"`
+	HelperDetails             map[string][]tarpFunc `json:"-"`
+	IgnoredDetails            map[string][]tarpFunc `json:"-"`
+	GeneratedDetails          map[string][]tarpFunc `json:"-"`
 	LongestFunctionNameLength int                   `json:"-"`
 }
 
+// tarpModuleOutput is what gets reported when a pattern matches more than one package.
+type tarpModuleOutput struct {
+	Module        string       `json:"module,omitempty"`
+	Engine        string       `json:"engine"`
+	DeclaredCount int          `json:"declared"`
+	CalledCount   int          `json:"called"`
+	Score         int          `json:"score"`
+	MaxDepth      int          `json:"max_depth"`
+	Packages      []tarpOutput `json:"packages"`
+}
+
+// tarpWorkspaceOutput is what gets reported for every module in a go.work file.
+type tarpWorkspaceOutput struct {
+	Engine        string             `json:"engine"`
+	DeclaredCount int                `json:"declared"`
+	CalledCount   int                `json:"called"`
+	Score         int                `json:"score"`
+	MaxDepth      int                `json:"max_depth"`
+	Modules       []tarpModuleOutput `json:"modules"`
+}
+
+// tarpMatrixOutput is what gets reported for a --matrix run, keyed by configuration. Functions are named by
+// their package, file and name, since the same function can be declared in different files per configuration.
+type tarpMatrixOutput struct {
+	Engine             string                      `json:"engine"`
+	MaxDepth           int                         `json:"max_depth"`
+	Configurations     map[string]tarpModuleOutput `json:"configurations"`
+	UntestedEverywhere []string                    `json:"untested_everywhere"`
+	UntestedInSome     map[string][]string         `json:"untested_in_some"`
+	DeclaredInSome     map[string][]string         `json:"declared_in_some"`
+	Order              []string                    `json:"-"`
+}
+
 type tarpReport struct {
+	Engine          string
 	DeclaredDetails map[string]tarpFunc
 	Called          *set.Set
 	Declared        *set.Set
+	OrphanedTests   []string
 }
 
 type tarpDetails []tarpFunc
@@ -27,6 +105,24 @@ type tarpFunc struct {
 	DeclPos   token.Position
 	RBracePos token.Position
 	LBracePos token.Position
+	Distance  int
+	Credits   []string
+	Cases     []string
+	CaseCount int
+	Specs     []string
+	TestFuncs []string
+	Helpers   []string
+	Ignore    *tarpIgnore
+	Generated bool
+}
+
+// tarpIgnore is the //tarp:ignore or //tarp:ignore-file directive a function is ignored by. Expired and stale
+// directives are flagged in the report, and expired ones no longer leave their functions out of it.
+type tarpIgnore struct {
+	Reason  string `json:"reason,omitempty"`
+	Until   string `json:"until,omitempty"`
+	Expired bool   `json:"expired,omitempty"`
+	Stale   bool   `json:"stale,omitempty"`
 }
 
 func (td tarpDetails) Len() int {
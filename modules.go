@@ -0,0 +1,248 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// goModule is what tarp needs to know about a module to find its packages on disk: the module path
+// and directory from its go.mod file, along with any replace directives that point at local directories.
+type goModule struct {
+	Path    string
+	Dir     string
+	Replace map[string]string
+}
+
+// unquote strips the quotes go.mod allows around module paths and file paths.
+func unquote(s string) string {
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		return unquoted
+	}
+	return s
+}
+
+// addReplacement records a replace directive's fields if the replacement is a local directory.
+// Replacements with other modules live in the module cache, which we have no business analyzing.
+func (m goModule) addReplacement(fields []string) {
+	for i, field := range fields {
+		if field != "=>" || i == 0 || i+1 >= len(fields) {
+			continue
+		}
+		target := unquote(fields[i+1])
+		if !isLocalPath(target) {
+			return
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(m.Dir, target)
+		}
+		m.Replace[unquote(fields[0])] = target
+	}
+}
+
+// goModDirectives splits the contents of a go.mod or go.work file into its directives, one slice of fields
+// per directive, each beginning with its keyword. Directives inside a block are given the block's keyword.
+func goModDirectives(data []byte) [][]string {
+	directives := [][]string{}
+	block := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(strings.Replace(line, "(", " ( ", 1))
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch {
+		case block != "" && fields[0] == ")":
+			block = ""
+		case block != "":
+			directives = append(directives, append([]string{block}, fields...))
+		case len(fields) == 2 && fields[1] == "(":
+			block = fields[0]
+		default:
+			directives = append(directives, fields)
+		}
+	}
+	return directives
+}
+
+// parseGoMod reads the module path and local replace directives out of a go.mod file found in dir.
+func parseGoMod(data []byte, dir string) goModule {
+	mod := goModule{Dir: dir, Replace: map[string]string{}}
+	for _, directive := range goModDirectives(data) {
+		switch {
+		case directive[0] == "module" && len(directive) > 1:
+			mod.Path = unquote(directive[1])
+		case directive[0] == "replace":
+			mod.addReplacement(directive[1:])
+		}
+	}
+	return mod
+}
+
+// findModule looks for the go.mod file governing dir, starting in dir and working up towards the root.
+func findModule(dir string) (goModule, bool) {
+	for {
+		if data, err := ioutil.ReadFile(filepath.Join(dir, "go.mod")); err == nil {
+			return parseGoMod(data, dir), true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return goModule{}, false
+		}
+		dir = parent
+	}
+}
+
+// matchModulePath reports whether importPath belongs to the module at modPath, and if so, returns the
+// remainder of the import path relative to the module root.
+func matchModulePath(importPath, modPath string) (string, bool) {
+	if importPath == modPath {
+		return "", true
+	}
+	if modPath != "" && strings.HasPrefix(importPath, modPath+"/") {
+		return strings.TrimPrefix(importPath, modPath+"/"), true
+	}
+	return "", false
+}
+
+// dirFor returns the directory holding the package at importPath, if the package belongs to the module
+// or to one of the modules it replaces with a local directory.
+func (m goModule) dirFor(importPath string) (string, bool) {
+	if rest, ok := matchModulePath(importPath, m.Path); ok {
+		return filepath.Join(m.Dir, filepath.FromSlash(rest)), true
+	}
+
+	// the longest matching replacement wins, same as it does for the go tool
+	var longest string
+	for modPath := range m.Replace {
+		if _, ok := matchModulePath(importPath, modPath); ok && len(modPath) > len(longest) {
+			longest = modPath
+		}
+	}
+	if longest != "" {
+		rest, _ := matchModulePath(importPath, longest)
+		return filepath.Join(m.Replace[longest], filepath.FromSlash(rest)), true
+	}
+	return "", false
+}
+
+// goWorkspace is the set of modules tied together by a go.work file, along with the workspace's own local
+// replace directives.
+type goWorkspace struct {
+	Dir     string
+	Modules []goModule
+	Replace map[string]string
+}
+
+// parseGoWork reads the modules used by a go.work file found in dir, loading each of their go.mod files.
+// Modules whose go.mod can't be read are skipped, same as they would be if they weren't listed.
+func parseGoWork(data []byte, dir string) goWorkspace {
+	ws := goWorkspace{Dir: dir, Replace: map[string]string{}}
+	replacements := goModule{Dir: dir, Replace: ws.Replace}
+	for _, directive := range goModDirectives(data) {
+		switch {
+		case directive[0] == "use" && len(directive) > 1:
+			modDir := unquote(directive[1])
+			if !filepath.IsAbs(modDir) {
+				modDir = filepath.Join(dir, modDir)
+			}
+			if data, err := ioutil.ReadFile(filepath.Join(modDir, "go.mod")); err == nil {
+				ws.Modules = append(ws.Modules, parseGoMod(data, modDir))
+			}
+		case directive[0] == "replace":
+			replacements.addReplacement(directive[1:])
+		}
+	}
+	return ws
+}
+
+// findWorkspace looks for the go.work file governing dir the same way the go command does: GOWORK names
+// the file to use, or turns workspaces off entirely, and otherwise we search from dir up towards the root.
+func findWorkspace(dir string) (goWorkspace, bool) {
+	switch gowork := os.Getenv("GOWORK"); gowork {
+	case "off":
+		return goWorkspace{}, false
+	case "":
+	default:
+		data, err := ioutil.ReadFile(gowork)
+		if err != nil {
+			return goWorkspace{}, false
+		}
+		return parseGoWork(data, filepath.Dir(gowork)), true
+	}
+
+	for {
+		if data, err := ioutil.ReadFile(filepath.Join(dir, "go.work")); err == nil {
+			return parseGoWork(data, dir), true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return goWorkspace{}, false
+		}
+		dir = parent
+	}
+}
+
+// dirFor returns the directory holding the package at importPath, if the package belongs to one of the
+// workspace's modules, or to a module replaced with a local directory by the workspace or one of its modules.
+func (w goWorkspace) dirFor(importPath string) (string, bool) {
+	// modules in the workspace always win, and the longest module path is the one a package belongs to
+	var owner *goModule
+	for i, mod := range w.Modules {
+		if _, ok := matchModulePath(importPath, mod.Path); ok && (owner == nil || len(mod.Path) > len(owner.Path)) {
+			owner = &w.Modules[i]
+		}
+	}
+	if owner != nil {
+		return owner.dirFor(importPath)
+	}
+
+	// the workspace's replacements take precedence over those of its modules
+	replacements := goModule{Replace: map[string]string{}}
+	for _, mod := range w.Modules {
+		for modPath, dir := range mod.Replace {
+			replacements.Replace[modPath] = dir
+		}
+	}
+	for modPath, dir := range w.Replace {
+		replacements.Replace[modPath] = dir
+	}
+	return replacements.dirFor(importPath)
+}
+
+// resolveImportPath finds the directory holding the package at importPath using the workspace or module
+// that governs fromDir. It returns false when there is neither, or the package doesn't belong to them.
+func resolveImportPath(importPath, fromDir string) (string, bool) {
+	if ws, ok := findWorkspace(fromDir); ok {
+		if dir, ok := ws.dirFor(importPath); ok {
+			return dir, true
+		}
+	}
+
+	mod, ok := findModule(fromDir)
+	if !ok {
+		return "", false
+	}
+	return mod.dirFor(importPath)
+}
+
+// moduleImportPath returns the import path of the package in pkgDir according to the module it belongs to.
+func moduleImportPath(pkgDir string) (string, bool) {
+	mod, ok := findModule(pkgDir)
+	if !ok || mod.Path == "" {
+		return "", false
+	}
+	rel, err := filepath.Rel(mod.Dir, pkgDir)
+	if err != nil {
+		return "", false
+	}
+	if rel == "." {
+		return mod.Path, true
+	}
+	return mod.Path + "/" + filepath.ToSlash(rel), true
+}
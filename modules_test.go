@@ -0,0 +1,422 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fatih/set"
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////
+//                                                    //
+//               Test Helper Functions                //
+//                                                    //
+////////////////////////////////////////////////////////
+
+// buildModuleTree writes a small module outside of GOPATH, with a sibling directory it replaces another
+// module with, and returns the module's root directory.
+func buildModuleTree(t *testing.T) string {
+	t.Helper()
+	root, err := ioutil.TempDir("", "tarp_module")
+	if err != nil {
+		t.Logf("error encountered creating temp directory: %v", err)
+		t.FailNow()
+	}
+
+	files := map[string]string{
+		"mod/go.mod": `module example.com/tarpmod // the module we're analyzing
+
+go 1.12
+
+require example.com/other v1.0.0
+
+replace example.com/other => ../other
+`,
+		"mod/lib/lib.go": `package lib
+
+func Double(x int) int { return x * 2 }
+
+func Triple(x int) int { return x * 3 }
+`,
+		"mod/lib/lib_test.go": `package lib
+
+import "testing"
+
+func TestDouble(t *testing.T) { Double(1) }
+`,
+		"other/go.mod": "module example.com/other\n",
+		"other/pkg/pkg.go": `package pkg
+
+func Other() {}
+`,
+	}
+	for name, contents := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Logf("error encountered creating directory: %v", err)
+			t.FailNow()
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Logf("error encountered creating file: %v", err)
+			t.FailNow()
+		}
+	}
+	return filepath.Join(root, "mod")
+}
+
+////////////////////////////////////////////////////////
+//                                                    //
+//                   Actual Tests                     //
+//                                                    //
+////////////////////////////////////////////////////////
+
+func TestUnquote(t *testing.T) {
+	assert.Equal(t, "example.com/mod", unquote(`"example.com/mod"`))
+	assert.Equal(t, "example.com/mod", unquote("example.com/mod"))
+}
+
+func TestGoModuleAddReplacement(t *testing.T) {
+	mod := goModule{Dir: "/mod", Replace: map[string]string{}}
+
+	mod.addReplacement([]string{"example.com/a", "=>", "../a"})
+	mod.addReplacement([]string{"example.com/b", "v1.0.0", "=>", "/abs/b"})
+	mod.addReplacement([]string{"example.com/c", "=>", "example.com/fork", "v1.2.3"})
+	mod.addReplacement([]string{"=>", "../nothing"})
+
+	expected := map[string]string{
+		"example.com/a": "/a",
+		"example.com/b": "/abs/b",
+	}
+	assert.Equal(t, expected, mod.Replace, "only replacements with local directories should be recorded")
+}
+
+func TestParseGoMod(t *testing.T) {
+	goMod := `
+		module "example.com/mod" // with a comment
+
+		go 1.12
+
+		require (
+			example.com/dep v1.0.0
+		)
+
+		replace example.com/single => ./single
+
+		replace (
+			example.com/block v1.0.0 => ../block
+			example.com/remote => example.com/fork v1.0.0
+		)
+	`
+
+	expected := goModule{
+		Path: "example.com/mod",
+		Dir:  "/mod",
+		Replace: map[string]string{
+			"example.com/single": "/mod/single",
+			"example.com/block":  "/block",
+		},
+	}
+
+	assert.Equal(t, expected, parseGoMod([]byte(goMod), "/mod"))
+}
+
+func TestFindModule(t *testing.T) {
+	modDir := buildModuleTree(t)
+	defer os.RemoveAll(filepath.Dir(modDir))
+
+	found := func(t *testing.T) {
+		mod, ok := findModule(filepath.Join(modDir, "lib"))
+		assert.True(t, ok)
+		assert.Equal(t, "example.com/tarpmod", mod.Path)
+		assert.Equal(t, modDir, mod.Dir)
+	}
+	t.Run("found", found)
+
+	notFound := func(t *testing.T) {
+		_, ok := findModule(buildExamplePackagePath(t, "simple", true))
+		assert.False(t, ok)
+	}
+	t.Run("not found", notFound)
+}
+
+func TestMatchModulePath(t *testing.T) {
+	rest, ok := matchModulePath("example.com/mod", "example.com/mod")
+	assert.True(t, ok)
+	assert.Equal(t, "", rest)
+
+	rest, ok = matchModulePath("example.com/mod/a/b", "example.com/mod")
+	assert.True(t, ok)
+	assert.Equal(t, "a/b", rest)
+
+	_, ok = matchModulePath("example.com/module", "example.com/mod")
+	assert.False(t, ok, "module paths should only match whole path elements")
+
+	_, ok = matchModulePath("example.com/mod", "")
+	assert.False(t, ok)
+}
+
+func TestGoModuleDirFor(t *testing.T) {
+	mod := goModule{
+		Path: "example.com/mod",
+		Dir:  "/mod",
+		Replace: map[string]string{
+			"example.com/other":        "/other",
+			"example.com/other/nested": "/nested",
+		},
+	}
+
+	for importPath, expected := range map[string]string{
+		"example.com/mod":              "/mod",
+		"example.com/mod/pkg":          "/mod/pkg",
+		"example.com/other/pkg":        "/other/pkg",
+		"example.com/other/nested/pkg": "/nested/pkg",
+	} {
+		actual, ok := mod.dirFor(importPath)
+		assert.True(t, ok, "expected %q to be found", importPath)
+		assert.Equal(t, expected, actual)
+	}
+
+	_, ok := mod.dirFor("example.com/elsewhere")
+	assert.False(t, ok)
+}
+
+func TestResolveImportPath(t *testing.T) {
+	modDir := buildModuleTree(t)
+	defer os.RemoveAll(filepath.Dir(modDir))
+
+	actual, ok := resolveImportPath("example.com/tarpmod/lib", modDir)
+	assert.True(t, ok)
+	assert.Equal(t, filepath.Join(modDir, "lib"), actual)
+
+	actual, ok = resolveImportPath("example.com/other/pkg", filepath.Join(modDir, "lib"))
+	assert.True(t, ok)
+	assert.Equal(t, filepath.Join(filepath.Dir(modDir), "other", "pkg"), actual)
+
+	_, ok = resolveImportPath("example.com/tarpmod/lib", buildExamplePackagePath(t, "simple", true))
+	assert.False(t, ok, "packages shouldn't be resolved outside of a module")
+}
+
+func TestModuleImportPath(t *testing.T) {
+	modDir := buildModuleTree(t)
+	defer os.RemoveAll(filepath.Dir(modDir))
+
+	actual, ok := moduleImportPath(modDir)
+	assert.True(t, ok)
+	assert.Equal(t, "example.com/tarpmod", actual)
+
+	actual, ok = moduleImportPath(filepath.Join(modDir, "lib"))
+	assert.True(t, ok)
+	assert.Equal(t, "example.com/tarpmod/lib", actual)
+
+	_, ok = moduleImportPath(buildExamplePackagePath(t, "simple", true))
+	assert.False(t, ok)
+}
+
+func TestModuleAnalysis(t *testing.T) {
+	modDir := buildModuleTree(t)
+	defer os.RemoveAll(filepath.Dir(modDir))
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(modDir)
+
+	actual := analyze("example.com/tarpmod/lib")
+	assert.Equal(t, set.New("lib.go:Double"), actual.Called)
+	assert.Equal(t, set.New("lib.go:Double", "lib.go:Triple"), actual.Declared)
+
+	assert.Equal(t, []string{filepath.Join(modDir, "lib")}, expandPatterns([]string{"example.com/tarpmod/..."}))
+	assert.Equal(t, "example.com/tarpmod/lib", packageName(filepath.Join(modDir, "lib")))
+}
+
+func TestGoModDirectives(t *testing.T) {
+	data := `
+		module example.com/mod // trailing comment
+		// a comment on its own
+
+		require (
+			example.com/a v1.0.0
+			example.com/b v1.0.0 // indirect
+		)
+		replace(
+			example.com/c => ../c
+		)
+		replace example.com/d => ../d
+	`
+
+	expected := [][]string{
+		{"module", "example.com/mod"},
+		{"require", "example.com/a", "v1.0.0"},
+		{"require", "example.com/b", "v1.0.0"},
+		{"replace", "example.com/c", "=>", "../c"},
+		{"replace", "example.com/d", "=>", "../d"},
+	}
+
+	assert.Equal(t, expected, goModDirectives([]byte(data)))
+}
+
+////////////////////////////////////////////////////////
+//                                                    //
+//                    Workspaces                      //
+//                                                    //
+////////////////////////////////////////////////////////
+
+// buildWorkspaceTree writes a workspace with two modules, one of which imports the other, and returns the
+// workspace's root directory.
+func buildWorkspaceTree(t *testing.T) string {
+	t.Helper()
+	root, err := ioutil.TempDir("", "tarp_workspace")
+	if err != nil {
+		t.Logf("error encountered creating temp directory: %v", err)
+		t.FailNow()
+	}
+
+	files := map[string]string{
+		"go.work": `go 1.18
+
+use (
+	./lib
+	"./svc"
+	./missing
+)
+
+replace example.com/replaced => ./replaced
+`,
+		"lib/go.mod": "module example.com/lib\n",
+		"lib/lib.go": `package lib
+
+func Double(x int) int { return x * 2 }
+
+func Triple(x int) int { return x * 3 }
+`,
+		"lib/lib_test.go": `package lib
+
+import "testing"
+
+func TestDouble(t *testing.T) { Double(1) }
+`,
+		"svc/go.mod": "module example.com/svc\n\nrequire example.com/lib v0.0.0\n",
+		"svc/svc.go": `package svc
+
+import "example.com/lib"
+
+type Server struct{}
+
+func (s Server) Handle() int { return lib.Double(2) }
+
+func New() Server { return Server{} }
+`,
+		"svc/svc_test.go": `package svc
+
+import "testing"
+
+func TestHandle(t *testing.T) { New().Handle() }
+`,
+	}
+	for name, contents := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Logf("error encountered creating directory: %v", err)
+			t.FailNow()
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Logf("error encountered creating file: %v", err)
+			t.FailNow()
+		}
+	}
+	return root
+}
+
+func TestParseGoWork(t *testing.T) {
+	root := buildWorkspaceTree(t)
+	defer os.RemoveAll(root)
+	data, _ := ioutil.ReadFile(filepath.Join(root, "go.work"))
+
+	actual := parseGoWork(data, root)
+
+	assert.Equal(t, root, actual.Dir)
+	assert.Len(t, actual.Modules, 2, "modules without a go.mod should be skipped")
+	assert.Equal(t, "example.com/lib", actual.Modules[0].Path)
+	assert.Equal(t, filepath.Join(root, "svc"), actual.Modules[1].Dir)
+	assert.Equal(t, map[string]string{"example.com/replaced": filepath.Join(root, "replaced")}, actual.Replace)
+}
+
+func TestFindWorkspace(t *testing.T) {
+	root := buildWorkspaceTree(t)
+	defer os.RemoveAll(root)
+	originalGOWORK := os.Getenv("GOWORK")
+	defer os.Setenv("GOWORK", originalGOWORK)
+
+	found := func(t *testing.T) {
+		os.Setenv("GOWORK", "")
+		ws, ok := findWorkspace(filepath.Join(root, "svc"))
+		assert.True(t, ok)
+		assert.Equal(t, root, ws.Dir)
+	}
+	t.Run("found", found)
+
+	notFound := func(t *testing.T) {
+		os.Setenv("GOWORK", "")
+		_, ok := findWorkspace(buildExamplePackagePath(t, "simple", true))
+		assert.False(t, ok)
+	}
+	t.Run("not found", notFound)
+
+	off := func(t *testing.T) {
+		os.Setenv("GOWORK", "off")
+		_, ok := findWorkspace(filepath.Join(root, "svc"))
+		assert.False(t, ok)
+	}
+	t.Run("GOWORK=off", off)
+
+	explicit := func(t *testing.T) {
+		os.Setenv("GOWORK", filepath.Join(root, "go.work"))
+		ws, ok := findWorkspace(buildExamplePackagePath(t, "simple", true))
+		assert.True(t, ok)
+		assert.Equal(t, root, ws.Dir)
+
+		os.Setenv("GOWORK", filepath.Join(root, "nonexistent.work"))
+		_, ok = findWorkspace(buildExamplePackagePath(t, "simple", true))
+		assert.False(t, ok)
+	}
+	t.Run("explicit GOWORK", explicit)
+}
+
+func TestGoWorkspaceDirFor(t *testing.T) {
+	ws := goWorkspace{
+		Modules: []goModule{
+			{Path: "example.com/a", Dir: "/a", Replace: map[string]string{"example.com/r": "/from-a", "example.com/s": "/s"}},
+			{Path: "example.com/a/nested", Dir: "/nested", Replace: map[string]string{}},
+		},
+		Replace: map[string]string{"example.com/r": "/from-workspace"},
+	}
+
+	for importPath, expected := range map[string]string{
+		"example.com/a/pkg":        "/a/pkg",
+		"example.com/a/nested/pkg": "/nested/pkg",
+		"example.com/r/pkg":        "/from-workspace/pkg",
+		"example.com/s":            "/s",
+	} {
+		actual, ok := ws.dirFor(importPath)
+		assert.True(t, ok, "expected %q to be found", importPath)
+		assert.Equal(t, expected, actual)
+	}
+
+	_, ok := ws.dirFor("example.com/elsewhere")
+	assert.False(t, ok)
+	assert.Equal(t, map[string]string{"example.com/r": "/from-workspace"}, ws.Replace, "the workspace's replacements shouldn't be modified")
+}
+
+func TestWorkspaceResolveImportPath(t *testing.T) {
+	root := buildWorkspaceTree(t)
+	defer os.RemoveAll(root)
+
+	actual, ok := resolveImportPath("example.com/lib", filepath.Join(root, "svc"))
+	assert.True(t, ok, "sibling modules should be resolved through the workspace")
+	assert.Equal(t, filepath.Join(root, "lib"), actual)
+
+	actual, ok = resolveImportPath("example.com/svc", filepath.Join(root, "svc"))
+	assert.True(t, ok)
+	assert.Equal(t, filepath.Join(root, "svc"), actual)
+}
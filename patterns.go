@@ -0,0 +1,123 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// isLocalPath reports whether a package argument refers to a directory on disk rather than an import path.
+func isLocalPath(pkg string) bool {
+	return pkg == "." || pkg == ".." || strings.HasPrefix(pkg, "./") || strings.HasPrefix(pkg, "../") || filepath.IsAbs(pkg)
+}
+
+// packageDir returns the directory a package argument refers to. Local paths are resolved against the
+// current directory, and everything else is treated as an import path, found through the go.mod governing
+// the current directory if there is one, or beneath $GOPATH/src otherwise.
+func packageDir(pkg string) string {
+	if isLocalPath(pkg) {
+		if filepath.IsAbs(pkg) {
+			return filepath.Clean(pkg)
+		}
+		wd, err := os.Getwd()
+		if err != nil {
+			fatalf(exitLoadError, "error encountered getting current working directory: %v", err)
+		}
+		return filepath.Join(wd, pkg)
+	}
+	if wd, err := os.Getwd(); err == nil {
+		if dir, ok := resolveImportPath(pkg, wd); ok {
+			return dir
+		}
+	}
+	return strings.Join([]string{os.Getenv("GOPATH"), "src", pkg}, "/")
+}
+
+// packageName returns the name a package directory is reported under: its import path when it belongs to
+// a module or lives in $GOPATH/src, or its path relative to the current directory otherwise, which is "." for
+// the current directory itself.
+func packageName(pkgDir string) string {
+	if importPath, ok := moduleImportPath(pkgDir); ok {
+		return importPath
+	}
+	gopathSrc := filepath.Join(os.Getenv("GOPATH"), "src") + string(filepath.Separator)
+	if strings.HasPrefix(pkgDir, gopathSrc) {
+		return filepath.ToSlash(strings.TrimPrefix(pkgDir, gopathSrc))
+	}
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, pkgDir); err == nil && !strings.HasPrefix(rel, "..") {
+			if rel == "." {
+				return rel
+			}
+			return "./" + filepath.ToSlash(rel)
+		}
+	}
+	return pkgDir
+}
+
+// skipDir reports whether a directory found while expanding a "..." pattern should be left out, along with
+// everything beneath it. The go tool ignores the same directories.
+func skipDir(name string) bool {
+	return name == "vendor" || name == "testdata" || strings.HasPrefix(name, "_") || strings.HasPrefix(name, ".")
+}
+
+// hasGoFiles reports whether dir directly contains any non-test Go source files that match the build context.
+func hasGoFiles(dir string) bool {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	matches := fileFilter(dir)
+	for _, f := range files {
+		if !f.IsDir() && !strings.HasSuffix(f.Name(), "_test.go") && matches(f) {
+			return true
+		}
+	}
+	return false
+}
+
+// isModuleRoot reports whether dir has a go.mod file in it.
+func isModuleRoot(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, "go.mod"))
+	return err == nil
+}
+
+// expandPatterns turns package arguments into a sorted list of package directories. A pattern ending in
+// "/..." matches the directory it names and every directory beneath it that has Go files in it, stopping
+// at nested modules the same way the go command does.
+func expandPatterns(patterns []string) []string {
+	dirs := map[string]bool{}
+	for _, pattern := range patterns {
+		if pattern != "..." && !strings.HasSuffix(pattern, "/...") {
+			dirs[packageDir(pattern)] = true
+			continue
+		}
+
+		root := strings.TrimSuffix(strings.TrimSuffix(pattern, "..."), "/")
+		if root == "" {
+			root = "."
+		}
+		rootDir := packageDir(root)
+		filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || !info.IsDir() {
+				return nil
+			}
+			if path != rootDir && (skipDir(info.Name()) || isModuleRoot(path)) {
+				return filepath.SkipDir
+			}
+			if hasGoFiles(path) {
+				dirs[path] = true
+			}
+			return nil
+		})
+	}
+
+	out := []string{}
+	for dir := range dirs {
+		out = append(out, dir)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bouk/monkey"
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////
+//                                                    //
+//               Test Helper Functions                //
+//                                                    //
+////////////////////////////////////////////////////////
+
+func buildPatternTree(t *testing.T) string {
+	t.Helper()
+	root, err := ioutil.TempDir("", "tarp_patterns")
+	if err != nil {
+		t.Logf("error encountered creating temp directory: %v", err)
+		t.FailNow()
+	}
+
+	files := []string{
+		"main.go",
+		"a/a.go",
+		"a/b/b.go",
+		"a/b/b_test.go",
+		"only_tests/x_test.go",
+		"vendor/v/v.go",
+		"testdata/td.go",
+		"_ignored/i.go",
+		".hidden/h.go",
+		"no_go_files/README.md",
+	}
+	for _, f := range files {
+		path := filepath.Join(root, f)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Logf("error encountered creating directory: %v", err)
+			t.FailNow()
+		}
+		if err := ioutil.WriteFile(path, []byte("package x\n"), 0644); err != nil {
+			t.Logf("error encountered creating file: %v", err)
+			t.FailNow()
+		}
+	}
+	return root
+}
+
+////////////////////////////////////////////////////////
+//                                                    //
+//                   Actual Tests                     //
+//                                                    //
+////////////////////////////////////////////////////////
+
+func TestIsLocalPath(t *testing.T) {
+	for _, pkg := range []string{".", "..", "./a", "../a", "/abs/path"} {
+		assert.True(t, isLocalPath(pkg), "expected %q to be a local path", pkg)
+	}
+	for _, pkg := range []string{"github.com/a/b", "fmt", ".hidden"} {
+		assert.False(t, isLocalPath(pkg), "expected %q to be an import path", pkg)
+	}
+}
+
+func TestPackageDir(t *testing.T) {
+	wd, _ := os.Getwd()
+
+	current := func(t *testing.T) {
+		assert.Equal(t, wd, packageDir("."))
+	}
+	t.Run("current directory", current)
+
+	relative := func(t *testing.T) {
+		assert.Equal(t, filepath.Join(wd, "example_packages", "simple"), packageDir("./example_packages/simple"))
+	}
+	t.Run("relative path", relative)
+
+	absolute := func(t *testing.T) {
+		assert.Equal(t, "/abs/path", packageDir("/abs/path/"))
+	}
+	t.Run("absolute path", absolute)
+
+	importPath := func(t *testing.T) {
+		assert.Equal(t, buildExamplePackagePath(t, "simple", true), packageDir(buildExamplePackagePath(t, "simple", false)))
+	}
+	t.Run("import path", importPath)
+
+	modulePath := func(t *testing.T) {
+		modDir := buildModuleTree(t)
+		defer os.RemoveAll(filepath.Dir(modDir))
+		defer os.Chdir(wd)
+		os.Chdir(modDir)
+
+		assert.Equal(t, filepath.Join(modDir, "lib"), packageDir("example.com/tarpmod/lib"))
+		assert.Equal(t, filepath.Join(filepath.Dir(modDir), "other", "pkg"), packageDir("example.com/other/pkg"), "local replacements should be followed")
+	}
+	t.Run("module path", modulePath)
+
+	directoryWoes := func(t *testing.T) {
+		monkey.Patch(os.Getwd, func() (string, error) {
+			return "", errors.New("pineapple on pizza")
+		})
+		defer monkey.Unpatch(os.Getwd)
+
+		var fatalfCalled bool
+		defer func() {
+			// recovered from our monkey patched log.Fatalf
+			if r := recover(); r != nil {
+				fatalfCalled = true
+			}
+			assert.True(t, fatalfCalled, "packageDir should call log.Fatalf() when it can't retrieve the current directory")
+		}()
+
+		packageDir(".")
+	}
+	t.Run("current directory unavailable", directoryWoes)
+}
+
+func TestPackageName(t *testing.T) {
+	gopath := func(t *testing.T) {
+		assert.Equal(t, buildExamplePackagePath(t, "simple", false), packageName(buildExamplePackagePath(t, "simple", true)))
+	}
+	t.Run("in GOPATH", gopath)
+
+	relative := func(t *testing.T) {
+		root := buildPatternTree(t)
+		defer os.RemoveAll(root)
+		wd, _ := os.Getwd()
+		defer os.Chdir(wd)
+		os.Chdir(root)
+
+		assert.Equal(t, "./a/b", packageName(filepath.Join(root, "a", "b")))
+		assert.Equal(t, ".", packageName(root), "the current directory should be named like it is on the command line")
+		assert.Equal(t, "./main.go:flush", matrixFuncName(packageName(root), "main.go:flush"))
+	}
+	t.Run("beneath the current directory", relative)
+
+	elsewhere := func(t *testing.T) {
+		assert.Equal(t, "/somewhere/else", packageName("/somewhere/else"))
+	}
+	t.Run("elsewhere", elsewhere)
+}
+
+func TestSkipDir(t *testing.T) {
+	for _, name := range []string{"vendor", "testdata", "_ignored", ".git"} {
+		assert.True(t, skipDir(name), "expected %q to be skipped", name)
+	}
+	for _, name := range []string{"pkg", "internal", "vendored"} {
+		assert.False(t, skipDir(name), "expected %q not to be skipped", name)
+	}
+}
+
+func TestHasGoFiles(t *testing.T) {
+	root := buildPatternTree(t)
+	defer os.RemoveAll(root)
+
+	assert.True(t, hasGoFiles(root))
+	assert.True(t, hasGoFiles(filepath.Join(root, "a", "b")))
+	assert.False(t, hasGoFiles(filepath.Join(root, "only_tests")), "directories with only tests shouldn't count")
+	assert.False(t, hasGoFiles(filepath.Join(root, "no_go_files")))
+	assert.False(t, hasGoFiles(filepath.Join(root, "absolutelynosuchdirectory")))
+
+	windowsOnly := filepath.Join(root, "windows_only")
+	os.MkdirAll(windowsOnly, 0755)
+	ioutil.WriteFile(filepath.Join(windowsOnly, "w_windows.go"), []byte("package windows_only\n"), 0644)
+
+	targetOS = "linux"
+	defer func() { targetOS = "" }()
+	assert.False(t, hasGoFiles(windowsOnly), "files excluded by the build context shouldn't count")
+	targetOS = "windows"
+	assert.True(t, hasGoFiles(windowsOnly))
+}
+
+func TestIsModuleRoot(t *testing.T) {
+	root := buildWorkspaceTree(t)
+	defer os.RemoveAll(root)
+
+	assert.True(t, isModuleRoot(filepath.Join(root, "lib")))
+	assert.False(t, isModuleRoot(root))
+}
+
+func TestExpandPatterns(t *testing.T) {
+	root := buildPatternTree(t)
+	defer os.RemoveAll(root)
+
+	recursive := func(t *testing.T) {
+		expected := []string{root, filepath.Join(root, "a"), filepath.Join(root, "a", "b")}
+		assert.Equal(t, expected, expandPatterns([]string{root + "/..."}), "vendor, testdata, and _ or . prefixed directories should be skipped")
+	}
+	t.Run("recursive", recursive)
+
+	subtree := func(t *testing.T) {
+		expected := []string{filepath.Join(root, "a"), filepath.Join(root, "a", "b")}
+		assert.Equal(t, expected, expandPatterns([]string{root + "/a/..."}))
+	}
+	t.Run("subtree", subtree)
+
+	multiple := func(t *testing.T) {
+		expected := []string{root, filepath.Join(root, "a", "b")}
+		assert.Equal(t, expected, expandPatterns([]string{root + "/a/b", root, root + "/a/b"}), "duplicate packages should only be reported once")
+	}
+	t.Run("multiple arguments", multiple)
+
+	relative := func(t *testing.T) {
+		wd, _ := os.Getwd()
+		defer os.Chdir(wd)
+		os.Chdir(root)
+
+		assert.Equal(t, []string{filepath.Join(root, "a"), filepath.Join(root, "a", "b")}, expandPatterns([]string{"./a/..."}))
+		assert.Len(t, expandPatterns([]string{"./..."}), 3)
+		assert.Len(t, expandPatterns([]string{"..."}), 3)
+	}
+	t.Run("relative", relative)
+
+	importPath := func(t *testing.T) {
+		actual := expandPatterns([]string{buildExamplePackagePath(t, "...", false)})
+		assert.Contains(t, actual, buildExamplePackagePath(t, "simple", true))
+		assert.NotContains(t, actual, buildExamplePackagePath(t, "no_go_files", true))
+	}
+	t.Run("import path", importPath)
+
+	nestedModules := func(t *testing.T) {
+		ws := buildWorkspaceTree(t)
+		defer os.RemoveAll(ws)
+		ioutil.WriteFile(filepath.Join(ws, "root.go"), []byte("package root\n"), 0644)
+
+		assert.Equal(t, []string{ws}, expandPatterns([]string{ws + "/..."}), "nested modules should be left out")
+		assert.Equal(t, []string{filepath.Join(ws, "lib")}, expandPatterns([]string{ws + "/lib/..."}))
+	}
+	t.Run("nested modules", nestedModules)
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"github.com/fatih/set"
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// suiteImportPath is the import path of testify's suite package. Suites are structs that embed its Suite
+// type, and their Test methods are run by suite.Run.
+const suiteImportPath = "github.com/stretchr/testify/suite"
+
+// isSuiteHook reports whether a method with the given name is one testify runs around a suite's tests.
+func isSuiteHook(name string) bool {
+	switch name {
+	case "SetupSuite", "SetupTest", "SetupSubTest", "BeforeTest", "AfterTest", "TearDownTest", "TearDownSubTest", "TearDownSuite":
+		return true
+	}
+	return false
+}
+
+// isSuiteSetup reports whether a method with the given name is a hook that testify runs before a suite's
+// tests, where the suite's fields are usually set up.
+func isSuiteSetup(name string) bool {
+	return isSuiteHook(name) && (strings.HasPrefix(name, "Setup") || name == "BeforeTest")
+}
+
+// receiverTypeName returns the name of the type a method is declared on, or "" for plain functions.
+func receiverTypeName(f *ast.FuncDecl) string {
+	if name := parseFuncDecl(f); name != f.Name.Name {
+		return strings.TrimSuffix(name, "."+f.Name.Name)
+	}
+	return ""
+}
+
+// findSuiteTypes adds the names of the struct types declared in the given file that embed testify's
+// suite.Suite, or another suite declared before them, to suites.
+func findSuiteTypes(in *ast.File, suites *set.Set) {
+	suitePkgs := set.New()
+	for _, name := range importedNames(in, suiteImportPath, "suite") {
+		suitePkgs.Add(name)
+	}
+
+	for _, d := range in.Decls {
+		gd, ok := d.(*ast.GenDecl)
+		if !ok {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts, ok := spec.(*ast.TypeSpec)
+			if !ok {
+				continue
+			}
+			st, ok := ts.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+			for _, field := range st.Fields.List {
+				if len(field.Names) > 0 {
+					continue
+				}
+				embedded := field.Type
+				if star, ok := embedded.(*ast.StarExpr); ok {
+					embedded = star.X
+				}
+				switch e := embedded.(type) {
+				case *ast.SelectorExpr:
+					if pkg, ok := e.X.(*ast.Ident); ok && suitePkgs.Has(pkg.Name) && e.Sel.Name == "Suite" {
+						suites.Add(ts.Name.Name)
+					}
+				case *ast.Ident:
+					if suites.Has(e.Name) {
+						suites.Add(ts.Name.Name)
+					}
+				}
+			}
+		}
+	}
+}
+
+// recordSuiteFields records the types of the values assigned to the fields of suites in their setup hooks,
+// so that a field declared as an interface resolves to the implementation the suite actually tests.
+// (handles hooks like `func (s *StoreSuite) SetupTest() { s.store = newMemStore() }`)
+func recordSuiteFields(in *ast.File, suites *set.Set, nameToTypeMap map[string]string) {
+	for _, d := range in.Decls {
+		f, ok := d.(*ast.FuncDecl)
+		if !ok || f.Body == nil || f.Recv == nil || len(f.Recv.List[0].Names) == 0 || !isSuiteSetup(f.Name.Name) {
+			continue
+		}
+		suiteName := receiverTypeName(f)
+		if !suites.Has(suiteName) {
+			continue
+		}
+
+		recv := f.Recv.List[0].Names[0].Name
+		ast.Inspect(f.Body, func(n ast.Node) bool {
+			if assign, ok := n.(*ast.AssignStmt); ok && len(assign.Lhs) == len(assign.Rhs) {
+				for i, lhs := range assign.Lhs {
+					sel, ok := lhs.(*ast.SelectorExpr)
+					if !ok {
+						continue
+					}
+					if x, ok := sel.X.(*ast.Ident); ok && x.Name == recv {
+						if t := exprType(assign.Rhs[i], nameToTypeMap); t != "" {
+							nameToTypeMap[suiteName+"."+sel.Sel.Name] = t
+						}
+					}
+				}
+			}
+			return true
+		})
+	}
+}
+
+// isSuite reports whether t, or the type it points to, is a testify suite: a struct type that embeds
+// suite.Suite, directly or through another suite.
+func isSuite(t types.Type) bool {
+	named := namedTypeOf(t)
+	if named == nil {
+		return false
+	}
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return false
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		field := st.Field(i)
+		if !field.Embedded() {
+			continue
+		}
+		// vendored copies of testify have their path prefixed by the vendor directory
+		if e := namedTypeOf(field.Type()); e != nil && e.Obj().Pkg() != nil && e.Obj().Name() == "Suite" && strings.HasSuffix(e.Obj().Pkg().Path(), suiteImportPath) {
+			return true
+		}
+		if isSuite(field.Type()) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSuiteMethod reports whether fn is a method of a testify suite.
+func isSuiteMethod(fn *types.Func) bool {
+	sig, ok := fn.Type().(*types.Signature)
+	return ok && sig.Recv() != nil && isSuite(sig.Recv().Type())
+}
+
+// suiteFields returns the concrete types assigned to the interface-typed fields of suites in their setup
+// hooks, throughout the given files.
+func suiteFields(files []*ast.File, info *types.Info) map[*types.Var]*types.Named {
+	fields := map[*types.Var]*types.Named{}
+	for _, f := range files {
+		for _, d := range f.Decls {
+			decl, ok := d.(*ast.FuncDecl)
+			if !ok || decl.Body == nil || !isSuiteSetup(decl.Name.Name) {
+				continue
+			}
+			if fn, ok := info.Defs[decl.Name].(*types.Func); !ok || !isSuiteMethod(fn) {
+				continue
+			}
+
+			ast.Inspect(decl.Body, func(n ast.Node) bool {
+				if assign, ok := n.(*ast.AssignStmt); ok && len(assign.Lhs) == len(assign.Rhs) {
+					for i, lhs := range assign.Lhs {
+						sel, ok := lhs.(*ast.SelectorExpr)
+						if !ok {
+							continue
+						}
+						selection, ok := info.Selections[sel]
+						if !ok || selection.Kind() != types.FieldVal || !types.IsInterface(selection.Obj().Type()) {
+							continue
+						}
+						if named := namedTypeOf(info.Types[assign.Rhs[i]].Type); named != nil && !types.IsInterface(named) {
+							fields[selection.Obj().(*types.Var)] = named
+						}
+					}
+				}
+				return true
+			})
+		}
+	}
+	return fields
+}
+
+// suiteCalls returns the methods that calls through the interface-typed fields of a suite end up in, given
+// the types assigned to those fields by suiteFields.
+// (handles calls like `s.store.Get("key")`, where store is a Store set to a *memStore in SetupTest)
+func suiteCalls(decl *ast.FuncDecl, info *types.Info, fields map[*types.Var]*types.Named) []*types.Func {
+	out := []*types.Func{}
+	if decl.Body == nil || len(fields) == 0 {
+		return out
+	}
+
+	ast.Inspect(decl.Body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		callee := calleeOf(call, info)
+		if callee == nil || !isInterfaceMethod(callee) {
+			return true
+		}
+		fun, ok := calleeExpr(call).(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		field, ok := astutil.Unparen(fun.X).(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if selection, ok := info.Selections[field]; ok && selection.Kind() == types.FieldVal {
+			if named, ok := fields[selection.Obj().(*types.Var)]; ok {
+				obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(named), true, callee.Pkg(), callee.Name())
+				if method, ok := obj.(*types.Func); ok {
+					out = append(out, method.Origin())
+				}
+			}
+		}
+		return true
+	})
+	return out
+}
+
+// getSuiteCalledNames adds the methods belonging to pkgPath that the given file's functions reach through the
+// interface-typed fields of suites to out.
+func getSuiteCalledNames(in *ast.File, info *types.Info, pkgPath string, fields map[*types.Var]*types.Named, out *set.Set) {
+	for _, d := range in.Decls {
+		if f, ok := d.(*ast.FuncDecl); ok {
+			for _, fn := range suiteCalls(f, info, fields) {
+				if fn.Pkg() != nil && fn.Pkg().Path() == pkgPath {
+					out.Add(qualifiedFuncName(fn))
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+	"testing"
+
+	"github.com/fatih/set"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/go/loader"
+)
+
+////////////////////////////////////////////////////////
+//                                                    //
+//               Test Helper Functions                //
+//                                                    //
+////////////////////////////////////////////////////////
+
+// loadSuitesPackage type-checks the suites example package, along with its tests.
+func loadSuitesPackage(t *testing.T) (*loader.PackageInfo, string) {
+	t.Helper()
+	prog, importPath, err := loadPackage(buildExamplePackagePath(t, "suites", true))
+	if err != nil {
+		t.Logf("failing because loadPackage returned error: %v", err)
+		t.FailNow()
+	}
+	return prog.InitialPackages()[0], importPath
+}
+
+// findFuncDecl returns the declaration of the function or method with the given name, as parseFuncDecl names it.
+func findFuncDecl(files []*ast.File, name string) *ast.FuncDecl {
+	for _, f := range files {
+		for _, d := range f.Decls {
+			if decl, ok := d.(*ast.FuncDecl); ok && parseFuncDecl(decl) == name {
+				return decl
+			}
+		}
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////
+//                                                    //
+//                   Actual Tests                     //
+//                                                    //
+////////////////////////////////////////////////////////
+
+func TestIsSuiteHook(t *testing.T) {
+	assert.True(t, isSuiteHook("SetupTest"))
+	assert.True(t, isSuiteHook("TearDownSuite"))
+	assert.False(t, isSuiteHook("TestSetup"))
+}
+
+func TestIsSuiteSetup(t *testing.T) {
+	assert.True(t, isSuiteSetup("SetupSuite"))
+	assert.True(t, isSuiteSetup("BeforeTest"))
+	assert.False(t, isSuiteSetup("TearDownTest"))
+	assert.False(t, isSuiteSetup("SetupStore"), "only the hooks testify calls should count")
+}
+
+func TestReceiverTypeName(t *testing.T) {
+	p := parseChunkOfCode(t, `
+		package main
+		func (s *StoreSuite) TestGet() {}
+		func (l List[T]) Len() int { return 0 }
+		func function() {}
+	`)
+
+	assert.Equal(t, "StoreSuite", receiverTypeName(p.Decls[0].(*ast.FuncDecl)))
+	assert.Equal(t, "List", receiverTypeName(p.Decls[1].(*ast.FuncDecl)))
+	assert.Equal(t, "", receiverTypeName(p.Decls[2].(*ast.FuncDecl)))
+}
+
+func TestFindSuiteTypes(t *testing.T) {
+	imported := func(t *testing.T) {
+		p := parseChunkOfCode(t, `
+			package main
+			import "github.com/stretchr/testify/suite"
+			type StoreSuite struct {
+				suite.Suite
+				store Store
+			}
+			type PointerSuite struct{ *suite.Suite }
+			type NestedSuite struct{ StoreSuite }
+			type notASuite struct{ Suite suite.Suite }
+		`)
+
+		actual := set.New()
+		findSuiteTypes(p, actual)
+		assert.Equal(t, set.New("StoreSuite", "PointerSuite", "NestedSuite"), actual)
+	}
+	t.Run("imported", imported)
+
+	renamed := func(t *testing.T) {
+		p := parseChunkOfCode(t, `
+			package main
+			import testsuite "github.com/stretchr/testify/suite"
+			type StoreSuite struct{ testsuite.Suite }
+			type OtherSuite struct{ other.Suite }
+		`)
+
+		actual := set.New()
+		findSuiteTypes(p, actual)
+		assert.Equal(t, set.New("StoreSuite"), actual)
+	}
+	t.Run("renamed import", renamed)
+}
+
+func TestRecordSuiteFields(t *testing.T) {
+	p := parseChunkOfCode(t, `
+		package main
+		func (s *StoreSuite) SetupTest() {
+			s.store = newMemStore()
+			s.count = 0
+		}
+		func (s *StoreSuite) TestGet() {
+			s.store = other()
+		}
+		func (o *other) SetupTest() {
+			o.store = newMemStore()
+		}
+	`)
+
+	nameToTypeMap := map[string]string{"newMemStore()": "memStore", "other()": "other"}
+	recordSuiteFields(p, set.New("StoreSuite"), nameToTypeMap)
+
+	assert.Equal(t, "memStore", nameToTypeMap["StoreSuite.store"])
+	assert.NotContains(t, nameToTypeMap, "StoreSuite.count")
+	assert.NotContains(t, nameToTypeMap, "other.store", "only suites should have their fields recorded")
+}
+
+func TestIsSuite(t *testing.T) {
+	pkgInfo, _ := loadSuitesPackage(t)
+
+	assert.True(t, isSuite(pkgInfo.Pkg.Scope().Lookup("StoreSuite").Type()))
+	assert.True(t, isSuite(types.NewPointer(pkgInfo.Pkg.Scope().Lookup("StoreSuite").Type())))
+	assert.False(t, isSuite(pkgInfo.Pkg.Scope().Lookup("Cache").Type()))
+	assert.False(t, isSuite(types.Typ[types.Int]))
+}
+
+func TestIsSuiteMethod(t *testing.T) {
+	pkgInfo, _ := loadSuitesPackage(t)
+	suiteType := pkgInfo.Pkg.Scope().Lookup("StoreSuite").Type()
+
+	testPut, _, _ := types.LookupFieldOrMethod(suiteType, true, pkgInfo.Pkg, "TestPut")
+	assert.True(t, isSuiteMethod(testPut.(*types.Func)))
+	lookup, _, _ := types.LookupFieldOrMethod(pkgInfo.Pkg.Scope().Lookup("Cache").Type(), true, pkgInfo.Pkg, "Lookup")
+	assert.False(t, isSuiteMethod(lookup.(*types.Func)))
+	assert.False(t, isSuiteMethod(pkgInfo.Pkg.Scope().Lookup("NewCache").(*types.Func)))
+}
+
+func TestSuiteFields(t *testing.T) {
+	pkgInfo, _ := loadSuitesPackage(t)
+
+	actual := map[string]string{}
+	for field, named := range suiteFields(pkgInfo.Files, &pkgInfo.Info) {
+		actual[field.Name()] = named.Obj().Name()
+	}
+	assert.Equal(t, map[string]string{"store": "memStore"}, actual, "only fields declared as interfaces need to be resolved")
+}
+
+func TestSuiteCalls(t *testing.T) {
+	pkgInfo, _ := loadSuitesPackage(t)
+	fields := suiteFields(pkgInfo.Files, &pkgInfo.Info)
+
+	actual := suiteCalls(findFuncDecl(pkgInfo.Files, "StoreSuite.TestPut"), &pkgInfo.Info, fields)
+	assert.Equal(t, set.New("memStore.Put", "memStore.Get"), funcNames(actual))
+
+	actual = suiteCalls(findFuncDecl(pkgInfo.Files, "StoreSuite.TestPut"), &pkgInfo.Info, map[*types.Var]*types.Named{})
+	assert.Empty(t, actual)
+}
+
+func TestGetSuiteCalledNames(t *testing.T) {
+	pkgInfo, importPath := loadSuitesPackage(t)
+	fields := suiteFields(pkgInfo.Files, &pkgInfo.Info)
+
+	actual := set.New()
+	for _, f := range pkgInfo.Files {
+		getSuiteCalledNames(f, &pkgInfo.Info, importPath, fields, actual)
+	}
+	assert.Equal(t, set.New("memStore.Put", "memStore.Get"), actual)
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/set"
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// tableTest is a table-driven test: a loop ranging over a slice or map literal of test cases. Cases holds
+// the name of every case in the table, or "" for cases without one.
+type tableTest struct {
+	loop  *ast.RangeStmt
+	cases []string
+}
+
+// tableCoverage is what the table-driven tests that exercise a single function have in common.
+type tableCoverage struct {
+	names   *set.Set
+	largest int
+}
+
+// isTable reports whether a composite literal looks like a table of test cases, which is to say a slice or
+// map literal with at least one element, every one of which is itself a composite literal.
+func isTable(lit *ast.CompositeLit) bool {
+	switch lit.Type.(type) {
+	case *ast.ArrayType, *ast.MapType:
+	default:
+		return false
+	}
+	if len(lit.Elts) == 0 {
+		return false
+	}
+	for _, elt := range lit.Elts {
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			elt = kv.Value
+		}
+		if _, ok := elt.(*ast.CompositeLit); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// stringLit returns the value of a string literal, and whether the expression was one.
+func stringLit(in ast.Expr) (string, bool) {
+	if lit, ok := in.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+		if s, err := strconv.Unquote(lit.Value); err == nil {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+// caseNames returns the name of each case in a table. Map tables are named by their keys, and slice tables
+// by the name field of each case, if it has one.
+func caseNames(table *ast.CompositeLit) []string {
+	names := []string{}
+	for _, elt := range table.Elts {
+		name := ""
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			name, _ = stringLit(kv.Key)
+		} else if tc, ok := elt.(*ast.CompositeLit); ok {
+			for _, field := range tc.Elts {
+				if kv, ok := field.(*ast.KeyValueExpr); ok {
+					if key, ok := kv.Key.(*ast.Ident); ok && strings.EqualFold(key.Name, "name") {
+						name, _ = stringLit(kv.Value)
+					}
+				}
+			}
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// recordTables adds the tables assigned to variables by the given node to tables, keyed by variable name.
+func recordTables(n ast.Node, tables map[string]*ast.CompositeLit) {
+	switch s := n.(type) {
+	case *ast.AssignStmt:
+		for i, lhs := range s.Lhs {
+			if id, ok := lhs.(*ast.Ident); ok && i < len(s.Rhs) {
+				if lit, ok := astutil.Unparen(s.Rhs[i]).(*ast.CompositeLit); ok && isTable(lit) {
+					tables[id.Name] = lit
+				}
+			}
+		}
+	case *ast.ValueSpec:
+		for i, id := range s.Names {
+			if i < len(s.Values) {
+				if lit, ok := astutil.Unparen(s.Values[i]).(*ast.CompositeLit); ok && isTable(lit) {
+					tables[id.Name] = lit
+				}
+			}
+		}
+	}
+}
+
+// findTableTests returns the table-driven tests in the function bodies of the given file. Tables can be
+// declared in the function itself, at the top level of the file, or right in the range statement.
+func findTableTests(in *ast.File) []tableTest {
+	fileTables := map[string]*ast.CompositeLit{}
+	for _, d := range in.Decls {
+		if gd, ok := d.(*ast.GenDecl); ok && gd.Tok == token.VAR {
+			for _, spec := range gd.Specs {
+				recordTables(spec, fileTables)
+			}
+		}
+	}
+
+	out := []tableTest{}
+	for _, d := range in.Decls {
+		f, ok := d.(*ast.FuncDecl)
+		if !ok || f.Body == nil {
+			continue
+		}
+
+		tables := map[string]*ast.CompositeLit{}
+		for name, lit := range fileTables {
+			tables[name] = lit
+		}
+		ast.Inspect(f.Body, func(n ast.Node) bool {
+			recordTables(n, tables)
+			if loop, ok := n.(*ast.RangeStmt); ok {
+				var table *ast.CompositeLit
+				switch x := astutil.Unparen(loop.X).(type) {
+				case *ast.Ident:
+					table = tables[x.Name]
+				case *ast.CompositeLit:
+					if isTable(x) {
+						table = x
+					}
+				}
+				if table != nil {
+					out = append(out, tableTest{loop: loop, cases: caseNames(table)})
+				}
+			}
+			return true
+		})
+	}
+	return out
+}
+
+// recordTableCoverage links every function called in the loop of a table-driven test to the table.
+func recordTableCoverage(table tableTest, called *set.Set, coverage map[string]*tableCoverage) {
+	for _, name := range set.StringSlice(called) {
+		if _, ok := coverage[name]; !ok {
+			coverage[name] = &tableCoverage{names: set.New()}
+		}
+		for _, caseName := range table.cases {
+			if caseName != "" {
+				coverage[name].names.Add(caseName)
+			}
+		}
+		if len(table.cases) > coverage[name].largest {
+			coverage[name].largest = len(table.cases)
+		}
+	}
+}
+
+// applyTableCoverage records the cases that exercise each declared function, along with the number of cases
+// in the largest table that does.
+func applyTableCoverage(declaredFuncInfo map[string]tarpFunc, coverage map[string]*tableCoverage) {
+	for key, f := range declaredFuncInfo {
+		if c, ok := coverage[f.Name]; ok {
+			f.Cases = set.StringSlice(c.names)
+			sort.Strings(f.Cases)
+			f.CaseCount = c.largest
+			declaredFuncInfo[key] = f
+		}
+	}
+}
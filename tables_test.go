@@ -0,0 +1,137 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/fatih/set"
+	"github.com/stretchr/testify/assert"
+)
+
+func parseTableExpr(t *testing.T, in string) *ast.CompositeLit {
+	t.Helper()
+	expr, err := parser.ParseExpr(in)
+	if err != nil {
+		t.Logf("failing because ParseExpr returned error: %v", err)
+		t.FailNow()
+	}
+	return expr.(*ast.CompositeLit)
+}
+
+func TestIsTable(t *testing.T) {
+	assert.True(t, isTable(parseTableExpr(t, `[]struct{ a int }{{a: 1}, {a: 2}}`)))
+	assert.True(t, isTable(parseTableExpr(t, `map[string]testCase{"a": {}}`)))
+	assert.False(t, isTable(parseTableExpr(t, `[]int{1, 2}`)), "tables should be made of composite literals")
+	assert.False(t, isTable(parseTableExpr(t, `[]testCase{}`)), "empty slices aren't tables")
+	assert.False(t, isTable(parseTableExpr(t, `testCase{a: 1}`)), "single structs aren't tables")
+}
+
+func TestStringLit(t *testing.T) {
+	actual, ok := stringLit(&ast.BasicLit{Kind: token.STRING, Value: `"name"`})
+	assert.True(t, ok)
+	assert.Equal(t, "name", actual)
+
+	_, ok = stringLit(ast.NewIdent("name"))
+	assert.False(t, ok)
+}
+
+func TestCaseNames(t *testing.T) {
+	slice := parseTableExpr(t, `[]testCase{{name: "first", a: 1}, {Name: "second"}, {a: 3}, {name: someVar}}`)
+	assert.Equal(t, []string{"first", "second", "", ""}, caseNames(slice))
+
+	keyed := parseTableExpr(t, `map[string]testCase{"first": {}, "second": {}}`)
+	assert.Equal(t, []string{"first", "second"}, caseNames(keyed))
+}
+
+func TestRecordTables(t *testing.T) {
+	codeSample := `
+		package main
+
+		var fileTable, other = []testCase{{}}, 1
+
+		func example() {
+			tests, n := []testCase{{}, {}}, 2
+			notATable := []int{1}
+		}
+	`
+	p := parseChunkOfCode(t, codeSample)
+	tables := map[string]*ast.CompositeLit{}
+
+	recordTables(p.Decls[0].(*ast.GenDecl).Specs[0], tables)
+	for _, stmt := range p.Decls[1].(*ast.FuncDecl).Body.List {
+		recordTables(stmt, tables)
+	}
+
+	assert.Len(t, tables, 2)
+	assert.Len(t, tables["fileTable"].Elts, 1)
+	assert.Len(t, tables["tests"].Elts, 2)
+}
+
+func TestFindTableTests(t *testing.T) {
+	codeSample := `
+		package main
+
+		var fileTable = []testCase{{name: "file"}}
+
+		func TestLocal(t *testing.T) {
+			tests := []testCase{{name: "a"}, {name: "b"}}
+			for _, tc := range tests {
+				t.Run(tc.name, func(t *testing.T) {})
+			}
+		}
+
+		func TestFileLevel(t *testing.T) {
+			for _, tc := range fileTable {}
+		}
+
+		func TestInline(t *testing.T) {
+			for _, tc := range []testCase{{}, {}, {}} {}
+		}
+
+		func TestNotATable(t *testing.T) {
+			tests := []int{1, 2}
+			for _, x := range tests {}
+			for _, x := range somethingElse() {}
+		}
+
+		func TestScoped(t *testing.T) {
+			// tests is only a table in TestLocal
+			for _, tc := range tests {}
+		}
+	`
+
+	actual := findTableTests(parseChunkOfCode(t, codeSample))
+
+	cases := [][]string{}
+	for _, table := range actual {
+		cases = append(cases, table.cases)
+	}
+	assert.Equal(t, [][]string{{"a", "b"}, {"file"}, {"", "", ""}}, cases)
+}
+
+func TestRecordTableCoverage(t *testing.T) {
+	coverage := map[string]*tableCoverage{}
+
+	recordTableCoverage(tableTest{cases: []string{"a", "b", ""}}, set.New("f", "g"), coverage)
+	recordTableCoverage(tableTest{cases: []string{"c"}}, set.New("f"), coverage)
+
+	assert.Equal(t, set.New("a", "b", "c"), coverage["f"].names)
+	assert.Equal(t, 3, coverage["f"].largest, "the largest table exercising a function should be kept")
+	assert.Equal(t, set.New("a", "b"), coverage["g"].names)
+}
+
+func TestApplyTableCoverage(t *testing.T) {
+	declaredFuncInfo := map[string]tarpFunc{
+		"main.go:f": {Name: "f", Filename: "main.go"},
+		"main.go:g": {Name: "g", Filename: "main.go"},
+	}
+
+	applyTableCoverage(declaredFuncInfo, map[string]*tableCoverage{"f": {names: set.New("b", "a"), largest: 2}})
+
+	assert.Equal(t, []string{"a", "b"}, declaredFuncInfo["main.go:f"].Cases)
+	assert.Equal(t, 2, declaredFuncInfo["main.go:f"].CaseCount)
+	assert.Empty(t, declaredFuncInfo["main.go:g"].Cases)
+	assert.Equal(t, 0, declaredFuncInfo["main.go:g"].CaseCount)
+}
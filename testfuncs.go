@@ -0,0 +1,88 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/fatih/set"
+)
+
+// test function kinds record which kind of function in a _test.go file called a function directly. go test
+// tells them apart by name, and so does tarp. Everything else declared in a test file, like helpers, suite
+// methods and specs, is considered a test.
+const (
+	testFunc      = "test"
+	exampleFunc   = "example"
+	benchmarkFunc = "benchmark"
+	fuzzFunc      = "fuzz"
+)
+
+// isTestName reports whether name is prefix followed by nothing, or by something that doesn't start with a
+// lowercase letter, which is how go test decides whether ExampleFoo or Benchmarkfoo is one of its functions.
+func isTestName(name, prefix string) bool {
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	}
+	if len(name) == len(prefix) {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(name[len(prefix):])
+	return !unicode.IsLower(r)
+}
+
+// funcKind returns the kind of test function a function declared in a _test.go file with the given name is.
+// Methods can't be examples, benchmarks or fuzz targets.
+func funcKind(name string, isMethod bool) string {
+	switch {
+	case isMethod:
+		return testFunc
+	case isTestName(name, "Example"):
+		return exampleFunc
+	case isTestName(name, "Benchmark"):
+		return benchmarkFunc
+	case isTestName(name, "Fuzz"):
+		return fuzzFunc
+	}
+	return testFunc
+}
+
+// countsAsTest reports whether the functions called by a test function of the given kind count as directly
+// tested, according to the --count-examples, --count-benchmarks and --count-fuzz flags.
+func countsAsTest(kind string) bool {
+	switch kind {
+	case exampleFunc:
+		return countExamples
+	case benchmarkFunc:
+		return countBenchmarks
+	case fuzzFunc:
+		return countFuzz
+	}
+	return true
+}
+
+// recordFuncKind records that the functions a test function of the given kind called were called by that kind
+// of function, and adds them to out if that kind counts as a test.
+func recordFuncKind(kind string, called *set.Set, out *set.Set, kinds map[string]*set.Set) {
+	for _, name := range set.StringSlice(called) {
+		if _, ok := kinds[name]; !ok {
+			kinds[name] = set.New()
+		}
+		kinds[name].Add(kind)
+	}
+	if countsAsTest(kind) {
+		out.Merge(called)
+	}
+}
+
+// applyFuncKinds records the kinds of test function that call each declared function directly.
+func applyFuncKinds(declaredFuncInfo map[string]tarpFunc, kinds map[string]*set.Set) {
+	for key, f := range declaredFuncInfo {
+		if k, ok := kinds[f.Name]; ok {
+			f.TestFuncs = set.StringSlice(k)
+			sort.Strings(f.TestFuncs)
+			declaredFuncInfo[key] = f
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/fatih/set"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsTestName(t *testing.T) {
+	assert.True(t, isTestName("BenchmarkParse", "Benchmark"))
+	assert.True(t, isTestName("Example", "Example"))
+	assert.True(t, isTestName("Example_suffix", "Example"))
+	assert.False(t, isTestName("Benchmarking", "Benchmark"), "names continuing in lowercase aren't benchmarks")
+	assert.False(t, isTestName("TestParse", "Benchmark"))
+}
+
+func TestFuncKind(t *testing.T) {
+	assert.Equal(t, testFunc, funcKind("TestParse", false))
+	assert.Equal(t, testFunc, funcKind("helper", false))
+	assert.Equal(t, exampleFunc, funcKind("ExampleParse", false))
+	assert.Equal(t, benchmarkFunc, funcKind("BenchmarkParse", false))
+	assert.Equal(t, fuzzFunc, funcKind("FuzzParse", false))
+	assert.Equal(t, testFunc, funcKind("BenchmarkParse", true), "methods can't be benchmarks")
+}
+
+func TestCountsAsTest(t *testing.T) {
+	defer func() { countExamples, countBenchmarks, countFuzz = true, true, true }()
+	countExamples, countBenchmarks, countFuzz = true, false, false
+
+	assert.True(t, countsAsTest(testFunc))
+	assert.True(t, countsAsTest(exampleFunc))
+	assert.False(t, countsAsTest(benchmarkFunc))
+	assert.False(t, countsAsTest(fuzzFunc))
+}
+
+func TestRecordFuncKind(t *testing.T) {
+	defer func() { countBenchmarks = true }()
+	countBenchmarks = false
+
+	out, kinds := set.New(), map[string]*set.Set{}
+	recordFuncKind(testFunc, set.New("Parse"), out, kinds)
+	recordFuncKind(benchmarkFunc, set.New("Parse", "Format"), out, kinds)
+
+	assert.Equal(t, set.New("Parse"), out, "functions only called by benchmarks shouldn't count")
+	assert.Equal(t, set.New(testFunc, benchmarkFunc), kinds["Parse"])
+	assert.Equal(t, set.New(benchmarkFunc), kinds["Format"])
+}
+
+func TestApplyFuncKinds(t *testing.T) {
+	declaredFuncInfo := map[string]tarpFunc{
+		"main.go:Parse":    {Name: "Parse"},
+		"main.go:Untested": {Name: "Untested"},
+	}
+	applyFuncKinds(declaredFuncInfo, map[string]*set.Set{"Parse": set.New(testFunc, benchmarkFunc)})
+
+	assert.Equal(t, []string{benchmarkFunc, testFunc}, declaredFuncInfo["main.go:Parse"].TestFuncs)
+	assert.Nil(t, declaredFuncInfo["main.go:Untested"].TestFuncs)
+}
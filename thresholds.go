@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// packageMinScore is a minimum score for the packages matching a pattern, parsed from a --package-min-score
+// setting like ./legacy/...=60.
+type packageMinScore struct {
+	pattern string
+	score   int
+}
+
+// parsePackageMinScore parses a --package-min-score setting, written pattern=score.
+func parsePackageMinScore(s string) (packageMinScore, error) {
+	i := strings.LastIndex(s, "=")
+	if i <= 0 {
+		return packageMinScore{}, fmt.Errorf("expected pattern=score, got %q", s)
+	}
+	score, err := strconv.Atoi(s[i+1:])
+	if err != nil || score < 0 || score > 100 {
+		return packageMinScore{}, fmt.Errorf("expected a score between 0 and 100 in %q", s)
+	}
+	return packageMinScore{pattern: s[:i], score: score}, nil
+}
+
+// matchesPackage reports whether a package pattern, written the way packages are passed to analyze, matches
+// the package reported under the given name. Patterns ending in "/..." match every package beneath them.
+func matchesPackage(pattern, pkg string) bool {
+	if pattern == "..." {
+		return true
+	}
+	root := strings.TrimSuffix(pattern, "/...")
+	if isLocalPath(root) {
+		root = packageName(packageDir(root))
+	}
+	if root == pkg {
+		return true
+	}
+	return strings.HasSuffix(pattern, "/...") && strings.HasPrefix(pkg, root+"/")
+}
+
+// minScoreFor returns the minimum score the package reported under the given name has to meet, and whether
+// it has one at all. When more than one pattern matches, the longest one wins.
+func minScoreFor(pkg string, minimums []packageMinScore) (int, bool) {
+	best, found := packageMinScore{}, false
+	for _, minimum := range minimums {
+		if matchesPackage(minimum.pattern, pkg) && (!found || len(minimum.pattern) > len(best.pattern)) {
+			best, found = minimum, true
+		}
+	}
+	return best.score, found
+}
+
+// parsePackageMinScores parses every --package-min-score setting.
+func parsePackageMinScores(settings []string) []packageMinScore {
+	minimums := []packageMinScore{}
+	for _, s := range settings {
+		minimum, err := parsePackageMinScore(s)
+		if err != nil {
+			fatalf(exitUsageError, "invalid --package-min-score: %v", err)
+		}
+		minimums = append(minimums, minimum)
+	}
+	return minimums
+}
+
+// checkThresholds returns a message for each threshold the given reports breach: the --min-score across
+// every package, the given per-package minimums, and the --max-untested cap, in that order.
+func checkThresholds(outputs []tarpOutput, minimums []packageMinScore) []string {
+	breaches := []string{}
+	declared, called := 0, 0
+	for _, output := range outputs {
+		declared += output.DeclaredCount
+		called += output.CalledCount
+	}
+	if score := percentage(called, declared); score < minScore {
+		breaches = append(breaches, fmt.Sprintf("score of %d%% is below --min-score of %d%%", score, minScore))
+	}
+
+	packageBreaches := []string{}
+	for _, output := range outputs {
+		if minimum, ok := minScoreFor(output.Package, minimums); ok && output.Score < minimum {
+			packageBreaches = append(packageBreaches, fmt.Sprintf("package %s scored %d%%, below its minimum of %d%%", output.Package, output.Score, minimum))
+		}
+	}
+	sort.Strings(packageBreaches)
+	breaches = append(breaches, packageBreaches...)
+
+	if untested := declared - called; maxUntested >= 0 && untested > maxUntested {
+		breaches = append(breaches, fmt.Sprintf("%d functions without direct unit tests, more than --max-untested of %d", untested, maxUntested))
+	}
+	return breaches
+}
+
+// checkMatrixThresholds checks the thresholds against each configuration of a --matrix run on its own, since
+// every configuration analyzes the same packages, and prefixes each breach with the configuration's name.
+func checkMatrixThresholds(matrixReport tarpMatrixOutput, minimums []packageMinScore) []string {
+	breaches := []string{}
+	for _, configName := range matrixReport.Order {
+		for _, breach := range checkThresholds(matrixReport.Configurations[configName].Packages, minimums) {
+			breaches = append(breaches, fmt.Sprintf("%s: %s", configName, breach))
+		}
+	}
+	return breaches
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePackageMinScore(t *testing.T) {
+	actual, err := parsePackageMinScore("./legacy/...=60")
+	assert.Nil(t, err)
+	assert.Equal(t, packageMinScore{pattern: "./legacy/...", score: 60}, actual)
+
+	for _, invalid := range []string{"./legacy/...", "=60", "./legacy/...=sixty", "./legacy/...=101", "./legacy/...=-1"} {
+		_, err = parsePackageMinScore(invalid)
+		assert.NotNil(t, err, "%q should be invalid", invalid)
+	}
+}
+
+func TestParsePackageMinScores(t *testing.T) {
+	actual := parsePackageMinScores([]string{"example.com/legacy/...=60", "example.com/core=95"})
+	assert.Equal(t, []packageMinScore{{pattern: "example.com/legacy/...", score: 60}, {pattern: "example.com/core", score: 95}}, actual)
+
+	assert.Panics(t, func() { parsePackageMinScores([]string{"example.com/core"}) }, "invalid settings should be fatal")
+}
+
+func TestMatchesPackage(t *testing.T) {
+	assert.True(t, matchesPackage("...", "example.com/core"))
+	assert.True(t, matchesPackage("example.com/core", "example.com/core"))
+	assert.False(t, matchesPackage("example.com/core", "example.com/core/store"))
+	assert.True(t, matchesPackage("example.com/core/...", "example.com/core/store"))
+	assert.True(t, matchesPackage("example.com/core/...", "example.com/core"))
+	assert.False(t, matchesPackage("example.com/core/...", "example.com/corelib"))
+	assert.True(t, matchesPackage(buildExamplePackagePath(t, "...", true), buildExamplePackagePath(t, "simple", false)), "local paths should match the packages in them")
+}
+
+func TestMinScoreFor(t *testing.T) {
+	minimums := []packageMinScore{{pattern: "example.com/...", score: 60}, {pattern: "example.com/core/...", score: 95}}
+
+	actual, ok := minScoreFor("example.com/core/store", minimums)
+	assert.True(t, ok)
+	assert.Equal(t, 95, actual, "the longest matching pattern should win")
+
+	actual, ok = minScoreFor("example.com/legacy", minimums)
+	assert.True(t, ok)
+	assert.Equal(t, 60, actual)
+
+	_, ok = minScoreFor("other.com/legacy", minimums)
+	assert.False(t, ok)
+}
+
+func TestCheckThresholds(t *testing.T) {
+	outputs := []tarpOutput{
+		{Package: "example.com/core", DeclaredCount: 10, CalledCount: 10, Score: 100},
+		{Package: "example.com/legacy", DeclaredCount: 10, CalledCount: 5, Score: 50},
+	}
+	minimums := []packageMinScore{{pattern: "example.com/...", score: 60}}
+
+	assert.Equal(t, []string{"package example.com/legacy scored 50%, below its minimum of 60%"}, checkThresholds(outputs, minimums))
+	assert.Empty(t, checkThresholds(outputs, nil), "there should be no thresholds by default")
+
+	defer func() { minScore, maxUntested = 0, -1 }()
+	minScore, maxUntested = 80, 4
+	expected := []string{
+		"score of 75% is below --min-score of 80%",
+		"package example.com/legacy scored 50%, below its minimum of 60%",
+		"5 functions without direct unit tests, more than --max-untested of 4",
+	}
+	assert.Equal(t, expected, checkThresholds(outputs, minimums))
+
+	minScore, maxUntested = 75, 5
+	assert.Empty(t, checkThresholds(outputs, nil))
+}
+
+func TestCheckMatrixThresholds(t *testing.T) {
+	packages := []tarpOutput{{Package: "example.com/lib", DeclaredCount: 10, CalledCount: 8, Score: 80}}
+	matrixReport := tarpMatrixOutput{
+		Configurations: map[string]tarpModuleOutput{
+			"linux":  {Packages: packages},
+			"darwin": {Packages: packages},
+		},
+		Order: []string{"linux", "darwin"},
+	}
+
+	defer func() { maxUntested = -1 }()
+	maxUntested = 2
+	assert.Empty(t, checkMatrixThresholds(matrixReport, nil), "the same package shouldn't be counted once per configuration")
+
+	maxUntested = 1
+	expected := []string{
+		"linux: 2 functions without direct unit tests, more than --max-untested of 1",
+		"darwin: 2 functions without direct unit tests, more than --max-untested of 1",
+	}
+	assert.Equal(t, expected, checkMatrixThresholds(matrixReport, nil))
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log"
+	"strings"
+
+	"github.com/fatih/set"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/loader"
+)
+
+const (
+	typesEngine = "types"
+	astEngine   = "ast"
+)
+
+// findPackage locates imported packages for the loader. Packages belonging to the current workspace or
+// module, or to a module replaced with a local directory, are found on disk without help from the go command.
+func findPackage(ctxt *build.Context, importPath, fromDir string, mode build.ImportMode) (*build.Package, error) {
+	if !build.IsLocalImport(importPath) {
+		if dir, ok := resolveImportPath(importPath, fromDir); ok {
+			bp, err := ctxt.ImportDir(dir, mode)
+			bp.ImportPath = importPath
+			return bp, err
+		}
+	}
+	return ctxt.Import(importPath, fromDir, mode)
+}
+
+// loadPackage type-checks the package in pkgDir along with its in-package and external tests.
+// Dependencies only have their declarations checked, since we never look inside their function bodies. The
+// BDD specs in the tests that never run are emptied once they've been checked.
+func loadPackage(pkgDir string) (*loader.Program, string, error) {
+	ctx := buildContext()
+	ctx.CgoEnabled = false
+	// in module mode, imports are resolved by the go command, which needs to run inside the module
+	ctx.Dir = pkgDir
+
+	bp, err := ctx.Import(".", pkgDir, 0)
+	if err != nil {
+		return nil, "", err
+	}
+	// packages outside of GOPATH are imported as ".", unless we can work out their module import path
+	importPath, target := bp.ImportPath, "."
+	if modPath, ok := moduleImportPath(pkgDir); ok {
+		importPath, target = modPath, modPath
+	}
+
+	conf := loader.Config{
+		Fset:        fileset,
+		ParserMode:  parser.ParseComments,
+		Build:       &ctx,
+		Cwd:         pkgDir,
+		FindPackage: findPackage,
+		TypeCheckFuncBodies: func(path string) bool {
+			return path == importPath || path == importPath+"_test"
+		},
+	}
+	conf.TypeChecker.Error = func(err error) {
+		if debug {
+			log.Printf("type error: %v", err)
+		}
+	}
+	conf.ImportWithTests(target)
+
+	prog, err := conf.Load()
+	if err != nil {
+		return nil, "", err
+	}
+	for _, pkgInfo := range prog.InitialPackages() {
+		for _, f := range pkgInfo.Files {
+			if strings.HasSuffix(fileset.Position(f.Pos()).Filename, "_test.go") {
+				dropSkippedSpecs(f)
+			}
+		}
+	}
+	return prog, importPath, nil
+}
+
+// qualifiedFuncName returns the name tarp uses to refer to a function or method object,
+// which matches what parseFuncDecl produces for the corresponding declaration.
+func qualifiedFuncName(fn *types.Func) string {
+	sig, ok := fn.Type().(*types.Signature)
+	if !ok || sig.Recv() == nil {
+		return fn.Name()
+	}
+
+	recv := sig.Recv().Type()
+	if p, ok := recv.(*types.Pointer); ok {
+		recv = p.Elem()
+	}
+	if n, ok := recv.(*types.Named); ok {
+		return fmt.Sprintf("%s.%s", n.Obj().Name(), fn.Name())
+	}
+	return fn.Name()
+}
+
+// calleeExpr returns the expression naming what a call expression invokes, looking through parentheses and
+// explicit instantiations like `Map[int, string]`.
+func calleeExpr(call *ast.CallExpr) ast.Expr {
+	fun := astutil.Unparen(call.Fun)
+	switch x := fun.(type) {
+	case *ast.IndexExpr:
+		return astutil.Unparen(x.X)
+	case *ast.IndexListExpr:
+		return astutil.Unparen(x.X)
+	}
+	return fun
+}
+
+// calleeOf returns the function or method a call expression invokes, if the type checker could
+// statically determine one. Calls to builtins, conversions, and function values yield nil. Calls to
+// generic functions and methods of generic types yield the generic declaration, not the instantiation.
+func calleeOf(call *ast.CallExpr, info *types.Info) *types.Func {
+	var obj types.Object
+	switch f := calleeExpr(call).(type) {
+	case *ast.Ident:
+		obj = info.Uses[f]
+	case *ast.SelectorExpr:
+		if sel, ok := info.Selections[f]; ok {
+			obj = sel.Obj()
+		} else {
+			obj = info.Uses[f.Sel]
+		}
+	}
+
+	fn, _ := obj.(*types.Func)
+	if fn == nil {
+		return nil
+	}
+	return fn.Origin()
+}
+
+// getResolvedCalledNames adds every function belonging to pkgPath that is called anywhere in the given
+// node to out. Unlike getCalledNames, it uses the type checker's resolution of each call, so the
+// receiver's type is known no matter how it was obtained.
+func getResolvedCalledNames(in ast.Node, info *types.Info, pkgPath string, out *set.Set) {
+	ast.Inspect(in, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			if fn := calleeOf(call, info); fn != nil && fn.Pkg() != nil && fn.Pkg().Path() == pkgPath {
+				out.Add(qualifiedFuncName(fn))
+			}
+		}
+		return true
+	})
+}
+
+// getResolvedTestCalledNames adds the functions belonging to pkgPath that the given test file's functions
+// call to out, as long as the kind of test function making the call counts as a test, and records which
+// kinds of test function called each of them in kinds. What the test helpers in helpers call is recorded in
+// helperCalls instead, to be credited to the tests that call them.
+func getResolvedTestCalledNames(in *ast.File, info *types.Info, pkgPath string, helpers *set.Set, out *set.Set, kinds map[string]*set.Set, helperCalls map[string]*set.Set) {
+	for _, d := range in.Decls {
+		called := set.New()
+		getResolvedCalledNames(d, info, pkgPath, called)
+		kind := testFunc
+		if f, ok := d.(*ast.FuncDecl); ok {
+			if fn, ok := info.Defs[f.Name].(*types.Func); ok {
+				getResolvedHelperNames(f, info, fn.Pkg(), helpers, called)
+			}
+			if isTestHelper(f) {
+				helperCalls[f.Name.Name] = called
+				continue
+			}
+			kind = funcKind(f.Name.Name, f.Recv != nil)
+		}
+		recordFuncKind(kind, called, out, kinds)
+	}
+}
+
+// getResolvedCallEdges records the functions belonging to pkgPath that each function declared in the given
+// file calls, keyed by file and name like getCallEdges.
+func getResolvedCallEdges(in *ast.File, fileset *token.FileSet, info *types.Info, pkgPath string, edges map[string]*set.Set) {
+	for _, d := range in.Decls {
+		if f, ok := d.(*ast.FuncDecl); ok && f.Body != nil {
+			out := set.New()
+			getResolvedCalledNames(f.Body, info, pkgPath, out)
+			edges[funcKey(tarpFunc{Name: parseFuncDecl(f), Filename: fileset.Position(f.Pos()).Filename})] = out
+		}
+	}
+}
+
+// interfaceCalledNames returns the names of the functions belonging to pkgPath that calls through interfaces
+// in test files can reach, going by the call graph's constructed algorithm. That approximates rapid type
+// analysis rather than tracking values like pointer analysis does: every implementation constructed by the
+// tests, or by the code they call, is considered, whether or not it's the value the interface holds.
+func interfaceCalledNames(prog *loader.Program, pkgPath string) *set.Set {
+	g := buildCallGraph(prog, constructedAlgorithm)
+	out := set.New()
+	for fn, summary := range g.summaries {
+		if summary.test {
+			out.Merge(g.dispatchedNames(fn, pkgPath))
+		}
+	}
+	return out
+}
+
+// getResolvedReferencedNames adds the functions belonging to pkgPath that are referenced without being called
+// in the given file's function bodies, like those passed as arguments or stored in tables, to out.
+func getResolvedReferencedNames(in *ast.File, info *types.Info, pkgPath string, out *set.Set) {
+	for _, d := range in.Decls {
+		if f, ok := d.(*ast.FuncDecl); ok {
+			out.Merge(summarizeFunc(f, info, true).referencedNames(pkgPath))
+		}
+	}
+}
+
+// getResolvedTableCoverage links the functions belonging to pkgPath that are called in each table-driven test
+// in the given file to its table.
+func getResolvedTableCoverage(in *ast.File, info *types.Info, pkgPath string, coverage map[string]*tableCoverage) {
+	for _, table := range findTableTests(in) {
+		called := set.New()
+		getResolvedCalledNames(table.loop.Body, info, pkgPath, called)
+		recordTableCoverage(table, called, coverage)
+	}
+}
+
+// getResolvedSpecCoverage links the functions belonging to pkgPath that are called by each spec of the BDD
+// style tests in the given file to the spec's path.
+func getResolvedSpecCoverage(in *ast.File, info *types.Info, pkgPath string, coverage map[string]*set.Set) {
+	for _, spec := range findSpecs(in) {
+		called := set.New()
+		for _, node := range spec.nodes {
+			getResolvedCalledNames(node, info, pkgPath, called)
+		}
+		recordSpecCoverage(spec, called, coverage)
+	}
+}
+
+// typeCheckedAnalysis builds a tarpReport for the package in pkgDir using go/types to resolve calls.
+// It returns an error if the package or its tests can't be type-checked.
+func typeCheckedAnalysis(pkgDir string) (tarpReport, error) {
+	prog, importPath, err := loadPackage(pkgDir)
+	if err != nil {
+		return tarpReport{}, err
+	}
+
+	declaredFuncInfo := map[string]tarpFunc{}
+	calledByPackage := map[string]*set.Set{}
+	callEdges := map[string]*set.Set{}
+	referenced := set.New()
+	coverage := map[string]*tableCoverage{}
+	specCoverage := map[string]*set.Set{}
+	kinds, carriers := map[string]*set.Set{}, map[string]*set.Set{}
+
+	for _, pkgInfo := range prog.InitialPackages() {
+		called := set.New()
+		calledByPackage[pkgInfo.Pkg.Path()] = called
+		fields := suiteFields(pkgInfo.Files, &pkgInfo.Info)
+		helpers, helperCalls := set.New(), map[string]*set.Set{}
+		for _, f := range pkgInfo.Files {
+			if strings.HasSuffix(fileset.Position(f.Pos()).Filename, "_test.go") {
+				findTestHelpers(f, helpers)
+			}
+		}
+		for _, f := range pkgInfo.Files {
+			filename := fileset.Position(f.Pos()).Filename
+			if strings.HasSuffix(filename, "_test.go") {
+				getResolvedTestCalledNames(f, &pkgInfo.Info, importPath, helpers, called, kinds, helperCalls)
+				getSuiteCalledNames(f, &pkgInfo.Info, importPath, fields, called)
+				if creditReferences {
+					getResolvedReferencedNames(f, &pkgInfo.Info, importPath, referenced)
+				}
+				getResolvedTableCoverage(f, &pkgInfo.Info, importPath, coverage)
+				getResolvedSpecCoverage(f, &pkgInfo.Info, importPath, specCoverage)
+			} else {
+				getDeclaredNames(f, fileset, declaredFuncInfo)
+				getResolvedCallEdges(f, fileset, &pkgInfo.Info, importPath, callEdges)
+			}
+		}
+		creditHelpers(helperCalls, called, kinds, carriers)
+	}
+
+	calledFuncs := set.New("init")
+	credits := creditTestPackages(calledByPackage, calledFuncs)
+	if creditInterfaces {
+		creditAs(interfaceCalledNames(prog, importPath), viaInterfaceCredit, calledFuncs, credits)
+	}
+	creditAs(referenced, byReferenceCredit, calledFuncs, credits)
+	report := buildReport(declaredFuncInfo, calledFuncs, callEdges, credits)
+	applyTableCoverage(report.DeclaredDetails, coverage)
+	applySpecCoverage(report.DeclaredDetails, specCoverage)
+	applyFuncKinds(report.DeclaredDetails, kinds)
+	applyHelperCredits(report.DeclaredDetails, carriers)
+	report.Engine = typesEngine
+	return report, nil
+}
@@ -0,0 +1,555 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fatih/set"
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////
+//                                                    //
+//               Test Helper Functions                //
+//                                                    //
+////////////////////////////////////////////////////////
+
+func typeCheckChunkOfCode(t *testing.T, chunkOfCode string) (*ast.File, *types.Package, *types.Info) {
+	t.Helper()
+	fset := token.NewFileSet()
+	p, err := parser.ParseFile(fset, "example.go", chunkOfCode, parser.AllErrors)
+	if err != nil {
+		log.Println(err)
+		t.FailNow()
+	}
+	info := &types.Info{
+		Uses:       map[*ast.Ident]types.Object{},
+		Defs:       map[*ast.Ident]types.Object{},
+		Selections: map[*ast.SelectorExpr]*types.Selection{},
+		Types:      map[ast.Expr]types.TypeAndValue{},
+	}
+	conf := types.Config{Importer: importer.Default()}
+	pkg, err := conf.Check("example", fset, []*ast.File{p}, info)
+	if err != nil {
+		log.Println(err)
+		t.FailNow()
+	}
+	return p, pkg, info
+}
+
+////////////////////////////////////////////////////////
+//                                                    //
+//                   Actual Tests                     //
+//                                                    //
+////////////////////////////////////////////////////////
+
+func TestFindPackage(t *testing.T) {
+	root := buildWorkspaceTree(t)
+	defer os.RemoveAll(root)
+
+	workspaceModule := func(t *testing.T) {
+		bp, err := findPackage(&build.Default, "example.com/lib", filepath.Join(root, "svc"), 0)
+		assert.Nil(t, err)
+		assert.Equal(t, "example.com/lib", bp.ImportPath)
+		assert.Equal(t, filepath.Join(root, "lib"), bp.Dir)
+	}
+	t.Run("workspace module", workspaceModule)
+
+	standardLibrary := func(t *testing.T) {
+		bp, err := findPackage(&build.Default, "fmt", filepath.Join(root, "svc"), 0)
+		assert.Nil(t, err)
+		assert.Equal(t, "fmt", bp.ImportPath)
+	}
+	t.Run("standard library", standardLibrary)
+}
+
+func TestLoadPackage(t *testing.T) {
+	simple := func(t *testing.T) {
+		prog, importPath, err := loadPackage(buildExamplePackagePath(t, "simple", true))
+
+		assert.Nil(t, err)
+		assert.Equal(t, buildExamplePackagePath(t, "simple", false), importPath)
+		assert.Len(t, prog.InitialPackages(), 1, "expected only the package itself, since it has no external tests")
+	}
+	t.Run("simple", simple)
+
+	workspace := func(t *testing.T) {
+		root := buildWorkspaceTree(t)
+		defer os.RemoveAll(root)
+
+		prog, importPath, err := loadPackage(filepath.Join(root, "svc"))
+
+		assert.Nil(t, err, "imports of sibling modules should be resolved through the workspace")
+		assert.Equal(t, "example.com/svc", importPath)
+		assert.NotNil(t, prog.Package("example.com/lib"))
+	}
+	t.Run("workspace module", workspace)
+
+	nonexistent := func(t *testing.T) {
+		_, _, err := loadPackage(buildExamplePackagePath(t, "absolutelynosuchpackage", true))
+		assert.NotNil(t, err)
+	}
+	t.Run("nonexistent package", nonexistent)
+}
+
+func TestQualifiedFuncName(t *testing.T) {
+	codeSample := `
+		package main
+
+		type example struct{}
+		func (e example) value() {}
+		func (e *example) pointer() {}
+		func function() {}
+
+		type list[T any] struct{}
+		func (l *list[T]) push(v T) {}
+		var ints list[int]
+	`
+
+	_, pkg, _ := typeCheckChunkOfCode(t, codeSample)
+	named := pkg.Scope().Lookup("example").Type().(*types.Named)
+
+	function := func(t *testing.T) {
+		fn := pkg.Scope().Lookup("function").(*types.Func)
+		assert.Equal(t, "function", qualifiedFuncName(fn))
+	}
+	t.Run("function", function)
+
+	valueReceiver := func(t *testing.T) {
+		assert.Equal(t, "example.value", qualifiedFuncName(named.Method(0)))
+	}
+	t.Run("value receiver", valueReceiver)
+
+	pointerReceiver := func(t *testing.T) {
+		assert.Equal(t, "example.pointer", qualifiedFuncName(named.Method(1)))
+	}
+	t.Run("pointer receiver", pointerReceiver)
+
+	instantiatedReceiver := func(t *testing.T) {
+		instance := pkg.Scope().Lookup("ints").Type().(*types.Named)
+		assert.Equal(t, "list.push", qualifiedFuncName(instance.Method(0)))
+	}
+	t.Run("instantiated receiver", instantiatedReceiver)
+}
+
+func TestCalleeExpr(t *testing.T) {
+	codeSample := `
+		package main
+
+		func main() {
+			function()
+			(function)()
+			Map[int](xs)
+			Map[int, string](xs)
+			(pkg.Map[int])(xs)
+		}
+	`
+
+	p := parseChunkOfCode(t, codeSample)
+	expected := []string{"function", "function", "Map", "Map", "pkg.Map"}
+	for i, stmt := range p.Decls[0].(*ast.FuncDecl).Body.List {
+		var buf bytes.Buffer
+		printer.Fprint(&buf, token.NewFileSet(), calleeExpr(stmt.(*ast.ExprStmt).X.(*ast.CallExpr)))
+		assert.Equal(t, expected[i], buf.String(), "unexpected callee for statement %d", i)
+	}
+}
+
+func TestCalleeOf(t *testing.T) {
+	codeSample := `
+		package main
+
+		type example struct{}
+		func (e example) method() {}
+		func function() {}
+
+		type list[T any] struct{}
+		func (l *list[T]) push(v T) {}
+		func mapped[T, U any](in T, f func(T) U) U { return f(in) }
+
+		func main() {
+			function()
+			example{}.method()
+			(function)()
+			println("builtin")
+			f := function
+			f()
+			mapped[int, string](1, func(int) string { return "" })
+			(&list[int]{}).push(1)
+		}
+	`
+
+	p, pkg, info := typeCheckChunkOfCode(t, codeSample)
+	body := p.Decls[6].(*ast.FuncDecl).Body.List
+	callAt := func(i int) *ast.CallExpr {
+		return body[i].(*ast.ExprStmt).X.(*ast.CallExpr)
+	}
+
+	ident := func(t *testing.T) {
+		assert.Equal(t, "function", calleeOf(callAt(0), info).Name())
+	}
+	t.Run("ident", ident)
+
+	selector := func(t *testing.T) {
+		assert.Equal(t, "method", calleeOf(callAt(1), info).Name())
+	}
+	t.Run("selector", selector)
+
+	parenthesized := func(t *testing.T) {
+		assert.Equal(t, "function", calleeOf(callAt(2), info).Name())
+	}
+	t.Run("parenthesized", parenthesized)
+
+	builtin := func(t *testing.T) {
+		assert.Nil(t, calleeOf(callAt(3), info))
+	}
+	t.Run("builtin", builtin)
+
+	functionValue := func(t *testing.T) {
+		assert.Nil(t, calleeOf(callAt(5), info))
+	}
+	t.Run("function value", functionValue)
+
+	instantiation := func(t *testing.T) {
+		assert.Equal(t, pkg.Scope().Lookup("mapped"), calleeOf(callAt(6), info), "explicit instantiations should resolve to the generic function")
+	}
+	t.Run("explicit instantiation", instantiation)
+
+	genericMethod := func(t *testing.T) {
+		named := pkg.Scope().Lookup("list").Type().(*types.Named)
+		assert.Equal(t, named.Method(0), calleeOf(callAt(7), info), "methods of instantiated types should resolve to the generic method")
+	}
+	t.Run("method of generic type", genericMethod)
+}
+
+func TestGetResolvedCalledNames(t *testing.T) {
+	codeSample := `
+		package main
+
+		type store struct{}
+		func (s *store) Get() string { return "" }
+
+		type server struct{ store *store }
+		func (s server) Handle() {}
+		func newServer() server { return server{store: &store{}} }
+
+		func TestA() {
+			newServer().Handle()
+		}
+
+		func TestB() {
+			x := map[string]server{"x": newServer()}
+			x["x"].store.Get()
+		}
+
+		func TestC() {
+			// x is an entirely different type here than in TestB
+			x := 1
+			_ = x
+		}
+	`
+
+	p, _, info := typeCheckChunkOfCode(t, codeSample)
+
+	actual := set.New()
+	expected := set.New("newServer", "server.Handle", "store.Get")
+
+	getResolvedCalledNames(p, info, "example", actual)
+
+	assert.Equal(t, expected, actual, "expected output did not match actual output")
+}
+
+func TestGetResolvedTestCalledNames(t *testing.T) {
+	defer func() { countFuzz = true }()
+	countFuzz = false
+
+	prog, importPath, err := loadPackage(buildExamplePackagePath(t, "testfuncs", true))
+	assert.Nil(t, err)
+
+	actual, kinds := set.New(), map[string]*set.Set{}
+	pkgInfo := prog.InitialPackages()[0]
+	for _, f := range pkgInfo.Files {
+		getResolvedTestCalledNames(f, &pkgInfo.Info, importPath, set.New(), actual, kinds, map[string]*set.Set{})
+	}
+
+	assert.Equal(t, set.New("Parse", "Format", "Validate"), actual, "calls made by fuzz targets shouldn't count")
+	assert.Equal(t, set.New(testFunc, benchmarkFunc), kinds["Parse"])
+	assert.Equal(t, set.New(fuzzFunc), kinds["Normalize"])
+
+	prog, importPath, err = loadPackage(buildExamplePackagePath(t, "helpers", true))
+	assert.Nil(t, err)
+
+	actual, kinds, helperCalls := set.New(), map[string]*set.Set{}, map[string]*set.Set{}
+	pkgInfo = prog.InitialPackages()[0]
+	for _, f := range pkgInfo.Files {
+		getResolvedTestCalledNames(f, &pkgInfo.Info, importPath, set.New("newTestStore", "fill", "unused"), actual, kinds, helperCalls)
+	}
+
+	assert.Equal(t, set.New("newTestStore", "Store.Get", "Reset", "seedAll", "fixture.seed", "Seed"), actual, "calls made by helpers should be left for creditHelpers")
+	assert.Equal(t, set.New("fill", "NewStore"), helperCalls["newTestStore"])
+	assert.Equal(t, set.New("Abandoned"), helperCalls["unused"])
+}
+
+func TestGetResolvedReferencedNames(t *testing.T) {
+	codeSample := `
+		package main
+
+		import "sort"
+
+		type server struct{}
+		func (s server) Handle() {}
+		func less(a, b int) bool { return a < b }
+		func called() {}
+		func apply(f func()) { f() }
+
+		func TestA() {
+			s := server{}
+			apply(s.Handle)
+			called()
+			xs := []int{2, 1}
+			sort.Slice(xs, func(i, j int) bool { return less(xs[i], xs[j]) })
+			tests := []struct{ fn func(int, int) bool }{{fn: less}}
+			_ = tests
+		}
+	`
+
+	p, _, info := typeCheckChunkOfCode(t, codeSample)
+
+	actual := set.New()
+	expected := set.New("server.Handle", "less")
+
+	getResolvedReferencedNames(p, info, "example", actual)
+
+	assert.Equal(t, expected, actual, "only functions referenced without being called should be found")
+}
+
+func TestGetResolvedTableCoverage(t *testing.T) {
+	codeSample := `
+		package main
+
+		type calc struct{}
+		func (c calc) add(a, b int) int { return a + b }
+		func other() {}
+
+		func TestA() {
+			c := calc{}
+			for _, tc := range map[string]struct{ a, b int }{"one": {1, 2}} {
+				c.add(tc.a, tc.b)
+			}
+			other()
+		}
+	`
+
+	p, _, info := typeCheckChunkOfCode(t, codeSample)
+
+	coverage := map[string]*tableCoverage{}
+	getResolvedTableCoverage(p, info, "example", coverage)
+
+	assert.Equal(t, set.New("one"), coverage["calc.add"].names)
+	assert.Equal(t, 1, coverage["calc.add"].largest)
+	assert.Nil(t, coverage["other"], "calls outside of the loop shouldn't be linked to the table")
+}
+
+func TestGetResolvedSpecCoverage(t *testing.T) {
+	prog, importPath, err := loadPackage(buildExamplePackagePath(t, "bdd", true))
+	assert.Nil(t, err)
+
+	coverage := map[string]*set.Set{}
+	pkgInfo := prog.InitialPackages()[0]
+	for _, f := range pkgInfo.Files {
+		getResolvedSpecCoverage(f, &pkgInfo.Info, importPath, coverage)
+	}
+
+	assert.Equal(t, set.New("Store › Get › returns not found", "Store › Put › stores the value"), coverage["NewStore"])
+	assert.Equal(t, set.New("Store › Put › stores the value"), coverage["Store.Put"])
+	assert.Equal(t, set.New("Format › pads short keys"), coverage["Format"])
+	assert.Nil(t, coverage["Untested"])
+}
+
+func TestGetResolvedCallEdges(t *testing.T) {
+	codeSample := `
+		package main
+
+		type store struct{}
+		func (s *store) Get() string { return helper() }
+
+		func helper() string { return "" }
+		func newStore() *store { return &store{} }
+		func wrapper() string { return newStore().Get() }
+	`
+
+	p, _, info := typeCheckChunkOfCode(t, codeSample)
+
+	actual := map[string]*set.Set{}
+	expected := map[string]*set.Set{
+		"store.Get": set.New("helper"),
+		"helper":    set.New(),
+		"newStore":  set.New(),
+		"wrapper":   set.New("newStore", "store.Get"),
+	}
+
+	getResolvedCallEdges(p, token.NewFileSet(), info, "example", actual)
+
+	assert.Equal(t, expected, actual, "expected output did not match actual output")
+}
+
+func TestInterfaceCalledNames(t *testing.T) {
+	prog, importPath, err := loadPackage(buildExamplePackagePath(t, "dispatch", true))
+	if err != nil {
+		t.Logf("failing because loadPackage returned error: %v", err)
+		t.FailNow()
+	}
+
+	actual := interfaceCalledNames(prog, importPath)
+	assert.Equal(t, set.New("memStore.get"), actual, "only implementations the tests construct should be reachable")
+}
+
+func TestTypeCheckedAnalysis(t *testing.T) {
+	methods := func(t *testing.T) {
+		actual, err := typeCheckedAnalysis(buildExamplePackagePath(t, "methods", true))
+
+		assert.Nil(t, err)
+		assert.Equal(t, set.New("main.go:example.A", "main.go:example.B", "main.go:example.C", "main.go:example.D", "main.go:example.E", "main.go:wrapper"), actual.Called)
+		assert.Equal(t, set.New("main.go:example.A", "main.go:example.B", "main.go:example.C", "main.go:example.D", "main.go:example.E", "main.go:example.F", "main.go:wrapper"), actual.Declared)
+	}
+	t.Run("methods", methods)
+
+	statements := func(t *testing.T) {
+		actual, err := typeCheckedAnalysis(buildExamplePackagePath(t, "statements", true))
+
+		assert.Nil(t, err)
+		assert.Empty(t, set.StringSlice(set.Difference(actual.Declared, actual.Called)), "expected calls in every statement and expression position to be found")
+	}
+	t.Run("statements", statements)
+
+	external := func(t *testing.T) {
+		actual, err := typeCheckedAnalysis(buildExamplePackagePath(t, "external", true))
+
+		assert.Nil(t, err)
+		assert.Equal(t, set.New("main.go:Untested"), set.Difference(actual.Declared, actual.Called))
+		assert.Equal(t, []string{whiteBoxCredit}, actual.DeclaredDetails["main.go:counter.Reset"].Credits)
+		assert.Equal(t, []string{blackBoxCredit}, actual.DeclaredDetails["main.go:Dotted"].Credits)
+	}
+	t.Run("external tests", external)
+
+	interfaces := func(t *testing.T) {
+		actual, err := typeCheckedAnalysis(buildExamplePackagePath(t, "dispatch", true))
+		assert.Nil(t, err)
+		assert.False(t, actual.Called.Has("main.go:memStore.get"), "calls through interfaces shouldn't be credited unless asked")
+
+		creditInterfaces = true
+		defer func() { creditInterfaces = false }()
+		actual, err = typeCheckedAnalysis(buildExamplePackagePath(t, "dispatch", true))
+		assert.Nil(t, err)
+		assert.True(t, actual.Called.Has("main.go:memStore.get"))
+		assert.False(t, actual.Called.Has("main.go:diskStore.get"), "implementations the tests never construct shouldn't be credited")
+		assert.Equal(t, []string{viaInterfaceCredit}, actual.DeclaredDetails["main.go:memStore.get"].Credits)
+	}
+	t.Run("credit interfaces", interfaces)
+
+	references := func(t *testing.T) {
+		actual, err := typeCheckedAnalysis(buildExamplePackagePath(t, "references", true))
+		assert.Nil(t, err)
+		assert.Equal(t, set.New("main.go:sortStrings", "main.go:retry"), actual.Called, "references shouldn't be credited unless asked")
+
+		creditReferences = true
+		defer func() { creditReferences = false }()
+		actual, err = typeCheckedAnalysis(buildExamplePackagePath(t, "references", true))
+		assert.Nil(t, err)
+		assert.Equal(t, set.New("main.go:Untested"), set.Difference(actual.Declared, actual.Called))
+		assert.Equal(t, []string{byReferenceCredit}, actual.DeclaredDetails["main.go:handler.ServeUser"].Credits)
+		assert.Equal(t, []string{byReferenceCredit}, actual.DeclaredDetails["main.go:B"].Credits)
+	}
+	t.Run("credit references", references)
+
+	tables := func(t *testing.T) {
+		actual, err := typeCheckedAnalysis(buildExamplePackagePath(t, "tables", true))
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"negative", "positive"}, actual.DeclaredDetails["main.go:Add"].Cases)
+		assert.Equal(t, []string{"equal"}, actual.DeclaredDetails["main.go:Sub"].Cases)
+		assert.Equal(t, 1, actual.DeclaredDetails["main.go:Sub"].CaseCount)
+		assert.Equal(t, 2, actual.DeclaredDetails["main.go:Mul"].CaseCount)
+		assert.Equal(t, 0, actual.DeclaredDetails["main.go:Div"].CaseCount, "functions called outside of a table shouldn't be linked to one")
+	}
+	t.Run("table-driven tests", tables)
+
+	generics := func(t *testing.T) {
+		actual, err := typeCheckedAnalysis(buildExamplePackagePath(t, "generics", true))
+
+		assert.Nil(t, err)
+		assert.Equal(t, set.New("main.go:List.Len", "main.go:Filter"), set.Difference(actual.Declared, actual.Called))
+		assert.Equal(t, []string{whiteBoxCredit}, actual.DeclaredDetails["main.go:List.Push"].Credits)
+	}
+	t.Run("generics", generics)
+
+	suites := func(t *testing.T) {
+		actual, err := typeCheckedAnalysis(buildExamplePackagePath(t, "suites", true))
+
+		assert.Nil(t, err)
+		assert.Equal(t, set.New("main.go:Cache.Hits", "main.go:Untested"), set.Difference(actual.Declared, actual.Called))
+		assert.Equal(t, []string{whiteBoxCredit}, actual.DeclaredDetails["main.go:memStore.Get"].Credits, "calls through suite fields should resolve to what the setup hooks assign them")
+	}
+	t.Run("testify suites", suites)
+
+	bdd := func(t *testing.T) {
+		actual, err := typeCheckedAnalysis(buildExamplePackagePath(t, "bdd", true))
+
+		assert.Nil(t, err)
+		assert.Equal(t, set.New("main.go:Store.Delete", "main.go:Store.Clear", "main.go:Parse", "main.go:Untested"), set.Difference(actual.Declared, actual.Called), "pending and skipped specs never run")
+		assert.Equal(t, []string{"Store › Get › returns not found", "Store › Put › stores the value"}, actual.DeclaredDetails["main.go:NewStore"].Specs)
+	}
+	t.Run("bdd specs", bdd)
+
+	testFuncs := func(t *testing.T) {
+		defer func() { countExamples = true }()
+		countExamples = false
+		actual, err := typeCheckedAnalysis(buildExamplePackagePath(t, "testfuncs", true))
+
+		assert.Nil(t, err)
+		assert.Equal(t, set.New("main.go:Validate", "main.go:Untested"), set.Difference(actual.Declared, actual.Called))
+		assert.Equal(t, []string{fuzzFunc}, actual.DeclaredDetails["main.go:Normalize"].TestFuncs)
+	}
+	t.Run("examples, benchmarks and fuzz targets", testFuncs)
+
+	helpers := func(t *testing.T) {
+		actual, err := typeCheckedAnalysis(buildExamplePackagePath(t, "helpers", true))
+
+		assert.Nil(t, err)
+		assert.Equal(t, set.New("main.go:Abandoned", "main.go:Untested"), set.Difference(actual.Declared, actual.Called), "helpers no test calls shouldn't credit anything")
+		assert.Equal(t, []string{"fill", "newTestStore"}, actual.DeclaredDetails["main.go:NewStore"].Helpers)
+	}
+	t.Run("test helpers", helpers)
+
+	constraints := func(t *testing.T) {
+		defer func() { buildTags, targetOS = nil, "" }()
+
+		targetOS = "linux"
+		actual, err := typeCheckedAnalysis(buildExamplePackagePath(t, "constraints", true))
+		assert.Nil(t, err)
+		assert.Equal(t, set.New("main.go:name", "main.go:flush", "file_linux.go:open"), actual.Declared)
+		assert.Equal(t, set.New("file_linux.go:open"), actual.Called, "tests behind unsatisfied build tags shouldn't credit anything")
+
+		targetOS, buildTags = "windows", []string{"integration"}
+		actual, err = typeCheckedAnalysis(buildExamplePackagePath(t, "constraints", true))
+		assert.Nil(t, err)
+		assert.Equal(t, set.New("main.go:name", "main.go:flush", "file_windows.go:open"), actual.Declared)
+		assert.Equal(t, set.New("main.go:flush", "file_windows.go:open"), actual.Called)
+	}
+	t.Run("build constraints", constraints)
+
+	nonexistent := func(t *testing.T) {
+		_, err := typeCheckedAnalysis(buildExamplePackagePath(t, "absolutelynosuchpackage", true))
+		assert.NotNil(t, err)
+	}
+	t.Run("nonexistent package", nonexistent)
+}